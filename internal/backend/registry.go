@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// EnvVar selects a backend when --backend is not given.
+const EnvVar = "BT_MANAGE_BACKEND"
+
+// Auto asks the registry to pick a backend for the running OS.
+const Auto = "auto"
+
+// Options are handed to a backend constructor.
+type Options struct {
+	Verbose bool
+	Logger  io.Writer
+}
+
+// Backend describes one core.BluetoothPort implementation.
+type Backend struct {
+	Name        string
+	Description string
+	// Platforms lists the GOOS values on which the backend takes part in auto-detection.
+	// Backends without platforms (simulators, replay, ...) must be selected explicitly.
+	Platforms []string
+	// Check reports whether the backend can be used in this environment (nil = usable).
+	Check func(ctx context.Context) error
+	New   func(opts Options) (core.BluetoothPort, error)
+	// Enricher optionally builds a source of extra device details (type, battery, ...).
+	Enricher func(opts Options) core.EnricherPort
+}
+
+func (b Backend) supports(goos string) bool {
+	for _, p := range b.Platforms {
+		if p == goos {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Backend) check(ctx context.Context) error {
+	if b.Check == nil {
+		return nil
+	}
+	return b.Check(ctx)
+}
+
+// ErrUnknown is returned when a backend name is not registered.
+type ErrUnknown struct {
+	Name      string
+	Available []string
+}
+
+func (e ErrUnknown) Error() string {
+	return fmt.Sprintf("unknown backend %q (available: %s)", e.Name, strings.Join(e.Available, ", "))
+}
+
+// Registry holds the backends in registration order; earlier backends win auto-detection.
+type Registry struct {
+	backends []Backend
+}
+
+func (r *Registry) Register(b Backend) {
+	for i, existing := range r.backends {
+		if existing.Name == b.Name {
+			r.backends[i] = b
+			return
+		}
+	}
+	r.backends = append(r.backends, b)
+}
+
+func (r *Registry) All() []Backend {
+	return append([]Backend(nil), r.backends...)
+}
+
+func (r *Registry) Lookup(name string) (Backend, bool) {
+	for _, b := range r.backends {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Backend{}, false
+}
+
+func (r *Registry) names() []string {
+	out := make([]string, 0, len(r.backends))
+	for _, b := range r.backends {
+		out = append(out, b.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Detect picks the first backend for goos whose Check passes. When none passes,
+// the first candidate is returned anyway so that its error (e.g. a missing
+// dependency) surfaces from the actual command.
+func (r *Registry) Detect(ctx context.Context, goos string) (Backend, error) {
+	var fallback *Backend
+	for i := range r.backends {
+		b := r.backends[i]
+		if !b.supports(goos) {
+			continue
+		}
+		if b.check(ctx) == nil {
+			return b, nil
+		}
+		if fallback == nil {
+			fallback = &b
+		}
+	}
+	if fallback != nil {
+		return *fallback, nil
+	}
+	return Backend{}, core.ErrUnsupportedPlatform{Platform: goos}
+}
+
+// Resolve returns the named backend, or auto-detects one for goos when name is empty or "auto".
+func (r *Registry) Resolve(ctx context.Context, name string, goos string) (Backend, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == Auto {
+		return r.Detect(ctx, goos)
+	}
+	b, ok := r.Lookup(name)
+	if !ok {
+		return Backend{}, ErrUnknown{Name: name, Available: r.names()}
+	}
+	return b, nil
+}
+
+// Status is a backend together with the result of its Check.
+type Status struct {
+	Backend Backend
+	Err     error
+}
+
+// Statuses runs every backend's Check.
+func (r *Registry) Statuses(ctx context.Context) []Status {
+	out := make([]Status, 0, len(r.backends))
+	for _, b := range r.backends {
+		out = append(out, Status{Backend: b, Err: b.check(ctx)})
+	}
+	return out
+}
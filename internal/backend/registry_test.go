@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func testRegistry(usable map[string]bool) *Registry {
+	check := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			if usable[name] {
+				return nil
+			}
+			return errors.New(name + " unavailable")
+		}
+	}
+	r := &Registry{}
+	r.Register(Backend{Name: "dbus", Platforms: []string{"linux"}, Check: check("dbus")})
+	r.Register(Backend{Name: "cli", Platforms: []string{"linux"}, Check: check("cli")})
+	r.Register(Backend{Name: "mac", Platforms: []string{"darwin"}, Check: check("mac")})
+	r.Register(Backend{Name: "sim"})
+	return r
+}
+
+func TestRegistry_Detect(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		goos   string
+		usable map[string]bool
+		want   string
+	}{
+		{name: "first usable wins", goos: "linux", usable: map[string]bool{"dbus": true, "cli": true}, want: "dbus"},
+		{name: "skips unusable", goos: "linux", usable: map[string]bool{"cli": true}, want: "cli"},
+		{name: "falls back to first candidate", goos: "linux", usable: map[string]bool{}, want: "dbus"},
+		{name: "per platform", goos: "darwin", usable: map[string]bool{"mac": true}, want: "mac"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := testRegistry(tc.usable).Detect(ctx, tc.goos)
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if b.Name != tc.want {
+				t.Fatalf("got %q, want %q", b.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Detect_Unsupported(t *testing.T) {
+	_, err := testRegistry(nil).Detect(context.Background(), "plan9")
+	var up core.ErrUnsupportedPlatform
+	if !errors.As(err, &up) {
+		t.Fatalf("expected ErrUnsupportedPlatform, got %T: %v", err, err)
+	}
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	ctx := context.Background()
+	r := testRegistry(map[string]bool{"cli": true})
+
+	b, err := r.Resolve(ctx, "sim", "linux")
+	if err != nil || b.Name != "sim" {
+		t.Fatalf("Resolve(sim)=%q, %v", b.Name, err)
+	}
+	b, err = r.Resolve(ctx, "auto", "linux")
+	if err != nil || b.Name != "cli" {
+		t.Fatalf("Resolve(auto)=%q, %v", b.Name, err)
+	}
+
+	_, err = r.Resolve(ctx, "nope", "linux")
+	var unk ErrUnknown
+	if !errors.As(err, &unk) {
+		t.Fatalf("expected ErrUnknown, got %T: %v", err, err)
+	}
+	if len(unk.Available) != 4 {
+		t.Fatalf("available=%v", unk.Available)
+	}
+}
+
+func TestRegistry_RegisterReplaces(t *testing.T) {
+	r := testRegistry(nil)
+	r.Register(Backend{Name: "sim", Description: "replaced"})
+	if len(r.All()) != 4 {
+		t.Fatalf("len=%d", len(r.All()))
+	}
+	b, _ := r.Lookup("sim")
+	if b.Description != "replaced" {
+		t.Fatalf("description=%q", b.Description)
+	}
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/backend"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/fumihumi/bt-manage/internal/platform/linux/bluetoothctl"
+	"github.com/fumihumi/bt-manage/internal/platform/linux/bluez"
+	"github.com/fumihumi/bt-manage/internal/platform/macos/blueutil"
+	"github.com/fumihumi/bt-manage/internal/platform/macos/sysprofiler"
+	"github.com/fumihumi/bt-manage/internal/platform/sim"
+	"github.com/spf13/cobra"
+)
+
+// backends is the set of BluetoothPort implementations selectable via --backend / BT_MANAGE_BACKEND.
+// Registration order is the auto-detection preference.
+var backends = builtinBackends()
+
+func lookPathCheck(bin string) func(context.Context) error {
+	return func(context.Context) error {
+		if _, err := exec.LookPath(bin); err != nil {
+			return core.ErrDependencyMissing{Dependency: bin}
+		}
+		return nil
+	}
+}
+
+func builtinBackends() *backend.Registry {
+	r := &backend.Registry{}
+	r.Register(backend.Backend{
+		Name:        "blueutil",
+		Description: "macOS, drives the blueutil CLI",
+		Platforms:   []string{"darwin"},
+		Check:       lookPathCheck("blueutil"),
+		New: func(opts backend.Options) (core.BluetoothPort, error) {
+			c := blueutil.Client{Verbose: opts.Verbose, Logger: opts.Logger}
+			if path := os.Getenv(envBlueutilRecord); path != "" {
+				c.Exec = &blueutil.Recorder{Path: path}
+			}
+			return c, nil
+		},
+		Enricher: func(opts backend.Options) core.EnricherPort {
+			return &sysprofiler.Client{Verbose: opts.Verbose, Logger: opts.Logger}
+		},
+	})
+	r.Register(backend.Backend{
+		Name:        "bluez",
+		Description: "Linux, talks to bluetoothd over the system D-Bus",
+		Platforms:   []string{"linux"},
+		Check: func(ctx context.Context) error {
+			c := &bluez.Client{}
+			defer c.Close()
+			return c.Check(ctx)
+		},
+		New: func(opts backend.Options) (core.BluetoothPort, error) {
+			return &bluez.Client{Verbose: opts.Verbose, Logger: opts.Logger}, nil
+		},
+	})
+	r.Register(backend.Backend{
+		Name:        "bluetoothctl",
+		Description: "Linux, drives BlueZ's bluetoothctl CLI",
+		Platforms:   []string{"linux"},
+		Check:       lookPathCheck("bluetoothctl"),
+		New: func(opts backend.Options) (core.BluetoothPort, error) {
+			return bluetoothctl.Client{Verbose: opts.Verbose, Logger: opts.Logger}, nil
+		},
+	})
+	r.Register(backend.Backend{
+		Name:        "blueutil-replay",
+		Description: "replays a blueutil session recorded to $BT_MANAGE_BLUEUTIL_CASSETTE",
+		Check: func(context.Context) error {
+			if os.Getenv(envBlueutilCassette) == "" {
+				return fmt.Errorf("%s is not set", envBlueutilCassette)
+			}
+			return nil
+		},
+		New: func(opts backend.Options) (core.BluetoothPort, error) {
+			path := os.Getenv(envBlueutilCassette)
+			if path == "" {
+				return nil, fmt.Errorf("%s is not set", envBlueutilCassette)
+			}
+			cassette, err := blueutil.LoadCassette(path)
+			if err != nil {
+				return nil, err
+			}
+			return blueutil.Client{Exec: &blueutil.Replayer{Cassette: cassette}, Verbose: opts.Verbose, Logger: opts.Logger}, nil
+		},
+	})
+	// sim has no platforms: it is never auto-detected, only selected explicitly.
+	r.Register(backend.Backend{
+		Name:        "sim",
+		Description: "simulated devices from $BT_MANAGE_SIM_SCENARIO (built-in desk setup if unset)",
+		New:         newSimulator,
+	})
+	return r
+}
+
+const (
+	envBlueutilRecord   = "BT_MANAGE_BLUEUTIL_RECORD"
+	envBlueutilCassette = "BT_MANAGE_BLUEUTIL_CASSETTE"
+)
+
+const (
+	envSimScenario = "BT_MANAGE_SIM_SCENARIO"
+	envSimSeed     = "BT_MANAGE_SIM_SEED"
+	envSimState    = "BT_MANAGE_SIM_STATE"
+)
+
+// newSimulator builds the sim backend. Without a state file every invocation
+// starts the scenario from scratch; with one, device state carries over.
+func newSimulator(opts backend.Options) (core.BluetoothPort, error) {
+	scenario := sim.DefaultScenario()
+	if path := os.Getenv(envSimScenario); path != "" {
+		s, err := sim.LoadScenario(path)
+		if err != nil {
+			return nil, err
+		}
+		scenario = s
+	}
+	if v := os.Getenv(envSimSeed); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", envSimSeed, v)
+		}
+		scenario.Seed = seed
+	}
+
+	s := sim.New(scenario)
+	s.Verbose = opts.Verbose
+	s.Logger = opts.Logger
+	if path := os.Getenv(envSimState); path != "" {
+		if err := s.UseStateFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// selectedBackendName returns the --backend flag, falling back to BT_MANAGE_BACKEND.
+func selectedBackendName(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup("backend"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return os.Getenv(backend.EnvVar)
+}
+
+func newBackendsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backends",
+		Short: "List Bluetooth backends and whether they are usable",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			selected := ""
+			if b, err := backends.Resolve(ctx, selectedBackendName(cmd), runtime.GOOS); err == nil {
+				selected = b.Name
+			}
+
+			rows := make([]output.BackendInfo, 0)
+			for _, s := range backends.Statuses(ctx) {
+				row := output.BackendInfo{
+					Name:        s.Backend.Name,
+					Description: s.Backend.Description,
+					Platforms:   append([]string{}, s.Backend.Platforms...),
+					Usable:      s.Err == nil,
+					Selected:    s.Backend.Name == selected,
+				}
+				if s.Err != nil {
+					row.Error = userFacingError(s.Err).Error()
+				}
+				rows = append(rows, row)
+			}
+
+			switch format {
+			case output.FormatTSV:
+				return output.WriteBackendsTSV(cmd.OutOrStdout(), rows, !noHeader)
+			case output.FormatJSON:
+				return output.WriteBackendsJSON(cmd.OutOrStdout(), rows)
+			default:
+				return fmt.Errorf("unsupported format")
+			}
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// errBatteryLow is returned by `battery --below` when some device is under the threshold.
+type errBatteryLow struct {
+	Count     int
+	Threshold int
+}
+
+func (e errBatteryLow) Error() string {
+	return fmt.Sprintf("%d device(s) below %d%% battery", e.Count, e.Threshold)
+}
+
+func newBatteryCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "battery",
+		Short: "Show battery levels of paired devices",
+		Long: "Show battery levels of paired devices that report one.\n\n" +
+			"With --below, only devices with a level (any of main/left/right/case) under the\n" +
+			"threshold are printed and the command exits with status 6 if there are any,\n" +
+			"so it can run from cron or launchd.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+			below, _ := cmd.Flags().GetInt("below")
+
+			if cmd.Flags().Changed("below") && (below < 1 || below > 100) {
+				return fmt.Errorf("--below must be between 1 and 100")
+			}
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			l := core.Lister{Bluetooth: e.bluetooth, Enricher: e.enricher, ShowHidden: e.showHidden}
+			devices, err := l.ListDevices(context.Background())
+			if err != nil {
+				return err
+			}
+
+			filtered := make([]core.Device, 0, len(devices))
+			for _, d := range devices {
+				if d.Battery == nil {
+					continue
+				}
+				lowest, ok := d.Battery.Lowest()
+				if !ok {
+					continue
+				}
+				if below > 0 && lowest >= below {
+					continue
+				}
+				filtered = append(filtered, d)
+			}
+
+			switch format {
+			case output.FormatTSV:
+				if below > 0 && len(filtered) == 0 {
+					// Stay quiet for cron when everything is fine.
+					break
+				}
+				err = output.WriteBatteryTSV(cmd.OutOrStdout(), filtered, !noHeader)
+			case output.FormatJSON:
+				err = output.WriteJSON(cmd.OutOrStdout(), filtered)
+			default:
+				return fmt.Errorf("unsupported format")
+			}
+			if err != nil {
+				return err
+			}
+
+			if below > 0 && len(filtered) > 0 {
+				return errBatteryLow{Count: len(filtered), Threshold: below}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+	cmd.Flags().Int("below", 0, "Only show devices below this percentage and exit 6 if there are any")
+
+	return cmd
+}
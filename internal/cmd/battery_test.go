@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func pct(n int) *int { return &n }
+
+func batteryEnv() env {
+	return env{
+		bluetooth: fakeBluetooth{devices: []core.Device{
+			{Name: "Keys", Address: "AA", Battery: &core.Battery{Main: pct(85)}},
+			{Name: "Pods", Address: "BB", Battery: &core.Battery{Left: pct(90), Right: pct(15), Case: pct(60)}},
+			{Name: "Speaker", Address: "CC"},
+		}},
+		isTTY: func() bool { return false },
+	}
+}
+
+func TestBatteryListsDevicesWithLevels(t *testing.T) {
+	cmd := newBatteryCmd(batteryEnv())
+	cmd.SetArgs([]string{"--no-header"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Keys") || !strings.Contains(got, "L 90% R 15% case 60%") || strings.Contains(got, "Speaker") {
+		t.Fatalf("got=%s", got)
+	}
+}
+
+func TestBatteryBelowExitsNonZero(t *testing.T) {
+	cmd := newBatteryCmd(batteryEnv())
+	cmd.SetArgs([]string{"--below", "20", "--format", "json"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+	var low errBatteryLow
+	if !errors.As(err, &low) || low.Count != 1 {
+		t.Fatalf("expected errBatteryLow for 1 device, got %v", err)
+	}
+	if exitCodeFor(err) != exitBatteryLow {
+		t.Fatalf("exit code=%d", exitCodeFor(err))
+	}
+	if got := out.String(); !strings.Contains(got, `"name": "Pods"`) || strings.Contains(got, `"name": "Keys"`) {
+		t.Fatalf("got=%s", got)
+	}
+}
+
+func TestBatteryBelowQuietWhenFine(t *testing.T) {
+	cmd := newBatteryCmd(batteryEnv())
+	cmd.SetArgs([]string{"--below", "10"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no output, got %q", out.String())
+	}
+}
@@ -17,8 +17,10 @@ func newConnectCmd(e env) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "connect [<Name>]",
 		Short: "Connect to a Bluetooth device",
-		Long:  "Connect to a Bluetooth device. Output is a single device in the selected format (json is a 1-element array, consistent with 'list').",
-		Args:  cobra.MaximumNArgs(1),
+		Long: "Connect to a Bluetooth device. Output is a single device in the selected format (json is a 1-element array, consistent with 'list').\n\n" +
+			"Other connected members of the device's exclusive groups (see config.yaml) are disconnected\n" +
+			"once it is connected; json then also lists them, with \"action\": \"disconnect\".",
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Do NOT apply timeout to interactive (TUI) selection.
 			baseCtx := context.Background()
@@ -56,12 +58,24 @@ func newConnectCmd(e env) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			retry, err := retryPolicy(cmd, e.retry)
+			if err != nil {
+				return err
+			}
+			// Connects get 10s, or the retry deadline if that is longer.
+			timeout := max(10*time.Second, retry.Deadline)
 
 			isTTY := e.isTTY()
 			if interactive && !isTTY {
 				return fmt.Errorf("--interactive requires a TTY")
 			}
 
+			if !dryRun {
+				if err := powerOnIfRequested(cmd, e); err != nil {
+					return err
+				}
+			}
+
 			var pk core.PickerPort
 			if interactive && isTTY {
 				pk = e.picker
@@ -84,15 +98,15 @@ func newConnectCmd(e env) *cobra.Command {
 					return err
 				}
 
+				// Plan first: two members of one exclusive group are an error
+				// before anything is connected.
+				c := core.Connector{Bluetooth: e.bluetooth, Groups: e.groups, Retry: retry}
+				displaced, err := c.Displace(baseCtx, selected, true)
+				if err != nil {
+					return err
+				}
 				if dryRun {
-					switch format {
-					case output.FormatTSV:
-						return output.WriteTSV(cmd.OutOrStdout(), selected, !noHeader)
-					case output.FormatJSON:
-						return output.WriteJSON(cmd.OutOrStdout(), selected)
-					default:
-						return fmt.Errorf("unsupported format")
-					}
+					return writeConnectResult(cmd, format, noHeader, selected, displaced, true)
 				}
 
 				fmt.Fprintln(cmd.ErrOrStderr(), "Connecting...")
@@ -114,9 +128,9 @@ func newConnectCmd(e env) *cobra.Command {
 						fmt.Fprintf(cmd.ErrOrStderr(), "- %s (%s)\n", dev.Name, dev.Address)
 
 						// Per-device timeout (independent).
-						dctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+						dctx, cancel := context.WithTimeout(context.Background(), timeout)
 						defer cancel()
-						err := e.bluetooth.Connect(dctx, dev.Address)
+						err := c.ConnectDevice(dctx, dev)
 						if err == nil {
 							fmt.Fprintf(cmd.ErrOrStderr(), "  ok: connected %s (%s)\n", dev.Name, dev.Address)
 						}
@@ -135,50 +149,46 @@ func newConnectCmd(e env) *cobra.Command {
 					}
 				}
 				if len(failed) > 0 {
-					return errors.New("some connects failed: " + strings.Join(failed, "; "))
+					return explainPoweredOff(e, errors.New("some connects failed: "+strings.Join(failed, "; ")))
 				}
 
-				switch format {
-				case output.FormatTSV:
-					return output.WriteTSV(cmd.OutOrStdout(), selected, !noHeader)
-				case output.FormatJSON:
-					return output.WriteJSON(cmd.OutOrStdout(), selected)
-				default:
-					return fmt.Errorf("unsupported format")
+				dctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				displaced, derr := c.Displace(dctx, selected, false)
+				if err := writeConnectResult(cmd, format, noHeader, selected, displaced, false); err != nil {
+					return err
 				}
+				return derr
 			}
 
 			// Single-select.
 			var ctx context.Context
 			var cancel func()
-			ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+			ctx, cancel = context.WithTimeout(context.Background(), timeout)
 			defer cancel()
 
-			c := core.Connector{Bluetooth: e.bluetooth, Picker: pk}
-			selected, err := c.ConnectByNameOrInteractive(ctx, core.ConnectParams{
+			c := core.Connector{Bluetooth: e.bluetooth, Picker: pk, Groups: e.groups, Retry: retry}
+			res, err := c.Connect(ctx, core.ConnectParams{
 				Name:        name,
 				Exact:       exact,
 				Interactive: interactive,
 				IsTTY:       isTTY,
 				DryRun:      dryRun,
 			})
-			if err != nil {
-				return err
+			if res.Device.Address == "" {
+				return explainPoweredOff(e, err)
 			}
 
 			if !dryRun {
 				fmt.Fprintln(cmd.ErrOrStderr(), "Connecting...")
-				fmt.Fprintf(cmd.ErrOrStderr(), "- %s (%s)\n", selected.Name, selected.Address)
+				fmt.Fprintf(cmd.ErrOrStderr(), "- %s (%s)\n", res.Device.Name, res.Device.Address)
 			}
 
-			switch format {
-			case output.FormatTSV:
-				return output.WriteTSV(cmd.OutOrStdout(), []core.Device{selected}, !noHeader)
-			case output.FormatJSON:
-				return output.WriteJSON(cmd.OutOrStdout(), []core.Device{selected})
-			default:
-				return fmt.Errorf("unsupported format")
+			// A failed displace leaves the device connected: report both.
+			if werr := writeConnectResult(cmd, format, noHeader, []core.Device{res.Device}, res.Displaced, dryRun); werr != nil {
+				return werr
 			}
+			return err
 		},
 	}
 
@@ -188,6 +198,29 @@ func newConnectCmd(e env) *cobra.Command {
 	cmd.Flags().BoolP("dry-run", "n", false, "Do not connect; only resolve and print the target device")
 	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
 	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+	addRetryFlags(cmd, core.DefaultRetryAttempts)
+	addPowerOnFlag(cmd)
 
 	return cmd
 }
+
+// writeConnectResult prints the connected devices. Devices displaced from
+// their exclusive groups are part of the JSON output and reported on stderr
+// otherwise, so that the TSV columns stay those of 'list'.
+func writeConnectResult(cmd *cobra.Command, format output.Format, noHeader bool, connected, displaced []core.Device, dryRun bool) error {
+	for _, d := range displaced {
+		verb := "disconnected"
+		if dryRun {
+			verb = "would disconnect"
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "- %s %s (%s) (exclusive group)\n", verb, d.DisplayName(), d.Address)
+	}
+	switch format {
+	case output.FormatTSV:
+		return output.WriteTSV(cmd.OutOrStdout(), connected, !noHeader)
+	case output.FormatJSON:
+		return output.WriteConnectJSON(cmd.OutOrStdout(), connected, displaced)
+	default:
+		return fmt.Errorf("unsupported format")
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// groupBluetooth reports the devices marked Connected as connected.
+type groupBluetooth struct {
+	fakeBluetooth
+}
+
+func (g groupBluetooth) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	var out []core.Device
+	for _, d := range g.devices {
+		if d.Connected {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func TestConnectDryRunShowsDisplacedDevices(t *testing.T) {
+	e := env{
+		bluetooth: groupBluetooth{fakeBluetooth{devices: []core.Device{
+			{Name: "MX Keys", Address: "AA-BB-CC-DD-EE-01"},
+			{Name: "HHKB", Address: "AA-BB-CC-DD-EE-02", Connected: true},
+		}}},
+		groups: []core.ExclusiveGroup{{Name: "keyboards", Members: []string{"MX Keys", "HHKB"}}},
+		isTTY:  func() bool { return false },
+	}
+
+	cmd := newConnectCmd(e)
+	cmd.SetArgs([]string{"MX", "--dry-run", "--format", "json"})
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	var got []struct {
+		Name   string `json:"name"`
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(got) != 2 || got[0].Name != "MX Keys" || got[0].Action != "connect" || got[1].Name != "HHKB" || got[1].Action != "disconnect" {
+		t.Fatalf("got %+v", got)
+	}
+	if !strings.Contains(errOut.String(), "would disconnect HHKB") {
+		t.Fatalf("stderr = %q", errOut.String())
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/daemon"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// envNoDaemon makes commands open the backend themselves even if a daemon is running.
+const envNoDaemon = "BT_MANAGE_NO_DAEMON"
+
+func newDaemonCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep the Bluetooth backend open and serve it to other commands",
+		Long: "Run in the foreground, serving the backend to other bt-manage commands over a Unix socket.\n" +
+			"While it runs, commands use it instead of opening the backend themselves: device lists come\n" +
+			"from a cache it refreshes every --interval (and on BlueZ change signals), and backend calls\n" +
+			"are serialized, so commands bound to hotkeys respond right away. Without a daemon, or with\n" +
+			"--no-daemon, commands work as before.\n\n" +
+			"The socket is --socket, $BT_MANAGE_SOCKET, $XDG_RUNTIME_DIR/bt-manage/daemon.sock, or\n" +
+			"daemon.sock in the config directory. A --backend given to a command that differs from the\n" +
+			"daemon's makes that command bypass the daemon.\n\n" +
+			"--hooks runs the connect/disconnect hooks (see 'bt-manage watch --help') for the changes\n" +
+			"the daemon sees.\n\n" +
+			"--keepalive keeps keepalive.devices in config.yaml connected (see 'bt-manage keepalive --help');\n" +
+			"its reconnects go through the daemon like any other command's calls.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			runHooks, _ := cmd.Flags().GetBool("hooks")
+			keepalive, _ := cmd.Flags().GetBool("keepalive")
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			var keep core.KeepaliveParams
+			if keepalive {
+				if keep, err = keepaliveParams(cmd, "keepalive-interval", cfg.Keepalive, cfg.Retry, nil); err != nil {
+					return err
+				}
+			}
+
+			ports, err := openBackend(cmd, verbose)
+			if err != nil {
+				return err
+			}
+			socket, err := socketPath(cmd)
+			if err != nil {
+				return err
+			}
+
+			srv := &daemon.Server{
+				Backend:   ports.name,
+				Bluetooth: ports.bt,
+				Adapter:   ports.adapter,
+				Notifier:  ports.notifier,
+				Enricher:  ports.enricher,
+				Interval:  interval,
+				Log:       cmd.ErrOrStderr(),
+			}
+			if runHooks {
+				runner, err := newHookRunner(cfg)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: %v\n", err)
+				}
+				if runner == nil {
+					return fmt.Errorf("--hooks: no hooks configured (see 'bt-manage watch --help')")
+				}
+				runner.Log = cmd.ErrOrStderr()
+				// Let hooks started by the last changes finish before exiting.
+				defer runner.Wait()
+				srv.Hooks = runner
+				if f, err := config.DefaultRegistryFile(); err == nil {
+					srv.Registry = f
+				}
+			}
+
+			ln, err := daemon.Listen(socket)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: daemon serving backend %s on %s\n", ports.name, socket)
+			if keepalive {
+				var wg sync.WaitGroup
+				defer wg.Wait()
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel() // the keeper stops with the daemon
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					keepAlive(ctx, cmd, socket, keep)
+				}()
+			}
+			return srv.Serve(ctx, ln)
+		},
+	}
+
+	cmd.Flags().Duration("interval", daemon.DefaultInterval, "How often the cached device list is refreshed")
+	cmd.Flags().Bool("hooks", false, "Run connect/disconnect hooks for the changes seen")
+	cmd.Flags().Bool("keepalive", false, "Reconnect keepalive.devices in config.yaml whenever they drop")
+	addKeepaliveFlags(cmd, "keepalive-interval")
+
+	cmd.AddCommand(newDaemonStatusCmd(), newDaemonStopCmd())
+	return cmd
+}
+
+// keepAlive runs a Keeper on the daemon's own socket, with the registry's
+// aliases, until ctx is done. Its failure is logged: the daemon keeps serving.
+func keepAlive(ctx context.Context, cmd *cobra.Command, socket string, p core.KeepaliveParams) {
+	var bt core.BluetoothPort = daemon.Client{Socket: socket}
+	reg := core.Registry{}
+	if f, err := config.DefaultRegistryFile(); err == nil {
+		if r, err := f.Load(ctx); err == nil {
+			reg = r
+		}
+	}
+	bt = core.AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}
+	k := &core.Keeper{Bluetooth: bt, Log: cmd.ErrOrStderr()}
+	if f, err := config.DefaultHoldFile(); err == nil {
+		k.Holds = f
+	}
+	if err := k.Run(ctx, p); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: keepalive: %v\n", prettyError(err))
+	}
+}
+
+func newDaemonStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether a daemon is running, and its backend",
+		Long:  "Show the running daemon. Exits with status 1 if none is running.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+			socket, err := socketPath(cmd)
+			if err != nil {
+				return err
+			}
+
+			st, err := daemon.Probe(context.Background(), socket, 3*time.Second)
+			if err != nil {
+				return err
+			}
+			info := output.DaemonInfo{
+				Socket:    socket,
+				Backend:   st.Backend,
+				PID:       st.PID,
+				Started:   st.Started,
+				Devices:   st.Devices,
+				Refreshed: st.Refreshed,
+				Hooks:     st.Hooks,
+			}
+			switch format {
+			case output.FormatTSV:
+				return output.WriteDaemonTSV(cmd.OutOrStdout(), info, time.Now(), !noHeader)
+			case output.FormatJSON:
+				return output.WriteDaemonJSON(cmd.OutOrStdout(), info)
+			default:
+				return fmt.Errorf("unsupported format")
+			}
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+
+	return cmd
+}
+
+func newDaemonStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running daemon",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			socket, err := socketPath(cmd)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return daemon.Client{Socket: socket}.Shutdown(ctx)
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/backend"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/daemon"
+)
+
+func TestCommandsUseRunningDaemon(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envNoDaemon, "")
+	t.Setenv(daemon.SocketEnvVar, "")
+	t.Setenv(backend.EnvVar, "")
+	socket := filepath.Join(t.TempDir(), "d.sock")
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		root := newRootCmd()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append(args, "--socket", socket, "--names-only"))
+		err := root.Execute()
+		return strings.TrimSpace(out.String()), err
+	}
+
+	// Without a daemon, the backend is opened directly.
+	if got, err := run("list", "--backend", "sim"); err != nil || !strings.Contains(got, "MX Keys") {
+		t.Fatalf("direct list = %q, %v", got, err)
+	}
+
+	ln, err := daemon.Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	srv := &daemon.Server{
+		Backend:   "sim",
+		Bluetooth: fakeBluetooth{devices: []core.Device{{Name: "Served Headphones", Address: "AA"}}},
+		Interval:  time.Hour,
+	}
+	go func() { done <- srv.Serve(ctx, ln) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, args := range [][]string{{"list"}, {"list", "--backend", "sim"}} {
+		if got, err := run(args...); err != nil || got != "Served Headphones" {
+			t.Fatalf("%v via daemon = %q, %v", args, got, err)
+		}
+	}
+	// --no-daemon, or another backend than the daemon's, bypasses it.
+	if got, err := run("list", "--backend", "sim", "--no-daemon"); err != nil || !strings.Contains(got, "MX Keys") {
+		t.Fatalf("list --no-daemon = %q, %v", got, err)
+	}
+	t.Setenv(envBlueutilCassette, "")
+	if _, err := run("list", "--backend", "blueutil-replay"); err == nil || !strings.Contains(err.Error(), envBlueutilCassette) {
+		t.Fatalf("list --backend blueutil-replay = %v, want the backend opened directly", err)
+	}
+}
@@ -39,10 +39,34 @@ func userFacingError(err error) error {
 
 	var dm core.ErrDependencyMissing
 	if errors.As(err, &dm) {
-		if dm.Dependency != "" {
+		switch dm.Dependency {
+		case "":
+			return fmt.Errorf("missing dependency")
+		case "bluetoothctl":
+			return fmt.Errorf("missing dependency: %s (install BlueZ, e.g. apt install bluez)", dm.Dependency)
+		case "bluetoothd":
+			return fmt.Errorf("missing dependency: %s is not running on the system bus (install/start BlueZ, e.g. systemctl start bluetooth)", dm.Dependency)
+		default:
 			return fmt.Errorf("missing dependency: %s (install via Homebrew: brew install blueutil)", dm.Dependency)
 		}
-		return fmt.Errorf("missing dependency")
+	}
+
+	var old core.ErrDependencyTooOld
+	if errors.As(err, &old) {
+		if old.Dependency == "blueutil" {
+			return fmt.Errorf("%s (upgrade via Homebrew: brew upgrade blueutil)", old.Error())
+		}
+		return old
+	}
+
+	var po core.ErrPoweredOff
+	if errors.As(err, &po) {
+		return fmt.Errorf("%s (turn it on with 'bt-manage power on', or pass --power-on)", po.Error())
+	}
+
+	var up core.ErrUnsupportedPlatform
+	if errors.As(err, &up) {
+		return fmt.Errorf("%s is not supported (bt-manage runs on macOS and Linux)", up.Platform)
 	}
 
 	return err
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"errors"
-	"runtime"
 
 	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/daemon"
 )
 
 const (
@@ -13,6 +13,11 @@ const (
 	exitUsage            = 2
 	exitDependencyMissing = 3
 	exitUnsupported       = 4
+	exitDependencyTooOld  = 5
+	exitBatteryLow        = 6
+	exitTimeout           = 7
+	exitNotFound          = 8
+	exitPoweredOff        = 9
 )
 
 func exitCodeFor(err error) int {
@@ -21,7 +26,8 @@ func exitCodeFor(err error) int {
 	}
 
 	// platform
-	if runtime.GOOS != "darwin" {
+	var up core.ErrUnsupportedPlatform
+	if errors.As(err, &up) {
 		return exitUnsupported
 	}
 
@@ -30,9 +36,33 @@ func exitCodeFor(err error) int {
 		return exitDependencyMissing
 	}
 
+	var low errBatteryLow
+	if errors.As(err, &low) {
+		return exitBatteryLow
+	}
+
+	var old core.ErrDependencyTooOld
+	if errors.As(err, &old) {
+		return exitDependencyTooOld
+	}
+
+	var off core.ErrPoweredOff
+	if errors.As(err, &off) {
+		return exitPoweredOff
+	}
+
+	var to core.ErrTimeout
+	if errors.As(err, &to) {
+		return exitTimeout
+	}
+
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return exitGeneric
+	}
+
 	var nf core.ErrNotFound
 	if errors.As(err, &nf) {
-		return exitUsage
+		return exitNotFound
 	}
 	var am core.ErrAmbiguous
 	if errors.As(err, &am) {
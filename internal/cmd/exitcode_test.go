@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{core.ErrDependencyMissing{Dependency: "blueutil"}, exitDependencyMissing},
+		{core.ErrUnsupportedPlatform{Platform: "plan9"}, exitUnsupported},
+		{core.ErrDependencyTooOld{Dependency: "blueutil", Need: "2.5.0"}, exitDependencyTooOld},
+		{core.ErrTimeout{}, exitTimeout},
+		{core.ErrPoweredOff{}, exitPoweredOff},
+		{fmt.Errorf("connect: %w", core.ErrPoweredOff{}), exitPoweredOff},
+		{core.ErrNotFound{Query: "Nope"}, exitNotFound},
+		{fmt.Errorf("connect: %w", core.ErrNotFound{Query: "Nope"}), exitNotFound},
+		{core.ErrAmbiguous{Query: "M", Count: 2}, exitUsage},
+		{core.ErrCanceled{}, exitUsage},
+		{errors.New("--interactive requires a TTY"), exitUsage},
+	}
+	for _, tc := range cases {
+		if got := exitCodeFor(tc.err); got != tc.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
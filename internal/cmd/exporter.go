@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fumihumi/bt-manage/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+func newExporterCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exporter",
+		Short: "Export device state as Prometheus metrics",
+		Long: "List devices every --interval and serve the result as Prometheus metrics at\n" +
+			"http://<listen>/metrics: connection state, RSSI, battery levels and time since last seen\n" +
+			"connected per device, plus latency and error counts of the backend calls (e.g. blueutil).\n\n" +
+			"Metrics (labels in braces):\n\n" +
+			"  bt_manage_up                                            last listing succeeded (1/0)\n" +
+			"  bt_manage_refresh_timestamp_seconds                     time of the last successful listing\n" +
+			"  bt_manage_refresh_errors_total                          failed listings\n" +
+			"  bt_manage_device_connected{address,name,type}           connected (1/0)\n" +
+			"  bt_manage_device_rssi_dbm{address,name}                 signal strength, when reported\n" +
+			"  bt_manage_device_battery_percent{address,name,part}     part: main, left, right, case\n" +
+			"  bt_manage_device_last_seen_seconds{address,name}        since last seen connected (0 while connected)\n" +
+			"  bt_manage_backend_calls_total{backend,method}\n" +
+			"  bt_manage_backend_call_errors_total{backend,method}\n" +
+			"  bt_manage_backend_call_duration_seconds{backend,method} histogram\n\n" +
+			"name is the device's alias when it has one.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("listen")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			warnIfReachable(cmd, addr, ln)
+
+			calls := &metrics.Calls{Backend: e.backend}
+			x := &metrics.Exporter{
+				Bluetooth: calls.Wrap(e.bluetooth),
+				Enricher:  e.enricher,
+				Calls:     calls,
+				Interval:  interval,
+				Log:       cmd.ErrOrStderr(),
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: serving metrics on http://%s/metrics\n", ln.Addr())
+			return x.Serve(ctx, ln)
+		},
+	}
+
+	cmd.Flags().String("listen", "127.0.0.1:9487", "Address to serve the metrics on")
+	cmd.Flags().Duration("interval", metrics.DefaultInterval, "How often devices are listed")
+
+	return cmd
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func newInfoCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info [<Name>|<Address>]",
+		Short: "Show everything known about a Bluetooth device",
+		Long: "Show everything known about one paired device: connection state, last connection,\n" +
+			"RSSI, paired/favourite flags, battery and other details. The device is selected like\n" +
+			"'connect' does (name prefix, --exact, picker when omitted or ambiguous); an address\n" +
+			"selects the device directly. json is a 1-element array, consistent with 'list'.\n\n" +
+			"With --watch the view is refreshed every --interval until interrupted.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			exact, _ := cmd.Flags().GetBool("exact")
+			interactive, _ := cmd.Flags().GetBool("interactive")
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+			watch, _ := cmd.Flags().GetBool("watch")
+			interval, _ := cmd.Flags().GetDuration("interval")
+
+			if !cmd.Flags().Changed("interactive") && name == "" {
+				interactive = true
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			isTTY := e.isTTY()
+			if interactive && !isTTY {
+				return fmt.Errorf("--interactive requires a TTY")
+			}
+			var pk core.PickerPort
+			if interactive && isTTY {
+				pk = e.picker
+			}
+
+			render := func(w io.Writer, d core.Device) error {
+				switch format {
+				case output.FormatTSV:
+					return output.WriteInfoTSV(w, d, !noHeader)
+				case output.FormatJSON:
+					return output.WriteJSON(w, []core.Device{d})
+				default:
+					return fmt.Errorf("unsupported format")
+				}
+			}
+
+			// Picking may take a while (user interaction); no timeout.
+			i := core.Inspector{Bluetooth: e.bluetooth, Picker: pk, Enricher: e.enricher}
+			dev, err := i.Inspect(context.Background(), core.InspectParams{
+				Name:        name,
+				Exact:       exact,
+				Interactive: interactive,
+				IsTTY:       isTTY,
+			})
+			if err != nil {
+				return err
+			}
+			if !watch {
+				return render(cmd.OutOrStdout(), dev)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return watchInfo(ctx, cmd, i, dev, interval, isTTY, render)
+		},
+	}
+
+	cmd.Flags().BoolP("exact", "e", false, "Match device name exactly")
+	cmd.Flags().BoolP("interactive", "i", false, "Always use interactive picker (TTY required)")
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+	cmd.Flags().BoolP("watch", "w", false, "Keep refreshing the view until interrupted")
+	cmd.Flags().Duration("interval", 2*time.Second, "Refresh interval for --watch")
+
+	return cmd
+}
+
+// watchInfo re-renders dev whenever it changes. On a terminal the screen is
+// redrawn; otherwise each changed view is appended, separated by a blank line,
+// so the output can be logged. Refresh errors are reported and retried, since
+// a flaky device is exactly what --watch is for.
+func watchInfo(
+	ctx context.Context,
+	cmd *cobra.Command,
+	i core.Inspector,
+	dev core.Device,
+	interval time.Duration,
+	redraw bool,
+	render func(io.Writer, core.Device) error,
+) error {
+	out := cmd.OutOrStdout()
+	var last []byte
+	show := func(d core.Device) error {
+		var buf bytes.Buffer
+		if err := render(&buf, d); err != nil {
+			return err
+		}
+		if bytes.Equal(buf.Bytes(), last) {
+			return nil
+		}
+		switch {
+		case redraw:
+			fmt.Fprint(out, "\x1b[H\x1b[2J")
+		case last != nil:
+			fmt.Fprintln(out)
+		}
+		last = buf.Bytes()
+		_, err := out.Write(last)
+		return err
+	}
+
+	if err := show(dev); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		d, err := i.Refresh(rctx, dev.Address)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "info: %v\n", err)
+			continue
+		}
+		if err := show(d); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestInfoByAddress(t *testing.T) {
+	rssi := -40
+	e := env{
+		bluetooth: fakeBluetooth{devices: []core.Device{
+			{Name: "MX Keys", Address: "aa:bb:cc:00:00:01", Paired: true, Connected: true, RSSI: &rssi},
+			{Name: "MX Master", Address: "aa:bb:cc:00:00:02", Paired: true},
+		}},
+		isTTY: func() bool { return false },
+	}
+
+	cmd := newInfoCmd(e)
+	cmd.SetArgs([]string{"AA-BB-CC-00-00-01"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"MX Keys", "Connected  yes", "-40 dBm"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "MX Master") {
+		t.Fatalf("output should only describe the selected device:\n%s", got)
+	}
+}
+
+// flappingBluetooth alternates the connection state on every List.
+type flappingBluetooth struct {
+	fakeBluetooth
+	calls *atomic.Int32
+}
+
+func (f flappingBluetooth) List(ctx context.Context) ([]core.Device, error) {
+	n := f.calls.Add(1)
+	return []core.Device{{Name: "Trackpad", Address: "aa", Paired: true, Connected: n%2 == 1}}, nil
+}
+
+func TestInfoWatchPrintsChanges(t *testing.T) {
+	e := env{bluetooth: flappingBluetooth{calls: &atomic.Int32{}}, isTTY: func() bool { return false }}
+
+	cmd := newInfoCmd(e)
+	cmd.SetArgs([]string{"Trackpad", "--watch", "--interval", "10ms", "--no-header"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Connected  yes") || !strings.Contains(got, "Connected  no") {
+		t.Fatalf("watch should print both states:\n%s", got)
+	}
+}
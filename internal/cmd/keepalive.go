@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func newKeepaliveCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keepalive [<Name>...]",
+		Short: "Reconnect devices whenever they drop",
+		Long: "Run in the foreground, reconnecting the given devices (or keepalive.devices in config.yaml)\n" +
+			"whenever they disconnect. Each outage is retried like 'pair' verifies a connection, pausing\n" +
+			"--backoff after a failed round and doubling the pause up to --max-backoff, for --budget.\n" +
+			"Then, with --repair, the device is repaired (unpair, inquiry, pair); otherwise it is left\n" +
+			"alone until it connects again.\n\n" +
+			"Each round's connect attempts follow the retry flags (retry in config.yaml), as for 'connect'.\n\n" +
+			"A device disconnected with bt-manage (disconnect, toggle, switch, ...) is left alone until it\n" +
+			"is connected again, so keepalive never fights you. Every action is logged to stderr.\n\n" +
+			"'bt-manage daemon --keepalive' does the same inside the daemon.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := keepaliveParams(cmd, "interval", e.keepalive, e.retry, args)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			k := &core.Keeper{Bluetooth: e.bluetooth, Holds: e.holds, Log: cmd.ErrOrStderr()}
+			return explainPoweredOff(e, k.Run(ctx, p))
+		},
+	}
+
+	cmd.Flags().BoolP("exact", "e", false, "Match device names exactly")
+	addKeepaliveFlags(cmd, "interval")
+	return cmd
+}
+
+// addKeepaliveFlags adds the flags shared by keepalive and daemon --keepalive,
+// whose --interval is taken, and the retry flags. Like the retry flags, they
+// only apply when given, so that config.yaml applies otherwise.
+func addKeepaliveFlags(cmd *cobra.Command, interval string) {
+	cmd.Flags().Duration(interval, 0, "How often the devices are checked (default 5s)")
+	cmd.Flags().Duration("backoff", 0, "Pause after the first failed reconnect round (default 2s)")
+	cmd.Flags().Duration("max-backoff", 0, "Longest pause between reconnect rounds (default 1m)")
+	cmd.Flags().Duration("budget", 0, "How long an outage is retried before giving up (default 5m)")
+	cmd.Flags().Bool("repair", false, "Repair a device once the budget is spent")
+	addRetryFlags(cmd, core.DefaultRetryAttempts)
+}
+
+// keepaliveParams merges the keepalive flags over cfg, and the retry flags
+// over retry. Names come from args, or else from cfg.
+func keepaliveParams(cmd *cobra.Command, interval string, cfg config.KeepaliveConfig, retry config.RetryConfig, args []string) (core.KeepaliveParams, error) {
+	p := core.KeepaliveParams{
+		Names:      cfg.Devices,
+		Interval:   cfg.Interval,
+		Backoff:    cfg.Backoff,
+		MaxBackoff: cfg.MaxBackoff,
+		Budget:     cfg.Budget,
+		Repair:     cfg.Repair,
+	}
+	if len(args) > 0 {
+		p.Names = args
+	}
+	if len(p.Names) == 0 {
+		return p, fmt.Errorf("no devices to keep connected: give names, or set keepalive.devices in config.yaml")
+	}
+	if cmd.Flags().Lookup("exact") != nil {
+		p.Exact, _ = cmd.Flags().GetBool("exact")
+	}
+	for _, f := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{interval, &p.Interval},
+		{"backoff", &p.Backoff},
+		{"max-backoff", &p.MaxBackoff},
+		{"budget", &p.Budget},
+	} {
+		if !cmd.Flags().Changed(f.name) {
+			continue
+		}
+		d, _ := cmd.Flags().GetDuration(f.name)
+		if d <= 0 {
+			return p, fmt.Errorf("--%s must be positive", f.name)
+		}
+		*f.dst = d
+	}
+	if cmd.Flags().Changed("repair") {
+		p.Repair, _ = cmd.Flags().GetBool("repair")
+	}
+	var err error
+	p.Retry, err = retryPolicy(cmd, retry)
+	return p, err
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestKeepaliveParams(t *testing.T) {
+	cfg := config.KeepaliveConfig{Devices: []string{"trackpad"}, Budget: 10 * time.Minute, Backoff: 3 * time.Second, Repair: true}
+	retry := config.RetryConfig{MaxAttempts: 5, Backoff: time.Second}
+	parse := func(args ...string) (core.KeepaliveParams, error) {
+		t.Helper()
+		cmd := newKeepaliveCmd(env{})
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return keepaliveParams(cmd, "interval", cfg, retry, cmd.Flags().Args())
+	}
+
+	// config.yaml applies where no flag is given.
+	got, err := parse("--budget", "1m", "--repair=false")
+	if err != nil || strings.Join(got.Names, ",") != "trackpad" || got.Budget != time.Minute || got.Backoff != 3*time.Second || got.Repair {
+		t.Fatalf("flags over config: %+v, %v", got, err)
+	}
+	// So does retry in config.yaml, under the retry flags.
+	if got.Retry.MaxAttempts != 5 || got.Retry.Backoff != time.Second {
+		t.Fatalf("retry from config: %+v", got.Retry)
+	}
+	if got, err := parse("--max-attempts", "2"); err != nil || got.Retry.MaxAttempts != 2 || got.Retry.Backoff != time.Second {
+		t.Fatalf("retry flags over config: %+v, %v", got.Retry, err)
+	}
+	// Names given replace keepalive.devices.
+	if got, err := parse("MX", "Magic"); err != nil || strings.Join(got.Names, ",") != "MX,Magic" {
+		t.Fatalf("names: %+v, %v", got, err)
+	}
+	if _, err := parse("--backoff", "0s"); err == nil {
+		t.Fatal("expected error for a zero --backoff")
+	}
+
+	cfg.Devices = nil
+	if _, err := parse(); err == nil || !strings.Contains(err.Error(), "keepalive.devices") {
+		t.Fatalf("no devices: err = %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/fumihumi/bt-manage/internal/core"
 	"github.com/fumihumi/bt-manage/internal/output"
@@ -19,6 +20,8 @@ func newListCmd(e env) *cobra.Command {
 			noHeader, _ := cmd.Flags().GetBool("no-header")
 			onlyConnected, _ := cmd.Flags().GetBool("connected")
 			onlyDisconnected, _ := cmd.Flags().GetBool("disconnected")
+			types, _ := cmd.Flags().GetStringSlice("type")
+			tags, _ := cmd.Flags().GetStringSlice("tag")
 			// Currently `list` always lists paired devices. `--paired` is a compatibility/explicitness flag.
 			_, _ = cmd.Flags().GetBool("paired")
 
@@ -41,7 +44,7 @@ func newListCmd(e env) *cobra.Command {
 				return err
 			}
 
-			l := core.Lister{Bluetooth: e.bluetooth}
+			l := core.Lister{Bluetooth: e.bluetooth, Enricher: e.enricher, ShowHidden: e.showHidden}
 			devices, err := l.ListDevices(context.Background())
 			if err != nil {
 				return err
@@ -67,6 +70,33 @@ func newListCmd(e env) *cobra.Command {
 				devices = filtered
 			}
 
+			if len(types) > 0 {
+				filtered := make([]core.Device, 0, len(devices))
+				for _, d := range devices {
+					for _, t := range types {
+						if strings.EqualFold(d.Type, strings.TrimSpace(t)) {
+							filtered = append(filtered, d)
+							break
+						}
+					}
+				}
+				devices = filtered
+			}
+
+			if len(tags) > 0 {
+				filtered := make([]core.Device, 0, len(devices))
+				for _, d := range devices {
+					meta := core.DeviceMeta{Tags: d.Tags}
+					for _, t := range tags {
+						if meta.HasTag(strings.TrimSpace(t)) {
+							filtered = append(filtered, d)
+							break
+						}
+					}
+				}
+				devices = filtered
+			}
+
 			if namesOnly {
 				for _, d := range devices {
 					if d.Name == "" {
@@ -94,6 +124,8 @@ func newListCmd(e env) *cobra.Command {
 	cmd.Flags().BoolP("connected", "c", false, "Show connected devices only")
 	cmd.Flags().BoolP("disconnected", "d", false, "Show disconnected devices only")
 	cmd.Flags().BoolP("names-only", "N", false, "Print device names only (one per line)")
+	cmd.Flags().StringSliceP("type", "t", nil, "Show devices of these types only (e.g. keyboard,mouse; case-insensitive)")
+	cmd.Flags().StringSlice("tag", nil, "Show devices with any of these tags only (case-insensitive)")
 	cmd.Flags().Bool("paired", true, "List paired devices (default)")
 
 	return cmd
@@ -74,3 +74,29 @@ func TestListConnectedAndDisconnectedAreMutuallyExclusive(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestListTypeFlagFiltersDevices(t *testing.T) {
+	e := env{
+		bluetooth: fakeBluetooth{devices: []core.Device{
+			{Name: "Keys", Address: "AA", Type: "Keyboard"},
+			{Name: "Pods", Address: "BB", Type: "Headphones"},
+			{Name: "Mouse", Address: "CC", Type: "Mouse"},
+			{Name: "Unknown", Address: "DD"},
+		}},
+		isTTY: func() bool { return false },
+	}
+
+	cmd := newListCmd(e)
+	cmd.SetArgs([]string{"--names-only", "--type", "keyboard,MOUSE"})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if got, want := out.String(), "Keys\nMouse\n"; got != want {
+		t.Fatalf("got=%q, want %q", got, want)
+	}
+}
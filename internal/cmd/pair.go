@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/hooks"
 	"github.com/spf13/cobra"
 )
 
@@ -20,12 +22,19 @@ func newPairCmd(e env) *cobra.Command {
 			inquiry, _ := cmd.Flags().GetDuration("inquiry")
 			pin, _ := cmd.Flags().GetString("pin")
 			waitConnect, _ := cmd.Flags().GetDuration("wait-connect")
-			maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+
+			retry, err := retryPolicy(cmd, e.retry)
+			if err != nil {
+				return err
+			}
 
 			isTTY := e.isTTY()
 			if interactive && !isTTY {
 				return fmt.Errorf("--interactive requires a TTY")
 			}
+			if err := powerOnIfRequested(cmd, e); err != nil {
+				return err
+			}
 
 			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
 			defer cancel()
@@ -37,10 +46,11 @@ func newPairCmd(e env) *cobra.Command {
 				InquiryDuration: int(inquiry.Truncate(time.Second).Seconds()),
 				Pin:             pin,
 				WaitConnect:     int(waitConnect.Truncate(time.Second).Seconds()),
-				MaxAttempts:     maxAttempts,
+				Retry:           retry,
 			})
 			if err != nil {
-				return err
+				firePairFailed(e, dev, err)
+				return explainPoweredOff(e, err)
 			}
 
 			fmt.Fprintf(cmd.OutOrStdout(), "paired: %s (%s)\n", dev.Name, dev.Address)
@@ -52,7 +62,19 @@ func newPairCmd(e env) *cobra.Command {
 	cmd.Flags().Duration("inquiry", 60*time.Second, "Inquiry duration (e.g. 60s)")
 	cmd.Flags().String("pin", "", "Optional PIN (if required by pairing)")
 	cmd.Flags().Duration("wait-connect", 10*time.Second, "Total time budget to wait for the device to become connected across retries")
-	cmd.Flags().Int("max-attempts", 6, "Connect retry count")
+	addRetryFlags(cmd, 6)
+	addPowerOnFlag(cmd)
 
 	return cmd
 }
+
+// firePairFailed runs the pair-failed hooks for dev (the device picked for
+// pairing, if any) and waits for them, since the command exits right after.
+func firePairFailed(e env, dev core.Device, err error) {
+	var ce core.ErrCanceled
+	if e.hooks == nil || dev.Address == "" || errors.As(err, &ce) {
+		return
+	}
+	e.hooks.Fire(context.Background(), hooks.Payload{Event: hooks.OnPairFailed, Device: dev, Error: err.Error()})
+	e.hooks.Wait()
+}
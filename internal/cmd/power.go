@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// powerOnTimeout bounds how long --power-on and `power on` wait for the adapter.
+const powerOnTimeout = 10 * time.Second
+
+func (e env) adapterControl() (core.AdapterControl, error) {
+	if e.adapter == nil {
+		return core.AdapterControl{}, fmt.Errorf("backend %s cannot control the Bluetooth adapter", e.backend)
+	}
+	return core.AdapterControl{Adapter: e.adapter}, nil
+}
+
+// powerOnIfRequested implements --power-on for connect/pair/repair.
+func powerOnIfRequested(cmd *cobra.Command, e env) error {
+	if on, _ := cmd.Flags().GetBool("power-on"); !on {
+		return nil
+	}
+	a, err := e.adapterControl()
+	if err != nil {
+		return err
+	}
+	a.ProgressWriter = cmd.ErrOrStderr()
+	return a.PowerOn(context.Background(), powerOnTimeout)
+}
+
+// explainPoweredOff turns a failure caused by a switched-off adapter into core.ErrPoweredOff.
+func explainPoweredOff(e env, err error) error {
+	if err == nil || e.adapter == nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return core.ExplainPoweredOff(ctx, e.adapter, err)
+}
+
+func addPowerOnFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("power-on", false, "Turn Bluetooth on first (if off) and wait until it is ready")
+}
+
+func writeAdapterState(cmd *cobra.Command, s core.AdapterState) error {
+	formatStr, _ := cmd.Flags().GetString("format")
+	noHeader, _ := cmd.Flags().GetBool("no-header")
+	format, err := output.ParseFormat(formatStr)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case output.FormatTSV:
+		return output.WriteAdapterTSV(cmd.OutOrStdout(), s, !noHeader)
+	case output.FormatJSON:
+		return output.WriteAdapterJSON(cmd.OutOrStdout(), s)
+	default:
+		return fmt.Errorf("unsupported format")
+	}
+}
+
+func newPowerCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "power [on|off|toggle|status]",
+		Short:     "Turn Bluetooth on or off",
+		Long:      "Turn the Bluetooth adapter on or off, or show its state (default: status).\nAfter on/off/toggle the new state is printed once the adapter reports it.",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"on", "off", "toggle", "status"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			action := "status"
+			if len(args) == 1 {
+				action = args[0]
+			}
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+
+			a, err := e.adapterControl()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+
+			switch action {
+			case "on":
+				err = a.SetPower(ctx, true, timeout)
+			case "off":
+				err = a.SetPower(ctx, false, timeout)
+			case "toggle":
+				_, err = a.TogglePower(ctx, timeout)
+			}
+			if err != nil {
+				return err
+			}
+
+			s, err := a.State(ctx)
+			if err != nil {
+				return err
+			}
+			return writeAdapterState(cmd, s)
+		},
+	}
+
+	cmd.Flags().Duration("timeout", powerOnTimeout, "How long to wait for the adapter to switch")
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+
+	return cmd
+}
+
+func newDiscoverableCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "discoverable [on|off|status]",
+		Short:     "Make this computer discoverable to other Bluetooth devices",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"on", "off", "status"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := e.adapterControl()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+
+			if len(args) == 1 && args[0] != "status" {
+				if err := a.Adapter.SetDiscoverable(ctx, args[0] == "on"); err != nil {
+					return explainPoweredOff(e, err)
+				}
+			}
+			s, err := a.State(ctx)
+			if err != nil {
+				return err
+			}
+			return writeAdapterState(cmd, s)
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+
+	return cmd
+}
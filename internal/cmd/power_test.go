@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// fakeAdapter is an AdapterPort whose switches flip immediately.
+type fakeAdapter struct {
+	powered, discoverable bool
+}
+
+func (f *fakeAdapter) Power(ctx context.Context) (bool, error) { return f.powered, nil }
+func (f *fakeAdapter) SetPower(ctx context.Context, on bool) error {
+	f.powered = on
+	return nil
+}
+func (f *fakeAdapter) Discoverable(ctx context.Context) (bool, error) { return f.discoverable, nil }
+func (f *fakeAdapter) SetDiscoverable(ctx context.Context, on bool) error {
+	f.discoverable = on
+	return nil
+}
+
+// offConnectBluetooth fails to connect the way blueutil does with Bluetooth off.
+type offConnectBluetooth struct {
+	fakeBluetooth
+	adapter *fakeAdapter
+}
+
+func (f offConnectBluetooth) Connect(ctx context.Context, address string) error {
+	if !f.adapter.powered {
+		return errors.New("blueutil: exit status 1")
+	}
+	return nil
+}
+
+func TestPowerToggle(t *testing.T) {
+	a := &fakeAdapter{}
+	cmd := newPowerCmd(env{bluetooth: fakeBluetooth{}, adapter: a})
+	cmd.SetArgs([]string{"toggle", "--no-header"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !a.powered || !strings.HasPrefix(out.String(), "yes") {
+		t.Fatalf("powered=%v out=%q", a.powered, out.String())
+	}
+}
+
+func TestPowerRequiresAdapterSupport(t *testing.T) {
+	cmd := newPowerCmd(env{bluetooth: fakeBluetooth{}, backend: "fake"})
+	cmd.SetArgs([]string{"on"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "cannot control") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestConnectExplainsPoweredOff(t *testing.T) {
+	a := &fakeAdapter{}
+	e := env{
+		bluetooth: offConnectBluetooth{fakeBluetooth: fakeBluetooth{devices: []core.Device{{Name: "Keys", Address: "AA"}}}, adapter: a},
+		adapter:   a,
+		isTTY:     func() bool { return false },
+	}
+
+	cmd := newConnectCmd(e)
+	cmd.SetArgs([]string{"Keys"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	if !errors.As(err, &core.ErrPoweredOff{}) {
+		t.Fatalf("expected ErrPoweredOff, got %v", err)
+	}
+	if code := exitCodeFor(err); code != exitPoweredOff {
+		t.Fatalf("exit code = %d, want %d", code, exitPoweredOff)
+	}
+
+	cmd = newConnectCmd(e)
+	cmd.SetArgs([]string{"Keys", "--power-on"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() with --power-on error: %v", err)
+	}
+	if !a.powered {
+		t.Fatalf("--power-on should have turned the adapter on")
+	}
+}
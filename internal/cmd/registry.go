@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/spf13/cobra"
+)
+
+// registryEditor builds a RegistryEditor that also offers hidden devices, so
+// that `unhide` (and editing a hidden device) works without --all.
+func (e env) registryEditor(cmd *cobra.Command, name string) (core.RegistryEditor, core.EditParams, error) {
+	if e.registry == nil {
+		return core.RegistryEditor{}, core.EditParams{}, fmt.Errorf("device registry is unavailable (cannot locate the config directory)")
+	}
+	bt := e.bluetooth
+
+	exact, _ := cmd.Flags().GetBool("exact")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	if !cmd.Flags().Changed("interactive") && name == "" {
+		interactive = true
+	}
+	isTTY := e.isTTY()
+	if interactive && !isTTY {
+		return core.RegistryEditor{}, core.EditParams{}, fmt.Errorf("--interactive requires a TTY")
+	}
+	var pk core.PickerPort
+	if interactive && isTTY {
+		pk = e.picker
+		if v, ok := pk.(core.VisiblePicker); ok {
+			pk = v.Picker
+		}
+	}
+
+	return core.RegistryEditor{Bluetooth: bt, Picker: pk, Registry: e.registry}, core.EditParams{
+		Name:        name,
+		Exact:       exact,
+		Interactive: interactive,
+		IsTTY:       isTTY,
+	}, nil
+}
+
+// editDevice applies change to the selected device's registry entry and
+// prints the resulting entry.
+func editDevice(cmd *cobra.Command, e env, name string, change func(r core.Registry, address string, m *core.DeviceMeta) error) error {
+	ed, p, err := e.registryEditor(cmd, name)
+	if err != nil {
+		return err
+	}
+	// Picking may take a while (user interaction); no timeout.
+	dev, m, err := ed.Edit(context.Background(), p, change)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), describeMeta(dev, m))
+	return nil
+}
+
+// describeMeta renders a registry entry as one line, e.g.
+// "Keyboard (aa:bb:cc:dd:ee:ff): alias=desk tags=home,work pinned".
+func describeMeta(d core.Device, m core.DeviceMeta) string {
+	name := d.Name
+	if name == "" {
+		name = "(unknown)"
+	}
+	var parts []string
+	if m.Alias != "" {
+		parts = append(parts, "alias="+m.Alias)
+	}
+	if len(m.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(m.Tags, ","))
+	}
+	if m.Pinned {
+		parts = append(parts, "pinned")
+	}
+	if m.Hidden {
+		parts = append(parts, "hidden")
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "(no registry entry)")
+	}
+	return fmt.Sprintf("%s (%s): %s", name, d.Address, strings.Join(parts, " "))
+}
+
+func addSelectFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("exact", "e", false, "Match device name exactly")
+	cmd.Flags().BoolP("interactive", "i", false, "Always use interactive picker (TTY required)")
+}
+
+func newAliasCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias [<Name>|<Address>] [<Alias>]",
+		Short: "Give a device a short name of your own",
+		Long: "Give a device an alias. The alias is shown instead of the device name and can be\n" +
+			"used wherever a device name is accepted (connect, disconnect, info, ...).\n" +
+			"Aliases are unique (case-insensitive). With --clear the alias is removed.",
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clearAlias, _ := cmd.Flags().GetBool("clear")
+			name, alias := "", ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if len(args) > 1 {
+				alias = strings.TrimSpace(args[1])
+			}
+			switch {
+			case clearAlias && alias != "":
+				return fmt.Errorf("--clear cannot be used with an alias argument")
+			case !clearAlias && alias == "":
+				return fmt.Errorf("an alias is required (or pass --clear)")
+			}
+
+			return editDevice(cmd, e, name, func(r core.Registry, address string, m *core.DeviceMeta) error {
+				if !clearAlias {
+					if err := r.CheckAlias(address, alias); err != nil {
+						return err
+					}
+				}
+				m.Alias = alias
+				return nil
+			})
+		},
+	}
+
+	addSelectFlags(cmd)
+	cmd.Flags().Bool("clear", false, "Remove the alias")
+
+	return cmd
+}
+
+func newTagCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag <Name>|<Address> <Tag>...",
+		Short: "Tag a device (e.g. work, home) for 'list --tag'",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remove, _ := cmd.Flags().GetBool("remove")
+			tags := args[1:]
+			for _, t := range tags {
+				if strings.TrimSpace(t) == "" || strings.ContainsAny(t, ", \t") {
+					return fmt.Errorf("invalid tag %q: tags cannot be empty or contain spaces or commas", t)
+				}
+			}
+			return editDevice(cmd, e, args[0], func(_ core.Registry, _ string, m *core.DeviceMeta) error {
+				if remove {
+					m.RemoveTags(tags...)
+				} else {
+					m.AddTags(tags...)
+				}
+				return nil
+			})
+		},
+	}
+
+	addSelectFlags(cmd)
+	cmd.Flags().Bool("remove", false, "Remove the tags instead of adding them")
+
+	return cmd
+}
+
+// newFlagCmd builds one of pin/unpin/hide/unhide.
+func newFlagCmd(use, short string, set func(m *core.DeviceMeta)) func(env) *cobra.Command {
+	return func(e env) *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   use + " [<Name>|<Address>]",
+			Short: short,
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				name := ""
+				if len(args) == 1 {
+					name = args[0]
+				}
+				return editDevice(cmd, e, name, func(_ core.Registry, _ string, m *core.DeviceMeta) error {
+					set(m)
+					return nil
+				})
+			},
+		}
+		addSelectFlags(cmd)
+		return cmd
+	}
+}
+
+var (
+	newPinCmd = newFlagCmd("pin", "Pin a device to the top of lists and pickers",
+		func(m *core.DeviceMeta) { m.Pinned = true })
+	newUnpinCmd = newFlagCmd("unpin", "Unpin a device",
+		func(m *core.DeviceMeta) { m.Pinned = false })
+	newHideCmd = newFlagCmd("hide", "Hide a device from lists and pickers (show it again with --all)",
+		func(m *core.DeviceMeta) { m.Hidden = true })
+	newUnhideCmd = newFlagCmd("unhide", "Show a hidden device again",
+		func(m *core.DeviceMeta) { m.Hidden = false })
+)
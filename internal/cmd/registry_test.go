@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/spf13/cobra"
+)
+
+type memRegistry struct {
+	reg core.Registry
+}
+
+func (m *memRegistry) Load(ctx context.Context) (core.Registry, error) {
+	out := core.Registry{}
+	for k, v := range m.reg {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (m *memRegistry) Save(ctx context.Context, r core.Registry) error {
+	m.reg = r
+	return nil
+}
+
+func registryEnv(reg *memRegistry, devices ...core.Device) env {
+	loaded, _ := reg.Load(context.Background())
+	return env{
+		bluetooth: core.AnnotatedBluetooth{BluetoothPort: fakeBluetooth{devices: devices}, Registry: loaded},
+		registry:  reg,
+		isTTY:     func() bool { return false },
+	}
+}
+
+// execRegistryCmd runs the command built by newCmd against a fresh env, as
+// each invocation of the binary would, and returns its stdout.
+func execRegistryCmd(reg *memRegistry, devices []core.Device, newCmd func(env) *cobra.Command, args ...string) (string, error) {
+	cmd := newCmd(registryEnv(reg, devices...))
+	cmd.SetArgs(args)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestAliasSetsAndChecksUniqueness(t *testing.T) {
+	reg := &memRegistry{reg: core.Registry{}}
+	devices := []core.Device{
+		{Name: "MX Keys", Address: "AA-BB-CC-DD-EE-01"},
+		{Name: "AirPods Pro", Address: "AA-BB-CC-DD-EE-02"},
+	}
+
+	out, err := execRegistryCmd(reg, devices, newAliasCmd, "MX", "kb")
+	if err != nil {
+		t.Fatalf("alias: %v", err)
+	}
+	if got := reg.reg.Get("aa:bb:cc:dd:ee:01").Alias; got != "kb" {
+		t.Fatalf("alias = %q, want kb", got)
+	}
+	if !strings.Contains(out, "alias=kb") {
+		t.Fatalf("output = %q", out)
+	}
+
+	// Aliases are unique, case-insensitively.
+	if _, err := execRegistryCmd(reg, devices, newAliasCmd, "AirPods", "KB"); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("expected alias conflict, got %v", err)
+	}
+
+	// The alias selects the device; clearing it drops the empty entry.
+	if _, err := execRegistryCmd(reg, devices, newAliasCmd, "kb", "--clear"); err != nil {
+		t.Fatalf("alias --clear: %v", err)
+	}
+	if len(reg.reg) != 0 {
+		t.Fatalf("registry = %+v, want empty", reg.reg)
+	}
+}
+
+func TestTagAndListByTag(t *testing.T) {
+	reg := &memRegistry{reg: core.Registry{}}
+	devices := []core.Device{
+		{Name: "Desk Keyboard", Address: "AA-BB-CC-DD-EE-01"},
+		{Name: "Headphones", Address: "AA-BB-CC-DD-EE-02"},
+	}
+
+	if _, err := execRegistryCmd(reg, devices, newTagCmd, "Desk", "work", "office"); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+	if _, err := execRegistryCmd(reg, devices, newTagCmd, "Desk", "office", "--remove"); err != nil {
+		t.Fatalf("tag --remove: %v", err)
+	}
+	if got := reg.reg.Get("aa:bb:cc:dd:ee:01").Tags; len(got) != 1 || got[0] != "work" {
+		t.Fatalf("tags = %v, want [work]", got)
+	}
+
+	out, err := execRegistryCmd(reg, devices, newListCmd, "--names-only", "--tag", "WORK")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if out != "Desk Keyboard\n" {
+		t.Fatalf("list --tag output = %q", out)
+	}
+}
+
+func TestHideAndUnhide(t *testing.T) {
+	reg := &memRegistry{reg: core.Registry{}}
+	devices := []core.Device{
+		{Name: "Old Speaker", Address: "AA-BB-CC-DD-EE-01"},
+		{Name: "Mouse", Address: "AA-BB-CC-DD-EE-02"},
+	}
+
+	if _, err := execRegistryCmd(reg, devices, newHideCmd, "Old"); err != nil {
+		t.Fatalf("hide: %v", err)
+	}
+	out, err := execRegistryCmd(reg, devices, newListCmd, "--names-only")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if out != "Mouse\n" {
+		t.Fatalf("hidden device listed: %q", out)
+	}
+
+	// unhide finds the device although it is hidden.
+	if _, err := execRegistryCmd(reg, devices, newUnhideCmd, "Old"); err != nil {
+		t.Fatalf("unhide: %v", err)
+	}
+	if len(reg.reg) != 0 {
+		t.Fatalf("registry = %+v, want empty", reg.reg)
+	}
+}
+
+func TestRegistryCommandsRequireRegistry(t *testing.T) {
+	e := env{bluetooth: fakeBluetooth{}, isTTY: func() bool { return false }}
+	cmd := newPinCmd(e)
+	cmd.SetArgs([]string{"AA-BB-CC-DD-EE-01"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected error without a registry")
+	}
+}
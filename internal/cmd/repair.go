@@ -21,12 +21,19 @@ func newRepairCmd(e env) *cobra.Command {
 			pin, _ := cmd.Flags().GetString("pin")
 			skipUnpair, _ := cmd.Flags().GetBool("skip-unpair")
 			waitConnect, _ := cmd.Flags().GetDuration("wait-connect")
-			maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+
+			retry, err := retryPolicy(cmd, e.retry)
+			if err != nil {
+				return err
+			}
 
 			isTTY := e.isTTY()
 			if interactive && !isTTY {
 				return fmt.Errorf("--interactive requires a TTY")
 			}
+			if err := powerOnIfRequested(cmd, e); err != nil {
+				return err
+			}
 
 			// Inquiry/pair/connect may take time.
 			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
@@ -40,10 +47,11 @@ func newRepairCmd(e env) *cobra.Command {
 				Pin:             pin,
 				SkipUnpair:      skipUnpair,
 				WaitConnect:     int(waitConnect.Truncate(time.Second).Seconds()),
-				MaxAttempts:     maxAttempts,
+				Retry:           retry,
 			})
 			if err != nil {
-				return err
+				firePairFailed(e, to, err)
+				return explainPoweredOff(e, err)
 			}
 
 			fmt.Fprintf(cmd.OutOrStdout(), "repaired: %s (%s) -> %s (%s)\n", from.Name, from.Address, to.Name, to.Address)
@@ -56,7 +64,8 @@ func newRepairCmd(e env) *cobra.Command {
 	cmd.Flags().String("pin", "", "Optional PIN (if required by pairing)")
 	cmd.Flags().Bool("skip-unpair", false, "Skip unpair step")
 	cmd.Flags().Duration("wait-connect", 10*time.Second, "Total time budget to wait for the device to become connected across retries")
-	cmd.Flags().Int("max-attempts", 6, "Connect retry count")
+	addRetryFlags(cmd, 6)
+	addPowerOnFlag(cmd)
 
 	return cmd
 }
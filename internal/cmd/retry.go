@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/spf13/cobra"
+)
+
+// addRetryFlags adds the flags of the retry policy, with attempts as the
+// default of --max-attempts.
+func addRetryFlags(cmd *cobra.Command, attempts int) {
+	cmd.Flags().Int("max-attempts", attempts, "Connect attempts (retry.maxAttempts in config.yaml)")
+	cmd.Flags().Duration("retry-backoff", core.DefaultRetryBackoff, "Pause after the first failed attempt, doubled after each one")
+	cmd.Flags().Duration("retry-max-backoff", core.DefaultRetryMaxBackoff, "Longest pause between attempts")
+	cmd.Flags().Float64("retry-jitter", core.DefaultRetryJitter, "Randomize each pause by up to this fraction of it (0 disables)")
+	cmd.Flags().Duration("retry-deadline", 0, "Stop retrying after this long in total (0: no limit)")
+}
+
+// retryPolicy is cfg's policy with the retry flags given applied over it.
+// --max-attempts also applies when cfg doesn't set the attempts, since its
+// default differs between commands.
+func retryPolicy(cmd *cobra.Command, cfg config.RetryConfig) (core.RetryPolicy, error) {
+	p := cfg.Policy()
+	flags := cmd.Flags()
+	if flags.Changed("max-attempts") || p.MaxAttempts == 0 {
+		p.MaxAttempts, _ = flags.GetInt("max-attempts")
+		if p.MaxAttempts < 1 {
+			return p, fmt.Errorf("--max-attempts must be at least 1")
+		}
+	}
+	for _, f := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"retry-backoff", &p.Backoff},
+		{"retry-max-backoff", &p.MaxBackoff},
+		{"retry-deadline", &p.Deadline},
+	} {
+		if !flags.Changed(f.name) {
+			continue
+		}
+		d, _ := flags.GetDuration(f.name)
+		if d < 0 {
+			return p, fmt.Errorf("--%s must not be negative", f.name)
+		}
+		*f.dst = d
+	}
+	if flags.Changed("retry-jitter") {
+		j, _ := flags.GetFloat64("retry-jitter")
+		if j < 0 || j > 1 {
+			return p, fmt.Errorf("--retry-jitter must be between 0 and 1")
+		}
+		p.Jitter = j
+		if j == 0 {
+			p.Jitter = -1 // none
+		}
+	}
+	return p, nil
+}
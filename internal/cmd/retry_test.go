@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestRetryPolicyFlags(t *testing.T) {
+	parse := func(cfg config.RetryConfig, args ...string) (core.RetryPolicy, error) {
+		t.Helper()
+		cmd := newPairCmd(env{})
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return retryPolicy(cmd, cfg)
+	}
+
+	// Without config, the command's default attempts apply.
+	if p, err := parse(config.RetryConfig{}); err != nil || p.MaxAttempts != 6 || p.Deadline != 0 {
+		t.Fatalf("defaults: %+v, %v", p, err)
+	}
+	// Config over the defaults, flags over config.
+	cfg := config.RetryConfig{MaxAttempts: 4, Deadline: time.Minute, Backoff: time.Second}
+	p, err := parse(cfg, "--retry-deadline", "20s", "--retry-jitter", "0")
+	if err != nil || p.MaxAttempts != 4 || p.Deadline != 20*time.Second || p.Backoff != time.Second || p.Jitter >= 0 {
+		t.Fatalf("flags over config: %+v, %v", p, err)
+	}
+	if p, err := parse(cfg, "--max-attempts", "2"); err != nil || p.MaxAttempts != 2 {
+		t.Fatalf("--max-attempts: %+v, %v", p, err)
+	}
+
+	for _, args := range [][]string{{"--max-attempts", "0"}, {"--retry-jitter", "2"}, {"--retry-backoff", "-1s"}} {
+		if _, err := parse(cfg, args...); err == nil {
+			t.Fatalf("%v: expected error", args)
+		}
+	}
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/fumihumi/bt-manage/internal/backend"
+	"github.com/fumihumi/bt-manage/internal/config"
 	"github.com/fumihumi/bt-manage/internal/core"
-	"github.com/fumihumi/bt-manage/internal/platform/macos/blueutil"
+	"github.com/fumihumi/bt-manage/internal/daemon"
+	"github.com/fumihumi/bt-manage/internal/hooks"
 	"github.com/fumihumi/bt-manage/internal/platform/tty"
 	"github.com/fumihumi/bt-manage/internal/tui/picker"
 	"github.com/spf13/cobra"
@@ -13,30 +21,263 @@ import (
 
 type env struct {
 	bluetooth core.BluetoothPort
+	adapter   core.AdapterPort  // nil when the backend can't control the adapter
+	notifier  core.NotifierPort // nil when the backend can only be polled
+	enricher  core.EnricherPort
+	registry  core.RegistryPort // nil when the config directory can't be located
+	groups    []core.ExclusiveGroup
+	hooks     *hooks.Runner // nil when no hooks are configured
+	holds     core.HoldPort // nil when the config directory can't be located
+	keepalive config.KeepaliveConfig
+	retry     config.RetryConfig
 	picker    core.PickerPort
-	isTTY     func() bool
-	verbose   bool
+	// showHidden (--all) keeps hidden devices in lists and pickers.
+	showHidden bool
+	isTTY      func() bool
+	verbose    bool
+	backend    string
 }
 
-func defaultEnv(verbose bool) env {
-	client := blueutil.Client{Verbose: verbose, Logger: os.Stderr}
+// backendPorts are the ports of a backend, before the registry annotates them.
+type backendPorts struct {
+	name     string
+	bt       core.BluetoothPort
+	adapter  core.AdapterPort  // nil when the backend can't control the adapter
+	notifier core.NotifierPort // nil when the backend can only be polled
+	enricher core.EnricherPort // nil when the backend has no extra details
+	daemon   bool              // served by a running daemon
+}
+
+// openBackend resolves the Bluetooth backend from --backend / BT_MANAGE_BACKEND
+// (auto-detected by OS when unset) and opens it in this process.
+func openBackend(cmd *cobra.Command, verbose bool) (backendPorts, error) {
+	b, err := backends.Resolve(context.Background(), selectedBackendName(cmd), runtime.GOOS)
+	if err != nil {
+		return backendPorts{}, err
+	}
+	opts := backend.Options{Verbose: verbose, Logger: os.Stderr}
+	bt, err := b.New(opts)
+	if err != nil {
+		return backendPorts{}, err
+	}
+	p := backendPorts{name: b.Name, bt: bt}
+	p.adapter, _ = bt.(core.AdapterPort)
+	p.notifier, _ = bt.(core.NotifierPort)
+	if b.Enricher != nil {
+		p.enricher = b.Enricher(opts)
+	}
+	return p, nil
+}
+
+// daemonBackend returns the ports of the daemon listening on --socket. ok is
+// false, and commands open the backend themselves, when no daemon is running,
+// --no-daemon / BT_MANAGE_NO_DAEMON is set, or --backend names another backend.
+func daemonBackend(cmd *cobra.Command) (backendPorts, bool) {
+	if noDaemon, _ := cmd.Flags().GetBool("no-daemon"); noDaemon || os.Getenv(envNoDaemon) != "" {
+		return backendPorts{}, false
+	}
+	socket, err := socketPath(cmd)
+	if err != nil {
+		return backendPorts{}, false
+	}
+	st, err := daemon.Probe(context.Background(), socket, time.Second)
+	if err != nil {
+		return backendPorts{}, false
+	}
+	if name := selectedBackendName(cmd); name != "" && name != backend.Auto && name != st.Backend {
+		return backendPorts{}, false
+	}
+
+	c := daemon.Client{Socket: socket}
+	p := backendPorts{name: st.Backend, bt: c, daemon: true}
+	if st.Adapter {
+		p.adapter = c
+	}
+	if st.Enricher {
+		p.enricher = c
+	}
+	return p, true
+}
+
+// socketPath returns --socket, or the default daemon socket.
+func socketPath(cmd *cobra.Command) (string, error) {
+	if p, _ := cmd.Flags().GetString("socket"); p != "" {
+		return p, nil
+	}
+	return daemon.DefaultSocket()
+}
+
+// newEnv builds the command environment on the running daemon's backend, or
+// else on the backend opened by openBackend.
+func newEnv(cmd *cobra.Command) (env, error) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	ports, ok := daemonBackend(cmd)
+	if !ok {
+		var err error
+		if ports, err = openBackend(cmd, verbose); err != nil {
+			return env{}, err
+		}
+	}
+	if verbose {
+		via := ""
+		if ports.daemon {
+			via = " (daemon)"
+		}
+		fmt.Fprintf(os.Stderr, "bt-manage: backend=%s%s\n", ports.name, via)
+	}
+
+	bt := ports.bt
+	var pick core.PickerPort = picker.Picker{}
+	if ports.enricher != nil {
+		pick = core.EnrichingPicker{Picker: pick, Enricher: ports.enricher}
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return env{}, err
+	}
+
+	// The registry (aliases, tags, pinned/hidden) is a convenience: a broken
+	// file must not stop connect from working, so it is only reported.
+	var registry core.RegistryPort
+	reg := core.Registry{}
+	if f, err := config.DefaultRegistryFile(); err == nil {
+		registry = f
+		if reg, err = f.Load(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "bt-manage: %v\n", err)
+			reg = core.Registry{}
+		}
+	}
+	runner, err := newHookRunner(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bt-manage: %v\n", err)
+	}
+
+	// Disconnects made here are deliberate: keepalive must not undo them.
+	var holds core.HoldPort
+	if f, err := config.DefaultHoldFile(); err == nil {
+		holds = f
+		bt = core.HoldingBluetooth{BluetoothPort: bt, Holds: holds}
+	}
+
+	// Hidden devices stay reachable (by address, exact name, groups); only
+	// lists and pickers leave them out.
+	bt = core.AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}
+	// The root's flag, even where a subcommand has a local --all.
+	showHidden, _ := cmd.Root().PersistentFlags().GetBool("all")
+	if !showHidden {
+		pick = core.VisiblePicker{Picker: pick}
+	}
+
 	return env{
-		bluetooth: client,
-		picker:    picker.Picker{},
-		isTTY:     tty.IsInteractive,
-		verbose:   verbose,
+		bluetooth:  bt,
+		adapter:    ports.adapter,
+		notifier:   ports.notifier,
+		enricher:   ports.enricher,
+		registry:   registry,
+		groups:     cfg.ExclusiveGroups(),
+		hooks:      runner,
+		holds:      holds,
+		keepalive:  cfg.Keepalive,
+		retry:      cfg.Retry,
+		picker:     pick,
+		showHidden: showHidden,
+		isTTY:      tty.IsInteractive,
+		verbose:    verbose,
+		backend:    ports.name,
+	}, nil
+}
+
+// loadConfig reads --config, or config.yaml in the config directory. Unlike the
+// registry, a broken config is an error: it may change what connect does.
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		p, err := config.DefaultPath()
+		if err != nil {
+			// Without a home directory there is no config to read.
+			return config.Config{}, nil
+		}
+		path = p
+	}
+	return config.Load(path)
+}
+
+// newHookRunner collects the hooks in the hooks directory and those declared
+// in cfg. Hooks in an unreadable directory are reported and skipped.
+func newHookRunner(cfg config.Config) (*hooks.Runner, error) {
+	declared, err := cfg.HookList()
+	if err != nil {
+		return nil, err
+	}
+	var dirErr error
+	if dir, err := config.Dir(); err == nil {
+		found, err := hooks.LoadDir(filepath.Join(dir, hooks.DirName))
+		dirErr = err
+		declared = append(found, declared...)
+	}
+	if len(declared) == 0 {
+		return nil, dirErr
 	}
+	return &hooks.Runner{
+		Hooks:       declared,
+		Timeout:     cfg.Hooks.Timeout,
+		Concurrency: cfg.Hooks.Concurrency,
+		Log:         os.Stderr,
+	}, dirErr
+}
+
+// envCommands are the subcommands that operate on a Bluetooth env.
+// Their env is built per invocation, after flags are parsed, so that
+// --verbose and --backend apply.
+var envCommands = []func(env) *cobra.Command{
+	newListCmd,
+	newConnectCmd,
+	newDisconnectCmd,
+	newSwitchCmd,
+	newToggleCmd,
+	newPairCmd,
+	newRepairCmd,
+	newInfoCmd,
+	newBatteryCmd,
+	newWatchCmd,
+	newWaitCmd,
+	newKeepaliveCmd,
+	newServeCmd,
+	newExporterCmd,
+	newPowerCmd,
+	newDiscoverableCmd,
+	newAliasCmd,
+	newTagCmd,
+	newPinCmd,
+	newUnpinCmd,
+	newHideCmd,
+	newUnhideCmd,
+}
+
+func backendNames() string {
+	names := []string{backend.Auto}
+	for _, b := range backends.All() {
+		names = append(names, b.Name)
+	}
+	return strings.Join(names, "|")
 }
 
 func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "bt-manage",
-		Short:         "Switch Bluetooth device connections on macOS",
+		Short:         "Switch Bluetooth device connections on macOS and Linux",
 		SilenceErrors: true,
 		SilenceUsage:  true,
 	}
 
 	cmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose logging to stderr")
+	cmd.PersistentFlags().String("config", "", "Configuration file (default: $XDG_CONFIG_HOME/bt-manage/config.yaml)")
+	cmd.PersistentFlags().Bool("all", false, "Include hidden devices (see 'hide')")
+	cmd.PersistentFlags().String("backend", "", fmt.Sprintf("Bluetooth backend (%s; default: $%s or auto)", backendNames(), backend.EnvVar))
+	cmd.PersistentFlags().String("socket", "", fmt.Sprintf("Daemon socket (default: $%s or $XDG_RUNTIME_DIR/bt-manage/daemon.sock)", daemon.SocketEnvVar))
+	cmd.PersistentFlags().Bool("no-daemon", false, fmt.Sprintf("Do not use a running daemon (also $%s)", envNoDaemon))
 
 	// Allow `bt-manage -c` etc to behave like `bt-manage list -c`.
 	// These flags are only used when root falls back to `list`.
@@ -52,8 +293,10 @@ func newRootCmd() *cobra.Command {
 	}
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		verbose, _ := cmd.Flags().GetBool("verbose")
-		e := defaultEnv(verbose)
+		e, err := newEnv(cmd)
+		if err != nil {
+			return err
+		}
 		// `bt-manage` 単体実行は `list` と同義。root のフラグも list に引き継ぐ。
 		listCmd := newListCmd(e)
 		listCmd.SetArgs(args)
@@ -84,42 +327,26 @@ func newRootCmd() *cobra.Command {
 		return listCmd.ExecuteContext(cmd.Context())
 	}
 
-	cmd.AddCommand(
-		newListCmd(defaultEnv(false)),
-		newConnectCmd(defaultEnv(false)),
-		newDisconnectCmd(defaultEnv(false)),
-		newPairCmd(defaultEnv(false)),
-		newRepairCmd(defaultEnv(false)),
-		newVersionCmd(),
-	)
-
-	// 子コマンド実行時の env を verbose に追従させるため、各コマンドの PreRun で env を差し替える。
-	for _, c := range cmd.Commands() {
-		origRunE := c.RunE
-		if origRunE == nil {
-			continue
-		}
+	// 子コマンド実行時の env を --verbose / --backend に追従させるため、実行時に env を解決してコマンドを再生成する。
+	for _, newCmd := range envCommands {
+		newCmd := newCmd
+		c := newCmd(env{})
 		c.RunE = func(cmd2 *cobra.Command, args2 []string) error {
-			verbose, _ := cmd2.Flags().GetBool("verbose")
-			e := defaultEnv(verbose)
-			// コマンド生成時の env を反映するため、ここでは再生成して実行する。
-			switch cmd2.Name() {
-			case "list":
-				return newListCmd(e).RunE(cmd2, args2)
-			case "connect":
-				return newConnectCmd(e).RunE(cmd2, args2)
-			case "disconnect":
-				return newDisconnectCmd(e).RunE(cmd2, args2)
-			case "pair":
-				return newPairCmd(e).RunE(cmd2, args2)
-			case "repair":
-				return newRepairCmd(e).RunE(cmd2, args2)
-			default:
-				return origRunE(cmd2, args2)
+			e, err := newEnv(cmd2)
+			if err != nil {
+				return err
 			}
+			return newCmd(e).RunE(cmd2, args2)
 		}
+		cmd.AddCommand(c)
 	}
 
+	cmd.AddCommand(
+		newDaemonCmd(),
+		newBackendsCmd(),
+		newVersionCmd(),
+	)
+
 	return cmd
 }
 
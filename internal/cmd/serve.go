@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/httpapi"
+	"github.com/fumihumi/bt-manage/internal/rpcapi"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve (--http ADDR | --stdio)",
+		Short: "Serve a local HTTP or JSON-RPC API for integrations",
+		Long: "With --http, serve a REST API with Server-Sent Events on ADDR (e.g. 127.0.0.1:8765), for Stream Deck\n" +
+			"buttons, home automation and the like:\n\n" +
+			"  GET  /devices                    paired devices (like list --format json)\n" +
+			"  GET  /devices/{device}           one device (address, or exact name or alias)\n" +
+			"  POST /devices/{device}/connect   connect (exclusive groups apply)\n" +
+			"  POST /devices/{device}/disconnect\n" +
+			"  POST /pair, POST /repair         start a job: {\"address\": ..., \"pin\": ...}\n" +
+			"  GET  /jobs/{id}                  job status and progress\n" +
+			"  GET  /events                     device changes as SSE (?type=connected,disconnected)\n\n" +
+			"Requests must send \"Authorization: Bearer <token>\". The token is read from --token-file\n" +
+			"(default: <config dir>/api-token), which is created with a random token if missing.\n\n" +
+			"With --stdio, speak JSON-RPC 2.0 (newline-delimited) on stdin/stdout, for editor and launcher\n" +
+			"extensions that keep one bt-manage process running. Methods mirror the commands: list, info,\n" +
+			"connect, disconnect ({\"name\": ..., \"exact\": ...}), pair.start and repair.start (take the bodies\n" +
+			"of POST /pair and /repair, return a job), and job.get ({\"id\": ...}). Jobs report progress as\n" +
+			"\"job.progress\" notifications and end with a \"job.finished\" notification. Serving stops when\n" +
+			"stdin is closed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("http")
+			stdio, _ := cmd.Flags().GetBool("stdio")
+			tokenFile, _ := cmd.Flags().GetString("token-file")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if addr != "" && stdio {
+				return fmt.Errorf("--http and --stdio are mutually exclusive")
+			}
+			if stdio {
+				return serveStdio(cmd, e)
+			}
+			if addr == "" {
+				return fmt.Errorf("--http or --stdio is required (e.g. --http 127.0.0.1:8765)")
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			if tokenFile == "" {
+				p, err := config.DefaultTokenPath()
+				if err != nil {
+					return err
+				}
+				tokenFile = p
+			}
+			token, created, err := config.LoadToken(tokenFile)
+			if err != nil {
+				return err
+			}
+			if created {
+				fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: created API token in %s\n", tokenFile)
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			warnIfReachable(cmd, addr, ln)
+
+			srv := &httpapi.Server{
+				Bluetooth: e.bluetooth,
+				Enricher:  e.enricher,
+				Notifier:  e.notifier,
+				Groups:    e.groups,
+				Token:     token,
+				Interval:  interval,
+				Retry:     e.retry.Policy(),
+				OnPairFailed: func(dev core.Device, err error) {
+					firePairFailed(e, dev, err)
+				},
+				Log: cmd.ErrOrStderr(),
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: serving HTTP API on http://%s (token in %s)\n", ln.Addr(), tokenFile)
+			return srv.Serve(ctx, ln)
+		},
+	}
+
+	cmd.Flags().String("http", "", "Address to serve the HTTP API on, e.g. 127.0.0.1:8765")
+	cmd.Flags().Bool("stdio", false, "Speak JSON-RPC 2.0 on stdin/stdout instead")
+	cmd.Flags().String("token-file", "", "File with the bearer token (default: <config dir>/api-token)")
+	cmd.Flags().Duration("interval", 2*time.Second, "How often devices are re-listed for /events")
+
+	return cmd
+}
+
+// serveStdio serves the JSON-RPC API on stdin/stdout; stdout carries nothing
+// else, so messages go to stderr.
+func serveStdio(cmd *cobra.Command, e env) error {
+	srv := &rpcapi.Server{
+		Bluetooth: e.bluetooth,
+		Enricher:  e.enricher,
+		Groups:    e.groups,
+		Retry:     e.retry.Policy(),
+		OnPairFailed: func(dev core.Device, err error) {
+			firePairFailed(e, dev, err)
+		},
+		Log: cmd.ErrOrStderr(),
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return srv.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+}
+
+// warnIfReachable warns when addr listens on more than the loopback interface.
+func warnIfReachable(cmd *cobra.Command, addr string, ln net.Listener) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); host == "" || (ip != nil && !ip.IsLoopback()) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "bt-manage: warning: %s is reachable from other hosts\n", ln.Addr())
+		}
+	}
+}
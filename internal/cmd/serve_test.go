@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/backend"
+	"github.com/fumihumi/bt-manage/internal/rpc"
+)
+
+func TestServeStdio(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envNoDaemon, "1")
+	t.Setenv(backend.EnvVar, "")
+
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetIn(strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "info", "params": {"name": "MX Keys"}}` + "\n"))
+	root.SetOut(&out)
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"serve", "--stdio", "--backend", "sim"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		rpc.Message
+		Result struct {
+			Name string `json:"name"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil || string(resp.ID) != "1" || resp.Result.Name != "MX Keys" {
+		t.Fatalf("response %q: %v", out.String(), err)
+	}
+
+	root = newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"serve", "--stdio", "--http", "127.0.0.1:0", "--backend", "sim"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("--stdio with --http should fail")
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func newSwitchCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "switch [<From> <To>]",
+		Short: "Switch from one device to another, keeping the first if the second fails",
+		Long: "Switch from one connected device to another (e.g. headphones). <To> is connected and\n" +
+			"verified within --timeout before <From> is disconnected; if that fails, <From> is kept\n" +
+			"(or reconnected if it was dropped). Without arguments both are picked interactively.\n\n" +
+			"The output has one row per device with its role (from/to) and result:\n" +
+			"connected, disconnected, failed, kept, restored, restore-failed or disconnect-error.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("switch takes both <From> and <To>, or neither (to pick interactively)")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var from, to string
+			if len(args) == 2 {
+				from, to = args[0], args[1]
+			}
+
+			exact, _ := cmd.Flags().GetBool("exact")
+			interactive, _ := cmd.Flags().GetBool("interactive")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+
+			if !cmd.Flags().Changed("interactive") && from == "" {
+				interactive = true
+			}
+			if timeout <= 0 {
+				return fmt.Errorf("--timeout must be positive")
+			}
+			retry, err := retryPolicy(cmd, e.retry)
+			if err != nil {
+				return err
+			}
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			isTTY := e.isTTY()
+			if interactive && !isTTY {
+				return fmt.Errorf("--interactive requires a TTY")
+			}
+			var pk core.PickerPort
+			if interactive && isTTY {
+				pk = e.picker
+			}
+
+			if !dryRun {
+				if err := powerOnIfRequested(cmd, e); err != nil {
+					return err
+				}
+			}
+
+			s := core.Switcher{Bluetooth: e.bluetooth, Picker: pk, ProgressWriter: cmd.ErrOrStderr(), Retry: retry}
+			// Picking may take a while (user interaction); no timeout.
+			fromDev, toDev, err := s.Resolve(context.Background(), core.SwitchParams{
+				From:        from,
+				To:          to,
+				Exact:       exact,
+				Interactive: interactive,
+				IsTTY:       isTTY,
+			})
+			if err != nil {
+				return explainPoweredOff(e, err)
+			}
+
+			res := core.SwitchResult{From: core.SwitchOutcome{Device: fromDev}, To: core.SwitchOutcome{Device: toDev}}
+			if !dryRun {
+				res, err = s.Switch(context.Background(), fromDev, toDev, timeout)
+			}
+
+			var werr error
+			switch format {
+			case output.FormatTSV:
+				werr = output.WriteSwitchTSV(cmd.OutOrStdout(), res, !noHeader)
+			case output.FormatJSON:
+				werr = output.WriteSwitchJSON(cmd.OutOrStdout(), res)
+			default:
+				werr = fmt.Errorf("unsupported format")
+			}
+			// A failed switch is still reported above; it exits non-zero.
+			if err != nil {
+				return explainPoweredOff(e, err)
+			}
+			return werr
+		},
+	}
+
+	cmd.Flags().BoolP("exact", "e", false, "Match device names exactly")
+	cmd.Flags().BoolP("interactive", "i", false, "Always use interactive picker (TTY required)")
+	cmd.Flags().BoolP("dry-run", "n", false, "Do not switch; only resolve and print both devices")
+	cmd.Flags().Duration("timeout", 15*time.Second, "Budget for connecting and verifying <To> before rolling back")
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+	addPowerOnFlag(cmd)
+	addRetryFlags(cmd, core.DefaultRetryAttempts)
+
+	return cmd
+}
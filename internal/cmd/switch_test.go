@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// failingConnectBluetooth fails every connect and reports the listed
+// connected devices as connected.
+type failingConnectBluetooth struct {
+	fakeBluetooth
+}
+
+func (f failingConnectBluetooth) Connect(ctx context.Context, address string) error {
+	return errors.New("page timeout")
+}
+
+func (f failingConnectBluetooth) IsConnected(ctx context.Context, address string) (bool, error) {
+	for _, d := range f.devices {
+		if d.Address == address {
+			return d.Connected, nil
+		}
+	}
+	return false, nil
+}
+
+func TestSwitchReportsRollback(t *testing.T) {
+	e := env{
+		bluetooth: failingConnectBluetooth{fakeBluetooth{devices: []core.Device{
+			{Name: "Headphones A", Address: "AA-00-00-00-00-01", Connected: true},
+			{Name: "Headphones B", Address: "AA-00-00-00-00-02"},
+		}}},
+		isTTY: func() bool { return false },
+	}
+
+	cmd := newSwitchCmd(e)
+	cmd.SilenceUsage = true
+	cmd.SetArgs([]string{"Headphones A", "Headphones B", "--timeout", "1s", "--no-header"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "previous device kept") {
+		t.Fatalf("err = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "from") || !strings.Contains(lines[0], "kept") ||
+		!strings.Contains(lines[1], "to") || !strings.Contains(lines[1], "failed") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
+
+func TestSwitchRequiresBothOrNeither(t *testing.T) {
+	cmd := newSwitchCmd(env{isTTY: func() bool { return false }})
+	cmd.SetArgs([]string{"Headphones A"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected error for a single argument")
+	}
+}
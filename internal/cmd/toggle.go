@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func newToggleCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "toggle <Name>",
+		Short: "Connect a device if it is disconnected, disconnect it if it is connected",
+		Long: "Connect a device if it is disconnected and disconnect it if it is connected; meant for\n" +
+			"hotkey bindings. The device is matched like 'connect' does. Without a TTY an ambiguous\n" +
+			"name fails immediately instead of opening the picker. The new state is printed\n" +
+			"(json: the device with \"action\", like 'connect').",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			exact, _ := cmd.Flags().GetBool("exact")
+			interactive, _ := cmd.Flags().GetBool("interactive")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			verify, _ := cmd.Flags().GetBool("verify")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+
+			if !cmd.Flags().Changed("interactive") && name == "" {
+				interactive = true
+			}
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			isTTY := e.isTTY()
+			if interactive && !isTTY {
+				return fmt.Errorf("--interactive requires a TTY")
+			}
+			var pk core.PickerPort
+			if interactive && isTTY {
+				pk = e.picker
+			}
+
+			if !dryRun {
+				if err := powerOnIfRequested(cmd, e); err != nil {
+					return err
+				}
+			}
+
+			ctx := context.Background()
+			if pk == nil {
+				// No picker to wait for: bound the whole toggle, so a hotkey never hangs.
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout+10*time.Second)
+				defer cancel()
+			}
+
+			params := core.ToggleParams{
+				Name:        name,
+				Exact:       exact,
+				Interactive: interactive,
+				IsTTY:       isTTY,
+				DryRun:      dryRun,
+			}
+			if verify {
+				params.Verify = timeout
+			}
+			t := core.Toggler{Bluetooth: e.bluetooth, Picker: pk, Groups: e.groups}
+			res, err := t.Toggle(ctx, params)
+			if err != nil && res.Device.Address == "" {
+				return explainPoweredOff(e, err)
+			}
+
+			var connected, disconnected []core.Device
+			if res.Device.Connected {
+				connected = []core.Device{res.Device}
+			} else {
+				disconnected = []core.Device{res.Device}
+			}
+			disconnected = append(disconnected, res.Displaced...)
+
+			var werr error
+			switch format {
+			case output.FormatTSV:
+				werr = output.WriteStateTSV(cmd.OutOrStdout(), append(connected, disconnected...), !noHeader)
+			case output.FormatJSON:
+				werr = output.WriteConnectJSON(cmd.OutOrStdout(), connected, disconnected)
+			default:
+				werr = fmt.Errorf("unsupported format")
+			}
+			if err != nil {
+				return err
+			}
+			return werr
+		},
+	}
+
+	cmd.Flags().BoolP("exact", "e", false, "Match device name exactly")
+	cmd.Flags().BoolP("interactive", "i", false, "Always use interactive picker (TTY required)")
+	cmd.Flags().BoolP("dry-run", "n", false, "Do not toggle; only print the state the device would have")
+	cmd.Flags().Bool("verify", false, "Wait until the device reports the new state (up to --timeout)")
+	cmd.Flags().Duration("timeout", 10*time.Second, "How long --verify waits for the new state")
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+	addPowerOnFlag(cmd)
+
+	return cmd
+}
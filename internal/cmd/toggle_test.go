@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestToggleAmbiguousFailsWithoutTTY(t *testing.T) {
+	e := env{
+		bluetooth: fakeBluetooth{devices: []core.Device{
+			{Name: "Headphones A", Address: "AA-00-00-00-00-01"},
+			{Name: "Headphones B", Address: "AA-00-00-00-00-02"},
+		}},
+		isTTY: func() bool { return false },
+	}
+
+	cmd := newToggleCmd(e)
+	cmd.SetArgs([]string{"Headphones"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err := cmd.Execute()
+	var am core.ErrAmbiguous
+	if !errors.As(err, &am) {
+		t.Fatalf("err = %v, want ErrAmbiguous", err)
+	}
+}
+
+func TestToggleConnectsDisconnectedDevice(t *testing.T) {
+	e := env{
+		bluetooth: fakeBluetooth{devices: []core.Device{{Name: "Headphones A", Address: "AA-00-00-00-00-01"}}},
+		isTTY:     func() bool { return false },
+	}
+
+	cmd := newToggleCmd(e)
+	cmd.SetArgs([]string{"Headphones", "--no-header"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "connected") || strings.Contains(out.String(), "disconnected") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
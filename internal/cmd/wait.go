@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func newWaitCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait <Name>... [--connected|--disconnected]",
+		Short: "Wait until devices are connected or disconnected",
+		Long: "Block until a device is connected (default) or disconnected, for scripts that must not go on\n" +
+			"before e.g. a headset is really there. Each name is matched like 'connect' does; a name that\n" +
+			"matches no device, or several, fails right away.\n\n" +
+			"With several names, --any (default) returns once one of them is in the state and --every once\n" +
+			"every one is. The devices in the state are printed.\n\n" +
+			"Exit codes: 0 when the state is reached, 7 after --timeout, 8 when a name matches no device,\n" +
+			"2 when it matches several.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exact, _ := cmd.Flags().GetBool("exact")
+			connected, _ := cmd.Flags().GetBool("connected")
+			disconnected, _ := cmd.Flags().GetBool("disconnected")
+			anyFlag, _ := cmd.Flags().GetBool("any")
+			every, _ := cmd.Flags().GetBool("every")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			formatStr, _ := cmd.Flags().GetString("format")
+			noHeader, _ := cmd.Flags().GetBool("no-header")
+
+			if connected && disconnected {
+				return fmt.Errorf("--connected and --disconnected are mutually exclusive")
+			}
+			if anyFlag && every {
+				return fmt.Errorf("--any and --every are mutually exclusive")
+			}
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			format, err := output.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			w := core.Waiter{Bluetooth: e.bluetooth, PollInterval: interval}
+			devices, err := w.Wait(ctx, core.WaitParams{
+				Names:        args,
+				Exact:        exact,
+				Disconnected: disconnected,
+				All:          every,
+				Timeout:      timeout,
+			})
+			if err != nil {
+				var to core.ErrTimeout
+				if errors.As(err, &to) {
+					return err // keep the timeout exit code even with Bluetooth off
+				}
+				return explainPoweredOff(e, err)
+			}
+
+			switch format {
+			case output.FormatTSV:
+				return output.WriteStateTSV(cmd.OutOrStdout(), devices, !noHeader)
+			case output.FormatJSON:
+				return output.WriteJSON(cmd.OutOrStdout(), devices)
+			default:
+				return fmt.Errorf("unsupported format")
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("exact", "e", false, "Match device names exactly")
+	cmd.Flags().BoolP("connected", "c", false, "Wait until connected (default)")
+	cmd.Flags().BoolP("disconnected", "d", false, "Wait until disconnected")
+	cmd.Flags().Bool("any", false, "Return once any of the devices is in the state (default)")
+	// Not --all: that is the root's include-hidden flag.
+	cmd.Flags().Bool("every", false, "Return once every one of the devices is in the state")
+	cmd.Flags().DurationP("timeout", "t", 30*time.Second, "Give up after this long (exit code 7; 0 waits forever)")
+	cmd.Flags().Duration("interval", 500*time.Millisecond, "How often the devices are checked")
+	cmd.Flags().StringP("format", "f", "tsv", "Output format (tsv|json)")
+	cmd.Flags().BoolP("no-header", "H", false, "Do not print header (tsv only)")
+
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestWaitExitCodes(t *testing.T) {
+	e := env{
+		bluetooth: fakeBluetooth{devices: []core.Device{
+			{Name: "MX Keys", Address: "AA-00-00-00-00-01"},
+			{Name: "Magic Trackpad", Address: "AA-00-00-00-00-02"},
+		}},
+		isTTY: func() bool { return false },
+	}
+	run := func(args ...string) (string, int) {
+		t.Helper()
+		cmd := newWaitCmd(e)
+		cmd.SetArgs(args)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		code := exitCodeFor(cmd.Execute())
+		return out.String(), code
+	}
+
+	// The fake never reports a device as connected.
+	if out, code := run("MX", "Magic", "--every", "--disconnected", "-H"); code != exitOK ||
+		!strings.Contains(out, "MX Keys") || !strings.Contains(out, "Magic Trackpad") {
+		t.Fatalf("--disconnected: code %d, output %q", code, out)
+	}
+	if out, code := run("MX", "--timeout", "30ms", "--interval", "10ms"); code != exitTimeout || strings.Contains(out, "MX Keys") {
+		t.Fatalf("--connected: code %d, output %q", code, out)
+	}
+	if _, code := run("MX", "Nope"); code != exitNotFound {
+		t.Fatalf("unknown name: code %d", code)
+	}
+	if _, code := run("M"); code != exitUsage {
+		t.Fatalf("ambiguous name: code %d", code)
+	}
+	if _, code := run("MX", "--any", "--every"); code != exitUsage {
+		t.Fatalf("--any --every: code %d", code)
+	}
+
+	// A hidden device is matched by its full name or address, not a prefix.
+	reg := core.Registry{}
+	reg.Set("AA-00-00-00-00-02", core.DeviceMeta{Hidden: true})
+	e.bluetooth = core.AnnotatedBluetooth{BluetoothPort: e.bluetooth, Registry: reg}
+	if out, code := run("Magic Trackpad", "--disconnected", "-H"); code != exitOK || !strings.Contains(out, "Magic Trackpad") {
+		t.Fatalf("hidden by name: code %d, output %q", code, out)
+	}
+	if out, code := run("aa:00:00:00:00:02", "MX", "--disconnected", "-H"); code != exitOK || !strings.Contains(out, "Magic Trackpad") {
+		t.Fatalf("hidden by address: code %d, output %q", code, out)
+	}
+	if _, code := run("Mag", "--disconnected"); code != exitNotFound {
+		t.Fatalf("hidden by prefix: code %d", code)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/hooks"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// errUntilMet stops watching once a --until condition matched.
+var errUntilMet = errors.New("until condition met")
+
+func newWatchCmd(e env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Stream device connection changes",
+		Long: "Watch paired devices and print an event whenever one changes: connected, disconnected,\n" +
+			"appeared, removed, rssi-changed or battery-changed. Devices are re-listed every --interval,\n" +
+			"and right away when the backend reports a change (BlueZ).\n\n" +
+			"--format text prints one line per event; ndjson prints one JSON object per line.\n\n" +
+			"--until exits after the first event matching a condition (repeat the flag for any of several),\n" +
+			"whether or not --type prints it:\n" +
+			"  <type>[:<device>]      e.g. connected:AirPods, disconnected, removed:aa:bb:cc:dd:ee:ff\n" +
+			"  battery<N[:<device>]   a battery level dropped below N%\n" +
+			"  rssi<N, rssi>N         RSSI crossed N dBm\n" +
+			"<device> is a name or alias prefix, or an address.\n\n" +
+			"--hooks runs the connect/disconnect hooks (executables in <config dir>/hooks/on-connect.d/\n" +
+			"and on-disconnect.d/, and hooks.commands in config.yaml) for the events seen.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formatStr, _ := cmd.Flags().GetString("format")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			rssiDelta, _ := cmd.Flags().GetInt("rssi-delta")
+			untilStrs, _ := cmd.Flags().GetStringArray("until")
+			types, _ := cmd.Flags().GetStringSlice("type")
+			runHooks, _ := cmd.Flags().GetBool("hooks")
+
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			write, err := eventWriter(formatStr)
+			if err != nil {
+				return err
+			}
+			until := make([]core.Condition, 0, len(untilStrs))
+			for _, s := range untilStrs {
+				c, err := core.ParseCondition(s)
+				if err != nil {
+					return err
+				}
+				until = append(until, c)
+			}
+			show := map[core.EventType]bool{}
+			for _, t := range types {
+				c, err := core.ParseCondition(strings.TrimSpace(t))
+				if err != nil || c.Type == "" || c.Device != "" {
+					return fmt.Errorf("unknown event type: %s", t)
+				}
+				show[c.Type] = true
+			}
+
+			if runHooks && e.hooks == nil {
+				return fmt.Errorf("--hooks: no hooks configured (see 'bt-manage watch --help')")
+			}
+			if runHooks {
+				// Let hooks started by the last events finish before exiting.
+				defer e.hooks.Wait()
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+
+			out := cmd.OutOrStdout()
+			w := core.Watcher{
+				Bluetooth:    e.bluetooth,
+				Notifier:     e.notifier,
+				Enricher:     e.enricher,
+				Interval:     interval,
+				MinRSSIDelta: rssiDelta,
+				OnError: func(err error) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "watch: %v\n", userFacingError(err))
+				},
+			}
+			err = w.Watch(ctx, nil, func(ev core.Event) error {
+				if runHooks {
+					if he, ok := hooks.FromWatch(ev.Type); ok {
+						e.hooks.Fire(ctx, hooks.Payload{Event: he, Time: ev.Time, Device: ev.Device})
+					}
+				}
+				// --type and hidden devices only limit what is printed;
+				// --until sees every event.
+				if (len(show) == 0 || show[ev.Type]) && (!ev.Device.Hidden || e.showHidden) {
+					if err := write(out, ev); err != nil {
+						return err
+					}
+				}
+				for _, c := range until {
+					if c.Match(ev) {
+						return errUntilMet
+					}
+				}
+				return nil
+			})
+			if errors.Is(err, errUntilMet) {
+				return nil
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "text", "Output format (text|ndjson)")
+	cmd.Flags().Duration("interval", 2*time.Second, "How often devices are re-listed")
+	cmd.Flags().Int("rssi-delta", 5, "Smallest RSSI change (dBm) reported as rssi-changed")
+	cmd.Flags().StringArray("until", nil, "Exit after the first event matching this condition (repeatable)")
+	cmd.Flags().StringSlice("type", nil, "Only print these event types (e.g. connected,disconnected)")
+	cmd.Flags().Bool("hooks", false, "Run connect/disconnect hooks for the events seen")
+
+	return cmd
+}
+
+func eventWriter(format string) (func(io.Writer, core.Event) error, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return output.WriteEventText, nil
+	case "ndjson", "json":
+		return output.WriteEventJSON, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s (want text or ndjson)", format)
+	}
+}
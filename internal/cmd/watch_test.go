@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// scriptedBluetooth returns the snapshots in turn from List, then keeps
+// returning the last one.
+type scriptedBluetooth struct {
+	fakeBluetooth
+	mu        sync.Mutex
+	snapshots [][]core.Device
+}
+
+func (s *scriptedBluetooth) List(ctx context.Context) ([]core.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.snapshots[0]
+	if len(s.snapshots) > 1 {
+		s.snapshots = s.snapshots[1:]
+	}
+	return append([]core.Device(nil), next...), nil
+}
+
+func TestWatchUntil(t *testing.T) {
+	a := core.Device{Name: "Headphones A", Address: "AA-00-00-00-00-01", Connected: true}
+	b := core.Device{Name: "Headphones B", Address: "AA-00-00-00-00-02"}
+	bOn := b
+	bOn.Connected = true
+	aOff := a
+	aOff.Connected = false
+
+	e := env{
+		bluetooth: &scriptedBluetooth{snapshots: [][]core.Device{
+			{a, b},
+			{aOff, b},
+			{aOff, bOn},
+		}},
+		isTTY: func() bool { return false },
+	}
+
+	cmd := newWatchCmd(e)
+	cmd.SetArgs([]string{"--format", "ndjson", "--interval", "10ms", "--until", "connected:Headphones B"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output = %q", out.String())
+	}
+	var ev struct {
+		Type   string      `json:"type"`
+		Device core.Device `json:"device"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil || ev.Type != "disconnected" || ev.Device.Name != "Headphones A" {
+		t.Fatalf("first event = %q (%v)", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil || ev.Type != "connected" || ev.Device.Name != "Headphones B" {
+		t.Fatalf("second event = %q (%v)", lines[1], err)
+	}
+}
+
+func TestWatchRejectsBadCondition(t *testing.T) {
+	cmd := newWatchCmd(env{isTTY: func() bool { return false }})
+	cmd.SetArgs([]string{"--until", "plugged-in"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// scriptedEnricher returns the battery levels in turn from Details, then
+// keeps returning the last one.
+type scriptedEnricher struct {
+	mu      sync.Mutex
+	address string
+	levels  []int
+}
+
+func (s *scriptedEnricher) Details(ctx context.Context) ([]core.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	level := s.levels[0]
+	if len(s.levels) > 1 {
+		s.levels = s.levels[1:]
+	}
+	return []core.Device{{Address: s.address, Battery: &core.Battery{Main: &level}}}, nil
+}
+
+func TestWatchUntilBatteryFromEnricher(t *testing.T) {
+	trackpad := core.Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:03", Connected: true}
+	e := env{
+		// The backend itself reports no battery, like blueutil.
+		bluetooth: &scriptedBluetooth{snapshots: [][]core.Device{{trackpad}}},
+		enricher:  &scriptedEnricher{address: trackpad.Address, levels: []int{40, 20, 12}},
+		isTTY:     func() bool { return false },
+	}
+
+	cmd := newWatchCmd(e)
+	cmd.SetArgs([]string{"--format", "ndjson", "--interval", "10ms", "--until", "battery<15"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	// Without battery events the condition never holds; don't hang.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output = %q", out.String())
+	}
+	var ev struct {
+		Type   string      `json:"type"`
+		Device core.Device `json:"device"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil || ev.Type != "battery-changed" ||
+		ev.Device.Battery == nil || ev.Device.Battery.Main == nil || *ev.Device.Battery.Main != 12 {
+		t.Fatalf("last event = %q (%v)", lines[1], err)
+	}
+}
+
+func TestWatchUntilIgnoresTypeFilter(t *testing.T) {
+	trackpad := core.Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:03"}
+	on := trackpad
+	on.Connected = true
+	e := env{
+		bluetooth: &scriptedBluetooth{snapshots: [][]core.Device{{trackpad}, {on}}},
+		enricher:  &scriptedEnricher{address: trackpad.Address, levels: []int{40, 40, 30, 12}},
+		isTTY:     func() bool { return false },
+	}
+
+	cmd := newWatchCmd(e)
+	cmd.SetArgs([]string{"--format", "ndjson", "--interval", "10ms", "--type", "connected", "--until", "battery<20"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("--until never matched the battery event hidden by --type")
+	}
+
+	// Only the connected event is printed, though the battery one ended the watch.
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var ev struct {
+		Type string `json:"type"`
+	}
+	if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &ev) != nil || ev.Type != "connected" {
+		t.Fatalf("output = %q", out.String())
+	}
+}
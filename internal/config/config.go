@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/hooks"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the main configuration file inside Dir. Unlike the registry it
+// is only read; users edit it by hand.
+const FileName = "config.yaml"
+
+// Config is the content of config.yaml:
+//
+//	groups:
+//	  keyboards: ["aa:bb:cc:dd:ee:01", "MX Keys"]
+//	  headsets: [desk-headset, AirPods Pro]
+//	hooks:
+//	  timeout: 30s
+//	  concurrency: 4
+//	  commands:
+//	    - on: connect
+//	      device: AirPods
+//	      run: SwitchAudioSource -s "$BT_MANAGE_DEVICE_NAME"
+//	keepalive:
+//	  devices: [trackpad, MX Keys]
+//	  budget: 10m
+//	  repair: true
+//	retry:
+//	  maxAttempts: 4
+//	  deadline: 30s
+type Config struct {
+	// Groups maps a group name to its members (addresses, aliases or exact names).
+	Groups    map[string][]string `yaml:"groups"`
+	Hooks     HooksConfig         `yaml:"hooks"`
+	Keepalive KeepaliveConfig     `yaml:"keepalive"`
+	Retry     RetryConfig         `yaml:"retry"`
+}
+
+// RetryConfig is the default retry policy of connect, pair, repair and the
+// APIs; flags override it. Zero values use core.RetryPolicy's defaults; a
+// jitter of 0 disables jitter.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+	Jitter      *float64      `yaml:"jitter"`
+	Deadline    time.Duration `yaml:"deadline"`
+}
+
+// KeepaliveConfig is the default of the keepalive command (and daemon
+// --keepalive): the devices to keep connected and how hard to try. Zero
+// durations use the command's defaults.
+type KeepaliveConfig struct {
+	Devices    []string      `yaml:"devices"`
+	Interval   time.Duration `yaml:"interval"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+	Budget     time.Duration `yaml:"budget"`
+	Repair     bool          `yaml:"repair"`
+}
+
+// HooksConfig configures hook execution and declares hook commands
+// (in addition to the executables in the hooks directory).
+type HooksConfig struct {
+	Timeout     time.Duration `yaml:"timeout"`
+	Concurrency int           `yaml:"concurrency"`
+	Commands    []HookCommand `yaml:"commands"`
+}
+
+// HookCommand is one declared hook. Run is a shell command (sh -c); Exec is
+// an argv run directly. Exactly one of them is set.
+type HookCommand struct {
+	On      string        `yaml:"on"`
+	Device  string        `yaml:"device"`
+	Tag     string        `yaml:"tag"`
+	Run     string        `yaml:"run"`
+	Exec    []string      `yaml:"exec"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// DefaultPath is config.yaml in Dir.
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the configuration at path. A missing file is an empty Config.
+func Load(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	for name, members := range c.Groups {
+		if len(members) < 2 {
+			return Config{}, fmt.Errorf("config: %s: group %q needs at least two members", path, name)
+		}
+	}
+	if _, err := c.HookList(); err != nil {
+		return Config{}, fmt.Errorf("config: %s: %w", path, err)
+	}
+	k := c.Keepalive
+	if k.Interval < 0 || k.Backoff < 0 || k.MaxBackoff < 0 || k.Budget < 0 {
+		return Config{}, fmt.Errorf("config: %s: keepalive durations must not be negative", path)
+	}
+	if err := c.Retry.validate(); err != nil {
+		return Config{}, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// HookList returns the declared hook commands.
+func (c Config) HookList() ([]hooks.Hook, error) {
+	out := make([]hooks.Hook, 0, len(c.Hooks.Commands))
+	for i, hc := range c.Hooks.Commands {
+		ev, err := hooks.ParseEvent(hc.On)
+		if err != nil {
+			return nil, fmt.Errorf("hooks.commands[%d]: %w", i, err)
+		}
+		if (hc.Run == "") == (len(hc.Exec) == 0) {
+			return nil, fmt.Errorf("hooks.commands[%d]: set exactly one of run and exec", i)
+		}
+		name := hc.Run
+		if name == "" {
+			name = strings.Join(hc.Exec, " ")
+		}
+		out = append(out, hooks.Hook{
+			Name:    fmt.Sprintf("%q", name),
+			On:      ev,
+			Device:  hc.Device,
+			Tag:     hc.Tag,
+			Command: hc.Exec,
+			Shell:   hc.Run,
+			Timeout: hc.Timeout,
+		})
+	}
+	return out, nil
+}
+
+func (r RetryConfig) validate() error {
+	if r.MaxAttempts < 0 || r.Backoff < 0 || r.MaxBackoff < 0 || r.Deadline < 0 {
+		return fmt.Errorf("retry: values must not be negative")
+	}
+	if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+		return fmt.Errorf("retry.jitter: must be between 0 and 1")
+	}
+	return nil
+}
+
+// Policy returns the configured retry policy.
+func (r RetryConfig) Policy() core.RetryPolicy {
+	p := core.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		Backoff:     r.Backoff,
+		MaxBackoff:  r.MaxBackoff,
+		Deadline:    r.Deadline,
+	}
+	if r.Jitter != nil {
+		p.Jitter = *r.Jitter
+		if p.Jitter == 0 {
+			p.Jitter = -1 // none
+		}
+	}
+	return p
+}
+
+// ExclusiveGroups returns the configured groups, sorted by name.
+func (c Config) ExclusiveGroups() []core.ExclusiveGroup {
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make([]core.ExclusiveGroup, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, core.ExclusiveGroup{Name: name, Members: c.Groups[name]})
+	}
+	return groups
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if c, err := Load(path); err != nil || len(c.Groups) != 0 {
+		t.Fatalf("Load of a missing file = %+v, %v", c, err)
+	}
+
+	if err := os.WriteFile(path, []byte("groups:\n  keyboards: [\"aa:bb:cc:dd:ee:01\", HHKB]\n  headsets: [a, b]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	groups := c.ExclusiveGroups()
+	if len(groups) != 2 || groups[0].Name != "headsets" || groups[1].Members[1] != "HHKB" {
+		t.Fatalf("groups = %+v", groups)
+	}
+
+	if err := os.WriteFile(path, []byte("groups:\n  solo: [a]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for a one-member group")
+	}
+}
+
+func TestLoadConfig_Hooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	doc := `hooks:
+  timeout: 5s
+  commands:
+    - on: connect
+      device: AirPods
+      run: echo "$BT_MANAGE_DEVICE_NAME"
+    - on: on-pair-failed
+      exec: [notify-send, pairing failed]
+      timeout: 1s
+`
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	list, err := c.HookList()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("HookList = %+v, %v", list, err)
+	}
+	if c.Hooks.Timeout.String() != "5s" || list[0].Shell == "" || list[0].Device != "AirPods" || list[1].On != "pair-failed" || len(list[1].Command) != 2 {
+		t.Fatalf("hooks = %+v (timeout %s)", list, c.Hooks.Timeout)
+	}
+
+	if err := os.WriteFile(path, []byte("hooks:\n  commands:\n    - on: plugged\n      run: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for an unknown event")
+	}
+}
+
+func TestLoadConfig_Keepalive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("keepalive:\n  devices: [trackpad]\n  budget: 10m\n  repair: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if k := c.Keepalive; len(k.Devices) != 1 || k.Budget != 10*time.Minute || !k.Repair {
+		t.Fatalf("keepalive = %+v", k)
+	}
+
+	if err := os.WriteFile(path, []byte("keepalive:\n  backoff: -1s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for a negative backoff")
+	}
+}
+
+func TestLoadConfig_Retry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("retry:\n  maxAttempts: 4\n  deadline: 30s\n  jitter: 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p := c.Retry.Policy(); p.MaxAttempts != 4 || p.Deadline != 30*time.Second || p.Jitter >= 0 || p.Backoff != 0 {
+		t.Fatalf("policy = %+v", p)
+	}
+
+	if err := os.WriteFile(path, []byte("retry:\n  jitter: 1.5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for a jitter above 1")
+	}
+}
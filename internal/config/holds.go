@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HoldsFileName is the file inside Dir recording the devices the user
+// disconnected on purpose. It is state, not configuration: bt-manage writes
+// it on every disconnect and connect.
+const HoldsFileName = "holds.json"
+
+// HoldFile implements core.HoldPort with a JSON file mapping addresses to
+// the time they were disconnected:
+//
+//	{"aa:bb:cc:dd:ee:ff": "2026-10-17T09:30:00+09:00"}
+type HoldFile struct {
+	Path string
+}
+
+// DefaultHoldFile is the hold file in Dir.
+func DefaultHoldFile() (HoldFile, error) {
+	dir, err := Dir()
+	if err != nil {
+		return HoldFile{}, err
+	}
+	return HoldFile{Path: filepath.Join(dir, HoldsFileName)}, nil
+}
+
+// holdsMu serializes the read-modify-write of hold files within a process
+// (the daemon and its keepalive share one).
+var holdsMu sync.Mutex
+
+func holdKey(address string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(address), "-", ":"))
+}
+
+func (f HoldFile) load() (map[string]time.Time, error) {
+	holds := map[string]time.Time{}
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return holds, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := json.Unmarshal(b, &holds); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", f.Path, err)
+	}
+	return holds, nil
+}
+
+func (f HoldFile) Hold(ctx context.Context, address string) error {
+	holdsMu.Lock()
+	defer holdsMu.Unlock()
+	holds, err := f.load()
+	if err != nil {
+		return err
+	}
+	holds[holdKey(address)] = time.Now()
+	b, err := json.MarshalIndent(holds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return writeFile(f.Path, append(b, '\n'))
+}
+
+func (f HoldFile) Release(ctx context.Context, address string) error {
+	holdsMu.Lock()
+	defer holdsMu.Unlock()
+	holds, err := f.load()
+	if err != nil {
+		return err
+	}
+	key := holdKey(address)
+	if _, ok := holds[key]; !ok {
+		return nil
+	}
+	delete(holds, key)
+	b, err := json.MarshalIndent(holds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return writeFile(f.Path, append(b, '\n'))
+}
+
+func (f HoldFile) Held(ctx context.Context, address string) (bool, error) {
+	holdsMu.Lock()
+	defer holdsMu.Unlock()
+	holds, err := f.load()
+	if err != nil {
+		return false, err
+	}
+	_, ok := holds[holdKey(address)]
+	return ok, nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// RegistryFileName is the registry file inside Dir.
+const RegistryFileName = "devices.yaml"
+
+// RegistryFile implements core.RegistryPort with a YAML file:
+//
+//	devices:
+//	  "aa:bb:cc:dd:ee:ff":
+//	    alias: trackpad
+//	    tags: [desk]
+//	    pinned: true
+type RegistryFile struct {
+	Path string
+}
+
+// DefaultRegistryFile is the registry in Dir.
+func DefaultRegistryFile() (RegistryFile, error) {
+	dir, err := Dir()
+	if err != nil {
+		return RegistryFile{}, err
+	}
+	return RegistryFile{Path: filepath.Join(dir, RegistryFileName)}, nil
+}
+
+type registryDoc struct {
+	Devices map[string]deviceDoc `yaml:"devices"`
+}
+
+type deviceDoc struct {
+	Alias  string   `yaml:"alias,omitempty"`
+	Tags   []string `yaml:"tags,omitempty,flow"`
+	Pinned bool     `yaml:"pinned,omitempty"`
+	Hidden bool     `yaml:"hidden,omitempty"`
+}
+
+func (f RegistryFile) Load(ctx context.Context) (core.Registry, error) {
+	reg := core.Registry{}
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return reg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	var doc registryDoc
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", f.Path, err)
+	}
+	for addr, d := range doc.Devices {
+		// Set normalizes the address, so hand-edited keys like AA-BB-... work.
+		reg.Set(addr, core.DeviceMeta{Alias: d.Alias, Tags: d.Tags, Pinned: d.Pinned, Hidden: d.Hidden})
+	}
+	return reg, nil
+}
+
+func (f RegistryFile) Save(ctx context.Context, r core.Registry) error {
+	doc := registryDoc{Devices: map[string]deviceDoc{}}
+	for addr, m := range r {
+		doc.Devices[addr] = deviceDoc{Alias: m.Alias, Tags: m.Tags, Pinned: m.Pinned, Hidden: m.Hidden}
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return writeFile(f.Path, b)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestDir_XDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	got, err := Dir()
+	if err != nil || got != "/tmp/xdg/bt-manage" {
+		t.Fatalf("Dir() = %q, %v", got, err)
+	}
+}
+
+func TestRegistryFile_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	f := RegistryFile{Path: filepath.Join(t.TempDir(), "nested", RegistryFileName)}
+
+	reg, err := f.Load(ctx)
+	if err != nil || len(reg) != 0 {
+		t.Fatalf("Load of a missing file = %v, %v", reg, err)
+	}
+
+	reg.Set("AA-BB-CC-DD-EE-FF", core.DeviceMeta{Alias: "trackpad", Tags: []string{"desk"}, Pinned: true})
+	if err := f.Save(ctx, reg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if st, err := os.Stat(f.Path); err != nil || st.Mode().Perm() != 0o600 {
+		t.Fatalf("stat: %v %v", st, err)
+	}
+
+	got, err := f.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	m := got.Get("aa:bb:cc:dd:ee:ff")
+	if m.Alias != "trackpad" || !m.Pinned || !m.HasTag("DESK") {
+		t.Fatalf("got %+v", m)
+	}
+}
+
+func TestRegistryFile_NormalizesHandEditedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RegistryFileName)
+	if err := os.WriteFile(path, []byte("devices:\n  AA-BB-CC-DD-EE-FF:\n    hidden: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := RegistryFile{Path: path}.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reg.Get("aa:bb:cc:dd:ee:ff").Hidden {
+		t.Fatalf("got %+v", reg)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), TokenFileName)
+	token, created, err := LoadToken(path)
+	if err != nil || !created || len(token) != 64 {
+		t.Fatalf("LoadToken (new) = %q, %v, %v", token, created, err)
+	}
+	again, created, err := LoadToken(path)
+	if err != nil || created || again != token {
+		t.Fatalf("LoadToken (existing) = %q, %v, %v", again, created, err)
+	}
+
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadToken(path); err == nil {
+		t.Fatal("a world-readable token file should be refused")
+	}
+}
+
+func TestHoldFile(t *testing.T) {
+	ctx := context.Background()
+	f := HoldFile{Path: filepath.Join(t.TempDir(), HoldsFileName)}
+	if held, err := f.Held(ctx, "aa:bb:cc:dd:ee:ff"); err != nil || held {
+		t.Fatalf("Held of a missing file = %v, %v", held, err)
+	}
+	if err := f.Hold(ctx, "AA-BB-CC-DD-EE-FF"); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	if held, err := f.Held(ctx, "aa:bb:cc:dd:ee:ff"); err != nil || !held {
+		t.Fatalf("Held = %v, %v", held, err)
+	}
+	if err := f.Release(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if held, err := f.Held(ctx, "AA:BB:CC:DD:EE:FF"); err != nil || held {
+		t.Fatalf("Held after Release = %v, %v", held, err)
+	}
+}
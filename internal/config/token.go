@@ -0,0 +1,57 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenFileName is the API token file in the config directory.
+const TokenFileName = "api-token"
+
+// DefaultTokenPath returns the path of api-token in the config directory.
+func DefaultTokenPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, TokenFileName), nil
+}
+
+// LoadToken reads the bearer token clients of the HTTP API must present.
+// A missing file is created with a random token (created is true then).
+// The file must only be accessible by its owner, like an SSH key.
+func LoadToken(path string) (token string, created bool, err error) {
+	st, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", false, fmt.Errorf("config: %w", err)
+		}
+		token = hex.EncodeToString(b)
+		if err := writeFile(path, []byte(token+"\n")); err != nil {
+			return "", false, err
+		}
+		return token, true, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("config: %w", err)
+	}
+	if st.Mode().Perm()&0o077 != 0 {
+		return "", false, fmt.Errorf("config: %s is accessible by other users (chmod 600 it)", path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("config: %w", err)
+	}
+	token = strings.TrimSpace(string(b))
+	if token == "" {
+		return "", false, fmt.Errorf("config: %s is empty", path)
+	}
+	return token, false, nil
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AdapterControl switches the adapter's power and discoverability.
+type AdapterControl struct {
+	Adapter        AdapterPort
+	ProgressWriter io.Writer // optional
+	// PollInterval is how often PowerOn re-reads the power state (default 250ms).
+	PollInterval time.Duration
+}
+
+func (a AdapterControl) progressf(format string, args ...any) {
+	if a.ProgressWriter == nil {
+		return
+	}
+	fmt.Fprintf(a.ProgressWriter, format, args...)
+}
+
+func (a AdapterControl) pollInterval() time.Duration {
+	if a.PollInterval > 0 {
+		return a.PollInterval
+	}
+	return 250 * time.Millisecond
+}
+
+// PowerOn turns the adapter on if it is off and waits until it reports
+// powered, i.e. until it accepts connections. It is a no-op for a powered adapter.
+func (a AdapterControl) PowerOn(ctx context.Context, timeout time.Duration) error {
+	on, err := a.Adapter.Power(ctx)
+	if err != nil {
+		return err
+	}
+	if on {
+		return nil
+	}
+	a.progressf("Turning Bluetooth on...\n")
+	if err := a.Adapter.SetPower(ctx, true); err != nil {
+		return err
+	}
+	return a.waitPower(ctx, true, timeout)
+}
+
+// SetPower switches the adapter and waits until the new state is reported.
+func (a AdapterControl) SetPower(ctx context.Context, on bool, timeout time.Duration) error {
+	if err := a.Adapter.SetPower(ctx, on); err != nil {
+		return err
+	}
+	return a.waitPower(ctx, on, timeout)
+}
+
+// TogglePower flips the power state and returns the new one.
+func (a AdapterControl) TogglePower(ctx context.Context, timeout time.Duration) (bool, error) {
+	on, err := a.Adapter.Power(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := a.SetPower(ctx, !on, timeout); err != nil {
+		return on, err
+	}
+	return !on, nil
+}
+
+func (a AdapterControl) waitPower(ctx context.Context, want bool, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(a.pollInterval())
+	defer ticker.Stop()
+	for {
+		on, err := a.Adapter.Power(ctx)
+		if err == nil && on == want {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for Bluetooth to turn %s", onOff(want))
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExplainPoweredOff replaces err with ErrPoweredOff when the adapter turns out
+// to be off, which backends otherwise report as an opaque failure.
+func ExplainPoweredOff(ctx context.Context, a AdapterPort, err error) error {
+	var ce ErrCanceled
+	if err == nil || a == nil || ctx.Err() != nil || errors.As(err, &ce) {
+		return err
+	}
+	if on, perr := a.Power(ctx); perr == nil && !on {
+		return ErrPoweredOff{}
+	}
+	return err
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// AdapterState is a snapshot of the adapter's switches.
+type AdapterState struct {
+	Powered      bool `json:"powered"`
+	Discoverable bool `json:"discoverable"`
+}
+
+// State reads the power and discoverable switches.
+func (a AdapterControl) State(ctx context.Context) (AdapterState, error) {
+	powered, err := a.Adapter.Power(ctx)
+	if err != nil {
+		return AdapterState{}, err
+	}
+	discoverable, err := a.Adapter.Discoverable(ctx)
+	if err != nil {
+		return AdapterState{}, err
+	}
+	return AdapterState{Powered: powered, Discoverable: discoverable}, nil
+}
@@ -0,0 +1,193 @@
+package core
+
+import "strings"
+
+// ClassOfDevice is the 24-bit Bluetooth Class of Device (CoD) field, laid out
+// per the Bluetooth Assigned Numbers (section 2.8, Class of Device):
+//
+//	bits 23-13  service classes (one bit each)
+//	bits 12-8   major device class
+//	bits 7-2    minor device class (meaning depends on the major class)
+//	bits 1-0    format type (always 0)
+type ClassOfDevice uint32
+
+// Major device classes.
+const (
+	MajorMiscellaneous = 0x00
+	MajorComputer      = 0x01
+	MajorPhone         = 0x02
+	MajorNetwork       = 0x03
+	MajorAudioVideo    = 0x04
+	MajorPeripheral    = 0x05
+	MajorImaging       = 0x06
+	MajorWearable      = 0x07
+	MajorToy           = 0x08
+	MajorHealth        = 0x09
+	MajorUncategorized = 0x1f
+)
+
+func (c ClassOfDevice) Major() int { return int(c>>8) & 0x1f }
+func (c ClassOfDevice) Minor() int { return int(c>>2) & 0x3f }
+
+var majorNames = map[int]string{
+	MajorMiscellaneous: "Miscellaneous",
+	MajorComputer:      "Computer",
+	MajorPhone:         "Phone",
+	MajorNetwork:       "Network Access Point",
+	MajorAudioVideo:    "Audio/Video",
+	MajorPeripheral:    "Peripheral",
+	MajorImaging:       "Imaging",
+	MajorWearable:      "Wearable",
+	MajorToy:           "Toy",
+	MajorHealth:        "Health",
+	MajorUncategorized: "Uncategorized",
+}
+
+// MajorName returns the major device class name ("" if reserved).
+func (c ClassOfDevice) MajorName() string { return majorNames[c.Major()] }
+
+var minorNames = map[int][]string{
+	MajorComputer: {
+		"", "Desktop", "Server", "Laptop", "Handheld PC/PDA", "Palm-size PC/PDA", "Wearable Computer", "Tablet",
+	},
+	MajorPhone: {
+		"", "Cellular", "Cordless", "Smartphone", "Modem", "ISDN Access",
+	},
+	MajorAudioVideo: {
+		"", "Headset", "Hands-free", "", "Microphone", "Speaker", "Headphones", "Portable Audio",
+		"Car Audio", "Set-top Box", "HiFi Audio", "VCR", "Video Camera", "Camcorder", "Video Monitor",
+		"Video Display and Speaker", "Video Conferencing", "", "Gaming/Toy",
+	},
+	MajorWearable: {
+		"", "Wristwatch", "Pager", "Jacket", "Helmet", "Glasses", "Pin",
+	},
+	MajorToy: {
+		"", "Robot", "Vehicle", "Doll", "Controller", "Game",
+	},
+	MajorHealth: {
+		"", "Blood Pressure Monitor", "Thermometer", "Weighing Scale", "Glucose Meter", "Pulse Oximeter",
+		"Heart Rate Monitor", "Health Data Display", "Step Counter", "Body Composition Analyzer",
+		"Peak Flow Monitor", "Medication Monitor", "Knee Prosthesis", "Ankle Prosthesis",
+		"Generic Health Manager", "Personal Mobility Device",
+	},
+}
+
+// Peripheral minor classes are split: bits 7-6 keyboard/pointing, bits 5-2 the device kind.
+var (
+	peripheralInput = []string{"", "Keyboard", "Mouse", "Keyboard/Mouse"}
+	peripheralKind  = []string{
+		"", "Joystick", "Gamepad", "Remote Control", "Sensing Device", "Digitizer Tablet",
+		"Card Reader", "Digital Pen", "Handheld Scanner", "Gesture Input",
+	}
+)
+
+// Imaging minor classes are flags in bits 7-4 (a device may be several at once).
+var imagingFlags = []struct {
+	bit  int
+	name string
+}{{4, "Display"}, {5, "Camera"}, {6, "Scanner"}, {7, "Printer"}}
+
+// MinorName returns the minor device class name ("" if uncategorized or reserved).
+// Pointing devices are reported as "Mouse", the name macOS uses for them; for
+// peripherals the keyboard/pointing bits win over the device kind, so a
+// trackpad (pointing + digitizer) is a "Mouse" too.
+func (c ClassOfDevice) MinorName() string {
+	minor := c.Minor()
+	switch c.Major() {
+	case MajorPeripheral:
+		input, kind := minor>>4, minor&0x0f
+		if input != 0 {
+			return peripheralInput[input]
+		}
+		if kind < len(peripheralKind) {
+			return peripheralKind[kind]
+		}
+		return ""
+	case MajorImaging:
+		names := make([]string, 0, 1)
+		for _, f := range imagingFlags {
+			if c&(1<<f.bit) != 0 {
+				names = append(names, f.name)
+			}
+		}
+		return strings.Join(names, "/")
+	case MajorNetwork:
+		// The minor bits are the network utilisation, not a device kind.
+		return ""
+	}
+	if names, ok := minorNames[c.Major()]; ok && minor < len(names) {
+		return names[minor]
+	}
+	return ""
+}
+
+// DeviceType is a short human-readable type: the minor class when known,
+// otherwise the major class ("" for an unset or reserved class).
+func (c ClassOfDevice) DeviceType() string {
+	if c == 0 {
+		return ""
+	}
+	if n := c.MinorName(); n != "" {
+		return n
+	}
+	switch c.Major() {
+	case MajorMiscellaneous, MajorUncategorized:
+		return ""
+	}
+	return c.MajorName()
+}
+
+var serviceClasses = []struct {
+	bit  int
+	name string
+}{
+	{13, "Limited Discoverable"},
+	{14, "LE Audio"},
+	{16, "Positioning"},
+	{17, "Networking"},
+	{18, "Rendering"},
+	{19, "Capturing"},
+	{20, "Object Transfer"},
+	{21, "Audio"},
+	{22, "Telephony"},
+	{23, "Information"},
+}
+
+// Services returns the service classes advertised in bits 23-13.
+func (c ClassOfDevice) Services() []string {
+	out := make([]string, 0)
+	for _, s := range serviceClasses {
+		if c&(1<<s.bit) != 0 {
+			out = append(out, s.name)
+		}
+	}
+	return out
+}
+
+// iconTypes maps BlueZ's Icon property (freedesktop icon names) to device
+// types. BLE devices usually have no class, but BlueZ derives an icon from
+// their GAP appearance.
+var iconTypes = map[string]string{
+	"audio-card":        "Speaker",
+	"audio-headphones":  "Headphones",
+	"audio-headset":     "Headset",
+	"camera-photo":      "Camera",
+	"camera-video":      "Video Camera",
+	"computer":          "Computer",
+	"input-gaming":      "Gamepad",
+	"input-keyboard":    "Keyboard",
+	"input-mouse":       "Mouse",
+	"input-tablet":      "Digitizer Tablet",
+	"modem":             "Modem",
+	"multimedia-player": "Portable Audio",
+	"network-wireless":  "Network Access Point",
+	"phone":             "Phone",
+	"printer":           "Printer",
+	"scanner":           "Scanner",
+	"video-display":     "Video Monitor",
+}
+
+// TypeFromIcon returns the device type for a BlueZ icon name ("" if unknown).
+func TypeFromIcon(icon string) string {
+	return iconTypes[icon]
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Condition is a predicate on events, written as
+//
+//	<type>[:<device>]        e.g. connected:AirPods, removed, rssi-changed:aa:bb:cc:dd:ee:ff
+//	battery<N[:<device>]     a battery level drops below N percent
+//	rssi<N[:<device>]        RSSI below N dBm (rssi>N: above)
+//
+// <device> is a name or alias prefix, or an address.
+type Condition struct {
+	Type   EventType // "" for battery/rssi thresholds
+	Device string
+
+	metric    string // "battery" or "rssi"
+	less      bool
+	threshold int
+}
+
+// ParseCondition parses a condition (see Condition).
+func ParseCondition(s string) (Condition, error) {
+	s = strings.TrimSpace(s)
+	head, device, _ := strings.Cut(s, ":")
+	c := Condition{Device: strings.TrimSpace(device)}
+
+	for _, metric := range []string{"battery", "rssi"} {
+		rest, ok := strings.CutPrefix(head, metric)
+		if !ok || rest == "" || (rest[0] != '<' && rest[0] != '>') {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			return Condition{}, fmt.Errorf("invalid condition %q: %s needs a number", s, metric)
+		}
+		c.metric, c.less, c.threshold = metric, rest[0] == '<', n
+		return c, nil
+	}
+
+	for _, t := range EventTypes {
+		if strings.EqualFold(head, string(t)) {
+			c.Type = t
+			return c, nil
+		}
+	}
+	return Condition{}, fmt.Errorf("invalid condition %q (want <type>[:<device>], battery<N, rssi<N or rssi>N; types: %s)", s, eventTypeNames())
+}
+
+func eventTypeNames() string {
+	names := make([]string, len(EventTypes))
+	for i, t := range EventTypes {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// MatchDevice reports whether d is the condition's device (any device if none is given).
+func (c Condition) MatchDevice(d Device) bool {
+	if c.Device == "" {
+		return true
+	}
+	return len(findByName([]Device{d}, c.Device, false, true)) == 1
+}
+
+// Match reports whether ev satisfies the condition.
+func (c Condition) Match(ev Event) bool {
+	if !c.MatchDevice(ev.Device) {
+		return false
+	}
+	switch c.metric {
+	case "battery":
+		return ev.Type == EventBatteryChanged && c.MatchState(ev.Device)
+	case "rssi":
+		return ev.Type == EventRSSIChanged && c.MatchState(ev.Device)
+	default:
+		return ev.Type == c.Type
+	}
+}
+
+// MatchState reports whether d currently satisfies a threshold condition,
+// or, for connected/disconnected, is in that state. Other event types only
+// happen as changes and never match a state.
+func (c Condition) MatchState(d Device) bool {
+	if !c.MatchDevice(d) {
+		return false
+	}
+	compare := func(v int) bool {
+		if c.less {
+			return v < c.threshold
+		}
+		return v > c.threshold
+	}
+	switch c.metric {
+	case "battery":
+		if d.Battery == nil {
+			return false
+		}
+		level, ok := d.Battery.Lowest()
+		return ok && compare(level)
+	case "rssi":
+		return d.RSSI != nil && compare(*d.RSSI)
+	}
+	switch c.Type {
+	case EventConnected:
+		return d.Connected
+	case EventDisconnected:
+		return !d.Connected
+	}
+	return false
+}
+
+func (c Condition) String() string {
+	head := string(c.Type)
+	if c.metric != "" {
+		op := ">"
+		if c.less {
+			op = "<"
+		}
+		head = fmt.Sprintf("%s%s%d", c.metric, op, c.threshold)
+	}
+	if c.Device == "" {
+		return head
+	}
+	return head + ":" + c.Device
+}
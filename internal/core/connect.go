@@ -1,13 +1,14 @@
 package core
 
-import (
-	"context"
-	"fmt"
-)
+import "context"
 
 type Connector struct {
 	Bluetooth BluetoothPort
 	Picker    PickerPort
+	// Groups are exclusive groups: connecting a member disconnects the others.
+	Groups []ExclusiveGroup
+	// Retry shapes the connect attempts.
+	Retry RetryPolicy
 }
 
 type ConnectParams struct {
@@ -18,72 +19,56 @@ type ConnectParams struct {
 	DryRun      bool
 }
 
+// ConnectResult is the connected device and the group members it displaced
+// (with DryRun: the device that would be connected and the planned disconnects).
+type ConnectResult struct {
+	Device    Device
+	Displaced []Device
+}
+
 func (c Connector) ConnectByNameOrInteractive(ctx context.Context, p ConnectParams) (Device, error) {
+	r, err := c.Connect(ctx, p)
+	return r.Device, err
+}
+
+// Connect selects and connects a device, then disconnects the other members
+// of its exclusive groups. If only that last step fails, the result is still
+// returned along with the error.
+func (c Connector) Connect(ctx context.Context, p ConnectParams) (ConnectResult, error) {
 	devices, err := c.Bluetooth.List(ctx)
 	if err != nil {
-		return Device{}, err
+		return ConnectResult{}, err
 	}
 
-	if p.Name == "" {
-		if c.Picker == nil {
-			return Device{}, ErrNotFound{Query: ""}
-		}
-		selected, err := c.Picker.PickDevice(ctx, "Connect", devices)
-		if err != nil {
-			return Device{}, err
-		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := c.Bluetooth.Connect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
-		return selected, nil
+	selected, err := resolveDevice(ctx, c.Picker, devices, resolveParams{
+		Title:       "Connect",
+		Name:        p.Name,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return ConnectResult{}, err
 	}
-
-	matches := findByName(devices, p.Name, p.Exact)
-	switch len(matches) {
-	case 0:
-		return Device{}, ErrNotFound{Query: p.Name}
-	case 1:
-		selected := matches[0]
-		if p.Interactive {
-			if !p.IsTTY {
-				return Device{}, fmt.Errorf("interactive mode requires a TTY")
-			}
-			if c.Picker == nil {
-				return Device{}, ErrNotFound{Query: p.Name}
-			}
-			selected2, err := c.Picker.PickDevice(ctx, "Connect", matches)
-			if err != nil {
-				return Device{}, err
-			}
-			selected = selected2
-		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := c.Bluetooth.Connect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
-		return selected, nil
-	default:
-		if !p.IsTTY {
-			return Device{}, ErrAmbiguous{Query: p.Name, Count: len(matches)}
-		}
-		if c.Picker == nil {
-			return Device{}, ErrAmbiguous{Query: p.Name, Count: len(matches)}
-		}
-		selected, err := c.Picker.PickDevice(ctx, "Connect", matches)
+	if p.DryRun {
+		displaced, err := c.Displace(ctx, []Device{selected}, true)
 		if err != nil {
-			return Device{}, err
+			return ConnectResult{}, err
 		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := c.Bluetooth.Connect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
-		return selected, nil
+		return ConnectResult{Device: selected, Displaced: displaced}, nil
+	}
+	if err := c.ConnectDevice(ctx, selected); err != nil {
+		return ConnectResult{}, err
 	}
+	selected.Connected = true
+	displaced, err := c.Displace(ctx, []Device{selected}, false)
+	return ConnectResult{Device: selected, Displaced: displaced}, err
+}
+
+// ConnectDevice connects d, retrying per c.Retry; for callers that resolved
+// the device themselves, e.g. to connect several at once.
+func (c Connector) ConnectDevice(ctx context.Context, d Device) error {
+	return c.Retry.Do(ctx, func(ctx context.Context, attempt int) error {
+		return c.Bluetooth.Connect(ctx, d.Address)
+	})
 }
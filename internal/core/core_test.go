@@ -1,9 +1,15 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type fakeBluetooth struct {
@@ -104,13 +110,15 @@ func (f *fakeBluetooth) ConnectedDevices(ctx context.Context) ([]Device, error)
 }
 
 type fakePicker struct {
-	picked Device
-	err    error
-	calls  int
+	picked  Device
+	err     error
+	calls   int
+	offered []Device
 }
 
 func (p *fakePicker) PickDevice(ctx context.Context, title string, devices []Device) (Device, error) {
 	p.calls++
+	p.offered = devices
 	if p.err != nil {
 		return Device{}, p.err
 	}
@@ -269,3 +277,1094 @@ func TestDisconnectByNameOrInteractive(t *testing.T) {
 		t.Fatalf("disconnected=%v, want [CC]", bt.disconnected)
 	}
 }
+
+type fakeEnricher struct {
+	details []Device
+	err     error
+}
+
+func (f fakeEnricher) Details(ctx context.Context) ([]Device, error) {
+	return f.details, f.err
+}
+
+func intp(n int) *int { return &n }
+
+func TestEnrich(t *testing.T) {
+	ctx := context.Background()
+	devices := []Device{
+		{Name: "Pods", Address: "aa:bb:cc:dd:ee:01", Battery: &Battery{Main: intp(70)}},
+		{Name: "Keys", Address: "aa:bb:cc:dd:ee:02", Type: "Keyboard"},
+		{Name: "Other", Address: "aa:bb:cc:dd:ee:03"},
+	}
+	e := fakeEnricher{details: []Device{
+		{Address: "AA:BB:CC:DD:EE:01", Type: "Headphones", Battery: &Battery{Main: intp(10), Left: intp(90)}, Firmware: "6A326"},
+		{Address: "aa:bb:cc:dd:ee:02", Type: "Mouse", VendorID: "0x05AC", Services: []string{"HID"}},
+	}}
+
+	got, err := Enrich(ctx, e, devices)
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if got[0].Type != "Headphones" || got[0].Firmware != "6A326" {
+		t.Fatalf("got[0]=%+v", got[0])
+	}
+	if *got[0].Battery.Main != 70 || *got[0].Battery.Left != 90 {
+		t.Fatalf("backend battery level must win, enricher fills the rest: %+v", got[0].Battery)
+	}
+	if *devices[0].Battery.Main != 70 || devices[0].Battery.Left != nil {
+		t.Fatalf("input must not be modified: %+v", devices[0].Battery)
+	}
+	if got[1].Type != "Keyboard" || got[1].VendorID != "0x05AC" || len(got[1].Services) != 1 {
+		t.Fatalf("got[1]=%+v", got[1])
+	}
+	if got[2].Type != "" || got[2].Battery != nil {
+		t.Fatalf("got[2]=%+v", got[2])
+	}
+
+	failing := fakeEnricher{err: errors.New("boom")}
+	got, err = Enrich(ctx, failing, devices)
+	if err == nil || len(got) != 3 || got[1].Type != "Keyboard" {
+		t.Fatalf("on error devices must be returned unchanged: %+v, %v", got, err)
+	}
+}
+
+func TestLister_EnricherFailureIsIgnored(t *testing.T) {
+	bt := &fakeBluetooth{devices: []Device{{Name: "A", Address: "aa:aa:aa:aa:aa:aa"}}}
+	l := Lister{Bluetooth: bt, Enricher: fakeEnricher{err: errors.New("system_profiler timed out")}}
+
+	got, err := l.ListDevices(context.Background())
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ListDevices = %+v, %v", got, err)
+	}
+}
+
+type recordingPicker struct {
+	fakePicker
+	seen []Device
+}
+
+func (p *recordingPicker) PickDevice(ctx context.Context, title string, devices []Device) (Device, error) {
+	p.seen = devices
+	return p.fakePicker.PickDevice(ctx, title, devices)
+}
+
+func (p *recordingPicker) PickDeviceStream(ctx context.Context, title string, updates <-chan []Device) (Device, error) {
+	p.seen = <-updates
+	return p.picked, nil
+}
+
+func TestEnrichingPicker(t *testing.T) {
+	ctx := context.Background()
+	e := fakeEnricher{details: []Device{{Address: "aa:aa:aa:aa:aa:aa", Type: "Trackpad"}}}
+	inner := &recordingPicker{}
+	p := EnrichingPicker{Picker: inner, Enricher: e}
+
+	if _, err := p.PickDevice(ctx, "Connect", []Device{{Name: "T", Address: "aa:aa:aa:aa:aa:aa"}}); err != nil {
+		t.Fatalf("PickDevice: %v", err)
+	}
+	if len(inner.seen) != 1 || inner.seen[0].Type != "Trackpad" {
+		t.Fatalf("seen=%+v", inner.seen)
+	}
+
+	updates := make(chan []Device, 1)
+	updates <- []Device{{Name: "T", Address: "aa:aa:aa:aa:aa:aa"}}
+	close(updates)
+	inner.seen = nil
+	if _, err := p.PickDeviceStream(ctx, "Pair", updates); err != nil {
+		t.Fatalf("PickDeviceStream: %v", err)
+	}
+	if len(inner.seen) != 1 || inner.seen[0].Type != "Trackpad" {
+		t.Fatalf("stream seen=%+v", inner.seen)
+	}
+}
+
+func TestClassOfDevice(t *testing.T) {
+	cases := []struct {
+		class    ClassOfDevice
+		major    string
+		minor    string
+		typ      string
+		services []string
+	}{
+		{0x000000, "Miscellaneous", "", "", []string{}},
+		{0x000540, "Peripheral", "Keyboard", "Keyboard", []string{}},
+		{0x002580, "Peripheral", "Mouse", "Mouse", []string{"Limited Discoverable"}},
+		{0x0005c0, "Peripheral", "Keyboard/Mouse", "Keyboard/Mouse", []string{}},
+		{0x002594, "Peripheral", "Mouse", "Mouse", []string{"Limited Discoverable"}}, // Magic Trackpad: pointing + digitizer
+		{0x000508, "Peripheral", "Gamepad", "Gamepad", []string{}},
+		{0x00050c, "Peripheral", "Remote Control", "Remote Control", []string{}},
+		{0x000500, "Peripheral", "", "Peripheral", []string{}},
+		{0x240404, "Audio/Video", "Headset", "Headset", []string{"Rendering", "Audio"}},
+		{0x240418, "Audio/Video", "Headphones", "Headphones", []string{"Rendering", "Audio"}},
+		{0x240414, "Audio/Video", "Speaker", "Speaker", []string{"Rendering", "Audio"}},
+		{0x200408, "Audio/Video", "Hands-free", "Hands-free", []string{"Audio"}},
+		{0x200420, "Audio/Video", "Car Audio", "Car Audio", []string{"Audio"}},
+		{0x00040c, "Audio/Video", "", "Audio/Video", []string{}}, // reserved minor
+		{0x7a020c, "Phone", "Smartphone", "Smartphone", []string{"Networking", "Capturing", "Object Transfer", "Audio", "Telephony"}},
+		{0x38010c, "Computer", "Laptop", "Laptop", []string{"Capturing", "Object Transfer", "Audio"}},
+		{0x10011c, "Computer", "Tablet", "Tablet", []string{"Object Transfer"}},
+		{0x020300, "Network Access Point", "", "Network Access Point", []string{"Networking"}},
+		{0x040680, "Imaging", "Printer", "Printer", []string{"Rendering"}},
+		{0x0006a0, "Imaging", "Camera/Printer", "Camera/Printer", []string{}},
+		{0x000704, "Wearable", "Wristwatch", "Wristwatch", []string{}},
+		{0x000810, "Toy", "Controller", "Controller", []string{}},
+		{0x000918, "Health", "Heart Rate Monitor", "Heart Rate Monitor", []string{}},
+		{0x001f00, "Uncategorized", "", "", []string{}},
+		{0x001500, "", "", "", []string{}}, // reserved major
+	}
+	for _, tc := range cases {
+		if got := tc.class.MajorName(); got != tc.major {
+			t.Errorf("%#06x MajorName = %q, want %q", uint32(tc.class), got, tc.major)
+		}
+		if got := tc.class.MinorName(); got != tc.minor {
+			t.Errorf("%#06x MinorName = %q, want %q", uint32(tc.class), got, tc.minor)
+		}
+		if got := tc.class.DeviceType(); got != tc.typ {
+			t.Errorf("%#06x DeviceType = %q, want %q", uint32(tc.class), got, tc.typ)
+		}
+		if got := tc.class.Services(); strings.Join(got, ",") != strings.Join(tc.services, ",") {
+			t.Errorf("%#06x Services = %v, want %v", uint32(tc.class), got, tc.services)
+		}
+	}
+}
+
+func TestTypeFromIcon(t *testing.T) {
+	if got := TypeFromIcon("input-keyboard"); got != "Keyboard" {
+		t.Fatalf("got %q", got)
+	}
+	if got := TypeFromIcon("unknown-icon"); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestBattery_LowestAndString(t *testing.T) {
+	n := func(v int) *int { return &v }
+
+	if _, ok := (Battery{}).Lowest(); ok {
+		t.Fatalf("empty battery should have no level")
+	}
+	b := Battery{Left: n(90), Right: n(15), Case: n(60)}
+	if lvl, ok := b.Lowest(); !ok || lvl != 15 {
+		t.Fatalf("Lowest = %d,%v", lvl, ok)
+	}
+	if got := b.String(); got != "L 90% R 15% case 60%" {
+		t.Fatalf("String = %q", got)
+	}
+	if got := (Battery{Main: n(85)}).String(); got != "85%" {
+		t.Fatalf("String = %q", got)
+	}
+}
+
+func TestConnector_MatchesAddress(t *testing.T) {
+	bt := &fakeBluetooth{devices: []Device{{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"}, {Name: "MX Master", Address: "aa:bb:cc:00:00:02"}}}
+	c := Connector{Bluetooth: bt}
+
+	got, err := c.ConnectByNameOrInteractive(context.Background(), ConnectParams{Name: "AA-BB-CC-00-00-02"})
+	if err != nil {
+		t.Fatalf("ConnectByNameOrInteractive: %v", err)
+	}
+	if got.Name != "MX Master" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestConnector_MatchesAddressWithExact(t *testing.T) {
+	bt := &fakeBluetooth{devices: []Device{{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"}}}
+	c := Connector{Bluetooth: bt}
+
+	got, err := c.ConnectByNameOrInteractive(context.Background(), ConnectParams{Name: "aa:bb:cc:00:00:01", Exact: true})
+	if err != nil || got.Name != "MX Keys" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	// A partial address is not a prefix of anything.
+	if _, err := c.ConnectByNameOrInteractive(context.Background(), ConnectParams{Name: "aa:bb:cc"}); err == nil {
+		t.Fatalf("partial address should not match")
+	}
+}
+
+func TestDisconnector_MatchesAddress(t *testing.T) {
+	bt := &fakeBluetooth{devices: []Device{
+		{Name: "MX Keys", Address: "aa:bb:cc:00:00:01", Connected: true},
+		{Name: "MX Master", Address: "aa:bb:cc:00:00:02", Connected: true},
+	}}
+	d := Disconnector{Bluetooth: bt}
+
+	got, err := d.DisconnectByNameOrInteractive(context.Background(), DisconnectParams{Name: "aa-bb-cc-00-00-01"})
+	if err != nil {
+		t.Fatalf("DisconnectByNameOrInteractive: %v", err)
+	}
+	if got.Name != "MX Keys" || len(bt.disconnected) != 1 || bt.disconnected[0] != "aa:bb:cc:00:00:01" {
+		t.Fatalf("got %+v, disconnected=%v", got, bt.disconnected)
+	}
+}
+
+func TestAnnotatedBluetooth(t *testing.T) {
+	reg := Registry{}
+	reg.Set("AA-BB-CC-00-00-01", DeviceMeta{Alias: "kb", Tags: []string{"work"}})
+	reg.Set("aa:bb:cc:00:00:02", DeviceMeta{Hidden: true})
+	reg.Set("aa:bb:cc:00:00:03", DeviceMeta{Pinned: true})
+	bt := &fakeBluetooth{devices: []Device{
+		{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"},
+		{Name: "Old Speaker", Address: "aa:bb:cc:00:00:02"},
+		{Name: "Zoo Mouse", Address: "aa:bb:cc:00:00:03"},
+	}}
+
+	devices, err := Lister{Bluetooth: AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}}.ListDevices(context.Background())
+	if err != nil {
+		t.Fatalf("ListDevices: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "Zoo Mouse" || devices[1].DisplayName() != "kb" {
+		t.Fatalf("want pinned Zoo Mouse first, then kb, hidden left out; got %+v", devices)
+	}
+
+	all, _ := Lister{Bluetooth: AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}, ShowHidden: true}.ListDevices(context.Background())
+	if len(all) != 3 {
+		t.Fatalf("ShowHidden: got %d devices, want 3", len(all))
+	}
+	// The port itself keeps hidden devices, flagged.
+	listed, _ := AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}.List(context.Background())
+	if len(listed) != 3 || !listed[1].Hidden {
+		t.Fatalf("List: %+v", listed)
+	}
+}
+
+func TestConnector_HiddenDevice(t *testing.T) {
+	reg := Registry{}
+	reg.Set("aa:bb:cc:00:00:02", DeviceMeta{Hidden: true})
+	bt := &fakeBluetooth{devices: []Device{{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"}, {Name: "MX Master 3", Address: "aa:bb:cc:00:00:02"}}}
+	c := Connector{Bluetooth: AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}}
+	ctx := context.Background()
+
+	// An address reaches a hidden device.
+	got, err := c.ConnectByNameOrInteractive(ctx, ConnectParams{Name: "aa:bb:cc:00:00:02"})
+	if err != nil || got.Name != "MX Master 3" || !got.Hidden {
+		t.Fatalf("by address: %+v, %v", got, err)
+	}
+	// So does its full name.
+	if got, err := c.ConnectByNameOrInteractive(ctx, ConnectParams{Name: "MX Master 3"}); err != nil || got.Name != "MX Master 3" {
+		t.Fatalf("by full name: %+v, %v", got, err)
+	}
+	// A prefix only matches the visible device.
+	if got, err := c.ConnectByNameOrInteractive(ctx, ConnectParams{Name: "MX"}); err != nil || got.Name != "MX Keys" {
+		t.Fatalf("by prefix: %+v, %v", got, err)
+	}
+}
+
+func TestVisiblePicker(t *testing.T) {
+	pk := &fakePicker{}
+	devices := []Device{{Name: "MX Keys", Address: "AA"}, {Name: "Old Speaker", Address: "BB", Hidden: true}}
+	if _, err := (VisiblePicker{Picker: pk}).PickDevice(context.Background(), "Connect", devices); err != nil {
+		t.Fatal(err)
+	}
+	if len(pk.offered) != 1 || pk.offered[0].Name != "MX Keys" {
+		t.Fatalf("offered %+v", pk.offered)
+	}
+}
+
+func TestConnector_MatchesAlias(t *testing.T) {
+	reg := Registry{}
+	reg.Set("aa:bb:cc:00:00:02", DeviceMeta{Alias: "desk"})
+	bt := &fakeBluetooth{devices: []Device{{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"}, {Name: "MX Master", Address: "aa:bb:cc:00:00:02"}}}
+	c := Connector{Bluetooth: AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}}
+
+	got, err := c.ConnectByNameOrInteractive(context.Background(), ConnectParams{Name: "desk", Exact: true})
+	if err != nil {
+		t.Fatalf("ConnectByNameOrInteractive: %v", err)
+	}
+	if got.Name != "MX Master" || got.Alias != "desk" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestConnector_DisplacesGroupMembers(t *testing.T) {
+	kb1 := Device{Name: "MX Keys", Address: "aa:bb:cc:00:00:01"}
+	kb2 := Device{Name: "HHKB", Address: "aa:bb:cc:00:00:02", Connected: true}
+	mouse := Device{Name: "Mouse", Address: "aa:bb:cc:00:00:03", Connected: true}
+	groups := []ExclusiveGroup{{Name: "keyboards", Members: []string{"AA-BB-CC-00-00-01", "HHKB"}}}
+
+	t.Run("dry run plans only", func(t *testing.T) {
+		bt := &fakeBluetooth{devices: []Device{kb1, kb2, mouse}, connectedList: []Device{kb2, mouse}}
+		c := Connector{Bluetooth: bt, Groups: groups}
+		r, err := c.Connect(context.Background(), ConnectParams{Name: "MX", DryRun: true})
+		if err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if len(r.Displaced) != 1 || r.Displaced[0].Name != "HHKB" {
+			t.Fatalf("Displaced = %+v", r.Displaced)
+		}
+		if len(bt.connected) != 0 || len(bt.disconnected) != 0 {
+			t.Fatalf("dry run touched devices: %v %v", bt.connected, bt.disconnected)
+		}
+	})
+
+	t.Run("connects before disconnecting", func(t *testing.T) {
+		bt := &fakeBluetooth{devices: []Device{kb1, kb2, mouse}, connectedList: []Device{kb2, mouse}}
+		c := Connector{Bluetooth: bt, Groups: groups}
+		r, err := c.Connect(context.Background(), ConnectParams{Name: "MX"})
+		if err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if len(bt.connected) != 1 || len(bt.disconnected) != 1 || bt.disconnected[0] != kb2.Address {
+			t.Fatalf("connected=%v disconnected=%v", bt.connected, bt.disconnected)
+		}
+		if len(r.Displaced) != 1 || r.Displaced[0].Connected {
+			t.Fatalf("Displaced = %+v", r.Displaced)
+		}
+	})
+
+	t.Run("displaces a hidden member", func(t *testing.T) {
+		reg := Registry{}
+		reg.Set(kb2.Address, DeviceMeta{Hidden: true})
+		bt := &fakeBluetooth{devices: []Device{kb1, kb2}, connectedList: []Device{kb2}}
+		c := Connector{Bluetooth: AnnotatedBluetooth{BluetoothPort: bt, Registry: reg}, Groups: groups}
+		if _, err := c.Connect(context.Background(), ConnectParams{Name: "MX"}); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		if len(bt.disconnected) != 1 || bt.disconnected[0] != kb2.Address {
+			t.Fatalf("disconnected = %v", bt.disconnected)
+		}
+	})
+
+	t.Run("failed connect keeps the other member", func(t *testing.T) {
+		bt := &fakeBluetooth{devices: []Device{kb1, kb2}, connectedList: []Device{kb2}, connectErr: errors.New("boom")}
+		c := Connector{Bluetooth: bt, Groups: groups}
+		if _, err := c.Connect(context.Background(), ConnectParams{Name: "MX"}); err == nil {
+			t.Fatalf("expected error")
+		}
+		if len(bt.disconnected) != 0 {
+			t.Fatalf("disconnected = %v", bt.disconnected)
+		}
+	})
+}
+
+func TestPlanDisplace_Conflict(t *testing.T) {
+	groups := []ExclusiveGroup{{Name: "headsets", Members: []string{"A", "B"}}}
+	_, err := PlanDisplace(groups, []Device{{Name: "A", Address: "1"}, {Name: "B", Address: "2"}}, nil)
+	var conflict ErrGroupConflict
+	if !errors.As(err, &conflict) || conflict.Group != "headsets" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// switchFake keeps per-address connection state; connecting fail errors, and
+// connecting anything drops the others when exclusive (like a headset link).
+type switchFake struct {
+	*fakeBluetooth
+	state     map[string]bool
+	fail      string
+	exclusive bool
+}
+
+func (f *switchFake) Connect(ctx context.Context, address string) error {
+	if address == f.fail {
+		return errors.New("page timeout")
+	}
+	if f.exclusive {
+		f.state = map[string]bool{}
+	}
+	f.state[address] = true
+	return nil
+}
+
+func (f *switchFake) Disconnect(ctx context.Context, address string) error {
+	f.disconnected = append(f.disconnected, address)
+	f.state[address] = false
+	return nil
+}
+
+func (f *switchFake) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if !f.state[address] {
+		return errors.New("timeout")
+	}
+	return nil
+}
+
+func (f *switchFake) IsConnected(ctx context.Context, address string) (bool, error) {
+	return f.state[address], nil
+}
+
+func TestSwitcher(t *testing.T) {
+	a := Device{Name: "Headphones A", Address: "aa:00:00:00:00:01", Connected: true}
+	b := Device{Name: "Headphones B", Address: "aa:00:00:00:00:02"}
+
+	t.Run("disconnects from after to is verified", func(t *testing.T) {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{devices: []Device{a, b}}, state: map[string]bool{a.Address: true}}
+		s := Switcher{Bluetooth: bt}
+		from, to, err := s.Resolve(context.Background(), SwitchParams{From: "Headphones A", To: "Headphones B", Exact: true})
+		if err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		r, err := s.Switch(context.Background(), from, to, time.Second)
+		if err != nil {
+			t.Fatalf("Switch: %v", err)
+		}
+		if r.To.Result != SwitchConnected || r.From.Result != SwitchDisconnected || bt.state[a.Address] || !bt.state[b.Address] {
+			t.Fatalf("result=%+v state=%v", r, bt.state)
+		}
+	})
+
+	t.Run("keeps from when to fails", func(t *testing.T) {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{}, state: map[string]bool{a.Address: true}, fail: b.Address}
+		r, err := Switcher{Bluetooth: bt}.Switch(context.Background(), a, b, time.Second)
+		var sf ErrSwitchFailed
+		if !errors.As(err, &sf) || !sf.Restored {
+			t.Fatalf("err = %v", err)
+		}
+		if r.To.Result != SwitchFailed || r.From.Result != SwitchKept || !bt.state[a.Address] {
+			t.Fatalf("result=%+v state=%v", r, bt.state)
+		}
+	})
+
+	t.Run("restores from when to drops it and fails", func(t *testing.T) {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{}, state: map[string]bool{a.Address: true}, exclusive: true}
+		// b connects (dropping a) but never verifies.
+		s := Switcher{Bluetooth: &unverifiedFake{switchFake: bt, addrs: []string{b.Address}}}
+		r, err := s.Switch(context.Background(), a, b, time.Second)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if r.From.Result != SwitchRestored || !bt.state[a.Address] {
+			t.Fatalf("result=%+v state=%v", r, bt.state)
+		}
+	})
+
+	t.Run("retries with its policy", func(t *testing.T) {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{}, state: map[string]bool{a.Address: true}, fail: b.Address}
+		var retries int
+		s := Switcher{Bluetooth: bt, Retry: RetryPolicy{
+			MaxAttempts: 4,
+			Backoff:     time.Millisecond,
+			Jitter:      -1,
+			OnRetry:     func(int, error, time.Duration) { retries++ },
+		}}
+		if _, err := s.Switch(context.Background(), a, b, time.Second); err == nil {
+			t.Fatalf("expected error")
+		}
+		if retries != 3 {
+			t.Fatalf("retries = %d, want 3", retries)
+		}
+	})
+
+	t.Run("reports a restore that does not verify", func(t *testing.T) {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{}, state: map[string]bool{a.Address: true}, exclusive: true}
+		// b drops a and never verifies; neither does a once reconnected.
+		s := Switcher{Bluetooth: &unverifiedFake{switchFake: bt, addrs: []string{a.Address, b.Address}}}
+		r, err := s.Switch(context.Background(), a, b, time.Second)
+		var sf ErrSwitchFailed
+		if !errors.As(err, &sf) || sf.Restored {
+			t.Fatalf("err = %v", err)
+		}
+		if r.From.Result != SwitchRestoreFailed || r.From.Err == nil {
+			t.Fatalf("result=%+v", r)
+		}
+	})
+
+	t.Run("leaves to connected when it already was", func(t *testing.T) {
+		connectedB := b
+		connectedB.Connected = true
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{}, state: map[string]bool{a.Address: true, b.Address: true}}
+		s := Switcher{Bluetooth: &unverifiedFake{switchFake: bt, addrs: []string{b.Address}}}
+		if _, err := s.Switch(context.Background(), a, connectedB, time.Second); err == nil {
+			t.Fatalf("expected error")
+		}
+		if len(bt.disconnected) != 0 || !bt.state[b.Address] {
+			t.Fatalf("disconnected=%v state=%v", bt.disconnected, bt.state)
+		}
+	})
+}
+
+// unverifiedFake never reports addrs as connected.
+type unverifiedFake struct {
+	*switchFake
+	addrs []string
+}
+
+func (f *unverifiedFake) unverified(address string) bool {
+	for _, a := range f.addrs {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *unverifiedFake) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if f.unverified(address) {
+		return errors.New("timeout")
+	}
+	return f.switchFake.WaitConnect(ctx, address, timeoutSeconds)
+}
+
+func (f *unverifiedFake) IsConnected(ctx context.Context, address string) (bool, error) {
+	if f.unverified(address) {
+		return false, nil
+	}
+	return f.switchFake.IsConnected(ctx, address)
+}
+
+func TestToggler(t *testing.T) {
+	a := Device{Name: "Headphones A", Address: "aa:00:00:00:00:01"}
+	b := Device{Name: "Headphones B", Address: "aa:00:00:00:00:02"}
+	newBT := func(connected ...string) *switchFake {
+		bt := &switchFake{fakeBluetooth: &fakeBluetooth{devices: []Device{a, b}}, state: map[string]bool{}}
+		for _, addr := range connected {
+			bt.state[addr] = true
+		}
+		return bt
+	}
+
+	bt := newBT(a.Address)
+	r, err := Toggler{Bluetooth: bt, PollInterval: time.Millisecond}.Toggle(context.Background(), ToggleParams{Name: "Headphones A", Verify: time.Second})
+	if err != nil || r.Device.Connected || bt.state[a.Address] {
+		t.Fatalf("toggle connected device: %+v %v state=%v", r, err, bt.state)
+	}
+
+	r, err = Toggler{Bluetooth: bt}.Toggle(context.Background(), ToggleParams{Name: "Headphones A", Verify: time.Second})
+	if err != nil || !r.Device.Connected || !bt.state[a.Address] {
+		t.Fatalf("toggle disconnected device: %+v %v state=%v", r, err, bt.state)
+	}
+
+	_, err = Toggler{Bluetooth: newBT(), Picker: &fakePicker{picked: a}}.Toggle(context.Background(), ToggleParams{Name: "Headphones"})
+	var am ErrAmbiguous
+	if !errors.As(err, &am) {
+		t.Fatalf("ambiguous name without a TTY: err = %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC)
+	prev := []Device{
+		{Name: "A", Address: "aa:00:00:00:00:01", Connected: true, RSSI: intp(-70)},
+		{Name: "B", Address: "aa:00:00:00:00:02", Battery: &Battery{Main: intp(50)}},
+		{Name: "C", Address: "aa:00:00:00:00:03"},
+	}
+	cur := []Device{
+		{Name: "A", Address: "AA:00:00:00:00:01", Connected: false, RSSI: intp(-68)},
+		{Name: "B", Address: "aa:00:00:00:00:02", Connected: true, Battery: &Battery{Main: intp(45)}},
+		{Name: "D", Address: "aa:00:00:00:00:04", Connected: true},
+	}
+
+	var got []string
+	for _, ev := range Diff(prev, cur, now, 5) {
+		got = append(got, string(ev.Type)+" "+ev.Device.Name)
+		if !ev.Time.Equal(now) {
+			t.Fatalf("event time = %v", ev.Time)
+		}
+	}
+	want := []string{"disconnected A", "connected B", "battery-changed B", "removed C", "appeared D", "connected D"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+}
+
+func TestCondition(t *testing.T) {
+	airpods := Device{Name: "AirPods Pro", Address: "aa:00:00:00:00:01", Connected: true, Battery: &Battery{Left: intp(15), Right: intp(40)}}
+	other := Device{Name: "Mouse", Address: "aa:00:00:00:00:02", Connected: true}
+
+	tests := []struct {
+		cond string
+		ev   Event
+		want bool
+	}{
+		{"connected:AirPods", Event{Type: EventConnected, Device: airpods}, true},
+		{"connected:AirPods", Event{Type: EventConnected, Device: other}, false},
+		{"connected:aa-00-00-00-00-02", Event{Type: EventConnected, Device: other}, true},
+		{"disconnected", Event{Type: EventConnected, Device: other}, false},
+		{"battery<20", Event{Type: EventBatteryChanged, Device: airpods}, true},
+		{"battery<10:AirPods", Event{Type: EventBatteryChanged, Device: airpods}, false},
+		{"rssi>-50", Event{Type: EventRSSIChanged, Device: Device{RSSI: intp(-40)}}, true},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.cond)
+		if err != nil {
+			t.Fatalf("ParseCondition(%q): %v", tt.cond, err)
+		}
+		if got := c.Match(tt.ev); got != tt.want {
+			t.Errorf("%s.Match(%s %s) = %v, want %v", tt.cond, tt.ev.Type, tt.ev.Device.Name, got, tt.want)
+		}
+		if c.String() != tt.cond {
+			t.Errorf("String() = %q, want %q", c.String(), tt.cond)
+		}
+	}
+
+	for _, bad := range []string{"", "conected", "battery<x", "rssi=3"} {
+		if _, err := ParseCondition(bad); err == nil {
+			t.Errorf("ParseCondition(%q): expected error", bad)
+		}
+	}
+}
+
+// waitFake reports a device as connected from its n-th IsConnected call on
+// (never when absent from connectAt).
+type waitFake struct {
+	*fakeBluetooth
+	connectAt map[string]int
+	calls     map[string]int
+}
+
+func (f *waitFake) IsConnected(ctx context.Context, address string) (bool, error) {
+	f.calls[address]++
+	n, ok := f.connectAt[address]
+	return ok && f.calls[address] >= n, nil
+}
+
+func TestWaiter(t *testing.T) {
+	kb := Device{Name: "MX Keys", Address: "aa:00:00:00:00:01"}
+	tp := Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:02"}
+	newBT := func(connectAt map[string]int) *waitFake {
+		return &waitFake{fakeBluetooth: &fakeBluetooth{devices: []Device{kb, tp}}, connectAt: connectAt, calls: map[string]int{}}
+	}
+	ctx := context.Background()
+
+	// Any: the keyboard connects first.
+	bt := newBT(map[string]int{kb.Address: 3, tp.Address: 5})
+	got, err := Waiter{Bluetooth: bt, PollInterval: time.Millisecond}.Wait(ctx, WaitParams{Names: []string{"MX", "Magic"}, Timeout: time.Second})
+	if err != nil || len(got) != 1 || got[0].Address != kb.Address || !got[0].Connected {
+		t.Fatalf("any: %+v %v", got, err)
+	}
+
+	// All: both, by the time the trackpad connects.
+	bt = newBT(map[string]int{kb.Address: 3, tp.Address: 5})
+	got, err = Waiter{Bluetooth: bt, PollInterval: time.Millisecond}.Wait(ctx, WaitParams{Names: []string{"MX", "Magic", "aa-00-00-00-00-01"}, All: true, Timeout: time.Second})
+	if err != nil || len(got) != 2 || bt.calls[tp.Address] != 5 {
+		t.Fatalf("all: %+v %v calls=%v", got, err, bt.calls)
+	}
+
+	// Disconnected: a device that never connects is there right away.
+	got, err = Waiter{Bluetooth: newBT(nil)}.Wait(ctx, WaitParams{Names: []string{"Magic"}, Disconnected: true})
+	if err != nil || len(got) != 1 || got[0].Connected {
+		t.Fatalf("disconnected: %+v %v", got, err)
+	}
+
+	// Timeout, with the devices still pending.
+	bt = newBT(map[string]int{kb.Address: 1})
+	_, err = Waiter{Bluetooth: bt, PollInterval: time.Millisecond}.Wait(ctx, WaitParams{Names: []string{"MX", "Magic"}, All: true, Timeout: 20 * time.Millisecond})
+	var to ErrTimeout
+	if !errors.As(err, &to) || len(to.Pending) != 1 || to.Pending[0].Address != tp.Address {
+		t.Fatalf("timeout: err = %v", err)
+	}
+
+	// Selectors resolve like connect's: unknown and ambiguous names fail up front.
+	var nf ErrNotFound
+	if _, err := (Waiter{Bluetooth: newBT(nil)}).Wait(ctx, WaitParams{Names: []string{"MX", "Nope"}}); !errors.As(err, &nf) {
+		t.Fatalf("unknown name: err = %v", err)
+	}
+	var am ErrAmbiguous
+	bt = newBT(nil)
+	bt.devices = append(bt.devices, Device{Name: "MX Master", Address: "aa:00:00:00:00:03"})
+	if _, err := (Waiter{Bluetooth: bt}).Wait(ctx, WaitParams{Names: []string{"MX"}}); !errors.As(err, &am) {
+		t.Fatalf("ambiguous name: err = %v", err)
+	}
+}
+
+// keepFake is a device that drops on demand and, while broken, only
+// reconnects after being paired again.
+type keepFake struct {
+	*fakeBluetooth
+	mu       sync.Mutex
+	up       bool
+	broken   bool
+	connects int
+	repaired bool
+}
+
+func (f *keepFake) set(up, broken bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.up, f.broken = up, broken
+}
+
+func (f *keepFake) state() (up bool, connects int, repaired bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.up, f.connects, f.repaired
+}
+
+func (f *keepFake) Connect(ctx context.Context, address string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connects++
+	if f.broken {
+		return errors.New("page timeout")
+	}
+	f.up = true
+	return nil
+}
+
+func (f *keepFake) Disconnect(ctx context.Context, address string) error {
+	f.set(false, false)
+	return nil
+}
+
+func (f *keepFake) Pair(ctx context.Context, address, pin string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broken, f.repaired = false, true
+	return nil
+}
+
+func (f *keepFake) Unpair(ctx context.Context, address string) error { return nil }
+
+func (f *keepFake) Inquiry(ctx context.Context, durationSeconds int) ([]Device, error) {
+	return f.devices, nil
+}
+
+func (f *keepFake) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	return nil
+}
+
+func (f *keepFake) IsConnected(ctx context.Context, address string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.up, nil
+}
+
+type memHolds struct {
+	mu    sync.Mutex
+	holds map[string]bool
+}
+
+func (m *memHolds) Hold(ctx context.Context, address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holds[registryKey(address)] = true
+	return nil
+}
+
+func (m *memHolds) Release(ctx context.Context, address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.holds, registryKey(address))
+	return nil
+}
+
+func (m *memHolds) Held(ctx context.Context, address string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.holds[registryKey(address)], nil
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestKeeper(t *testing.T) {
+	tp := Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:02"}
+	bt := &keepFake{fakeBluetooth: &fakeBluetooth{devices: []Device{tp}}, up: true}
+	holds := &memHolds{holds: map[string]bool{}}
+	user := HoldingBluetooth{BluetoothPort: bt, Holds: holds}
+	var log safeBuffer
+	k := &Keeper{Bluetooth: bt, Holds: holds, Log: &log}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- k.Run(ctx, KeepaliveParams{Names: []string{"Magic"}, Interval: time.Millisecond, Backoff: time.Millisecond, Budget: 20 * time.Millisecond, MaxAttempts: 1, Repair: true})
+	}()
+
+	// A drop is reconnected.
+	eventually(t, "connected", func() bool { return strings.Contains(log.String(), "is connected") })
+	bt.set(false, false)
+	eventually(t, "reconnect", func() bool { up, n, _ := bt.state(); return up && n == 1 })
+
+	// A deliberate disconnect is left alone until the user connects again.
+	if err := user.Disconnect(ctx, tp.Address); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "hold", func() bool { return strings.Contains(log.String(), "on purpose") })
+	time.Sleep(10 * time.Millisecond)
+	if up, n, _ := bt.state(); up || n != 1 {
+		t.Fatalf("keeper fought a deliberate disconnect: up=%v connects=%d", up, n)
+	}
+	if err := user.Connect(ctx, tp.Address); err != nil {
+		t.Fatal(err)
+	}
+	if held, _ := holds.Held(ctx, tp.Address); held {
+		t.Fatal("connect should release the hold")
+	}
+
+	// A drop that reconnects can't fix is repaired once the budget is spent.
+	bt.set(false, true)
+	eventually(t, "repair", func() bool { up, _, repaired := bt.state(); return up && repaired })
+	if !strings.Contains(log.String(), "budget spent") {
+		t.Fatalf("log:\n%s", log.String())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestKeeper_RetryPolicy(t *testing.T) {
+	tp := Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:02"}
+	bt := &keepFake{fakeBluetooth: &fakeBluetooth{devices: []Device{tp}}, broken: true}
+	var log safeBuffer
+	k := &Keeper{Bluetooth: bt, Log: &log}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- k.Run(ctx, KeepaliveParams{
+			Names:    []string{"Magic"},
+			Interval: time.Millisecond,
+			Budget:   time.Nanosecond, // a single round
+			Retry:    RetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond, Jitter: -1},
+		})
+	}()
+
+	eventually(t, "give up", func() bool { return strings.Contains(log.String(), "giving up") })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, n, _ := bt.state(); n != 4 {
+		t.Fatalf("connects = %d, want the policy's 4", n)
+	}
+}
+
+// radioFake tracks several devices; those in broken never connect.
+type radioFake struct {
+	*fakeBluetooth
+	mu       sync.Mutex
+	up       map[string]bool
+	broken   map[string]bool
+	connects map[string]int
+}
+
+func (f *radioFake) Connect(ctx context.Context, address string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connects[address]++
+	if f.broken[address] {
+		return errors.New("page timeout")
+	}
+	f.up[address] = true
+	return nil
+}
+
+func (f *radioFake) Disconnect(ctx context.Context, address string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.up[address] = false
+	return nil
+}
+
+func (f *radioFake) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	return nil
+}
+
+func (f *radioFake) IsConnected(ctx context.Context, address string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.up[address], nil
+}
+
+func (f *radioFake) state(address string) (up bool, connects int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.up[address], f.connects[address]
+}
+
+func TestKeeper_BackoffDoesNotHoldRadio(t *testing.T) {
+	kb := Device{Name: "MX Keys", Address: "aa:00:00:00:00:01"}
+	tp := Device{Name: "Magic Trackpad", Address: "aa:00:00:00:00:02"}
+	bt := &radioFake{
+		fakeBluetooth: &fakeBluetooth{devices: []Device{kb, tp}},
+		up:            map[string]bool{tp.Address: true},
+		broken:        map[string]bool{kb.Address: true},
+		connects:      map[string]int{},
+	}
+	k := &Keeper{Bluetooth: bt}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- k.Run(ctx, KeepaliveParams{
+			Names:    []string{"MX", "Magic"},
+			Interval: time.Millisecond,
+			Retry:    RetryPolicy{MaxAttempts: 2, Backoff: time.Hour, Jitter: -1},
+		})
+	}()
+
+	// The keyboard fails its first attempt and sleeps in backoff; the
+	// trackpad dropping meanwhile is still reconnected.
+	eventually(t, "keyboard attempt", func() bool { _, n := bt.state(kb.Address); return n == 1 })
+	if err := bt.Disconnect(ctx, tp.Address); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "trackpad reconnect", func() bool { up, n := bt.state(tp.Address); return up && n == 1 })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+type safeBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *safeBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *safeBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// fakeClock advances only when slept on.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	busy := errors.New("org.bluez.Error.InProgress: device busy")
+	failing := func(errs ...error) (func(context.Context, int) error, *int) {
+		calls := 0
+		return func(ctx context.Context, attempt int) error {
+			calls++
+			if attempt != calls {
+				t.Fatalf("attempt %d on call %d", attempt, calls)
+			}
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	// Exponential backoff, capped, without jitter.
+	clock := &fakeClock{}
+	op, calls := failing(busy, busy, busy, busy)
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 3 * time.Second, Jitter: -1, Clock: clock}
+	if err := p.Do(ctx, op); err != nil || *calls != 5 {
+		t.Fatalf("Do = %v after %d calls", err, *calls)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}; !equalDurations(clock.sleeps, want) {
+		t.Fatalf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+
+	// The attempts run out: the last error is returned.
+	clock = &fakeClock{}
+	op, calls = failing(busy, context.DeadlineExceeded, busy)
+	if err := (RetryPolicy{MaxAttempts: 2, Clock: clock}).Do(ctx, op); !errors.Is(err, context.DeadlineExceeded) || *calls != 2 {
+		t.Fatalf("Do = %v after %d calls", err, *calls)
+	}
+
+	// Jitter stays within ±Jitter of the pause.
+	for _, r := range []float64{0, 0.5, 0.999} {
+		clock = &fakeClock{}
+		op, _ = failing(busy)
+		p := RetryPolicy{Backoff: time.Second, Jitter: 0.5, Clock: clock, Rand: func() float64 { return r }}
+		if err := p.Do(ctx, op); err != nil {
+			t.Fatal(err)
+		}
+		if got := clock.sleeps[0]; got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("rand %v: pause %v", r, got)
+		}
+	}
+
+	// The deadline ends the retries before a pause would cross it.
+	clock = &fakeClock{}
+	op, calls = failing(busy, busy, busy, busy)
+	p = RetryPolicy{MaxAttempts: 10, Backoff: time.Second, Jitter: -1, Deadline: 2500 * time.Millisecond, Clock: clock}
+	if err := p.Do(ctx, op); err != busy || *calls != 2 || len(clock.sleeps) != 1 {
+		t.Fatalf("Do = %v after %d calls, sleeps %v", err, *calls, clock.sleeps)
+	}
+
+	// Errors that retrying can't fix fail fast.
+	for _, err := range []error{
+		ErrDependencyMissing{Dependency: "blueutil"},
+		ErrPoweredOff{},
+		fmt.Errorf("connect: %w", fs.ErrPermission),
+		errors.New("org.freedesktop.DBus.Error.AccessDenied: not allowed"),
+		errors.New("sim: connect aa: Bluetooth is powered off"),
+	} {
+		clock = &fakeClock{}
+		op, calls = failing(err, err)
+		if got := (RetryPolicy{Clock: clock}).Do(ctx, op); got != err || *calls != 1 {
+			t.Fatalf("%v: Do = %v after %d calls", err, got, *calls)
+		}
+	}
+	for _, err := range []error{busy, context.DeadlineExceeded, errors.New("page timeout")} {
+		if !IsRetryable(err) {
+			t.Fatalf("%v should be retryable", err)
+		}
+	}
+}
+
+func TestConnectWithRetryVerify(t *testing.T) {
+	// The device reports a connection only from the third connect on.
+	bt := &keepFake{fakeBluetooth: &fakeBluetooth{}, broken: true}
+	clock := &fakeClock{}
+	retries := 0
+	policy := RetryPolicy{
+		MaxAttempts: 4,
+		Clock:       clock,
+		OnRetry: func(attempt int, err error, pause time.Duration) {
+			if retries++; retries == 2 {
+				bt.set(false, false)
+			}
+		},
+	}
+	var progress strings.Builder
+	progressf := func(format string, args ...any) { fmt.Fprintf(&progress, format, args...) }
+	if err := connectWithRetryVerify(context.Background(), bt, progressf, "aa:00:00:00:00:01", 0, policy); err != nil {
+		t.Fatalf("err = %v\n%s", err, progress.String())
+	}
+	if _, n, _ := bt.state(); n != 3 || len(clock.sleeps) != 2 || !strings.Contains(progress.String(), "retrying in") {
+		t.Fatalf("connects = %d, sleeps = %v\n%s", n, clock.sleeps, progress.String())
+	}
+
+	// Bluetooth being off is not retried.
+	bt = &keepFake{fakeBluetooth: &fakeBluetooth{}}
+	off := &offFake{keepFake: bt}
+	if err := connectWithRetryVerify(context.Background(), off, nil, "aa:00:00:00:00:01", 0, RetryPolicy{Clock: &fakeClock{}}); !errors.As(err, new(ErrPoweredOff)) || off.calls != 1 {
+		t.Fatalf("err = %v after %d connects", err, off.calls)
+	}
+}
+
+type offFake struct {
+	*keepFake
+	calls int
+}
+
+func (f *offFake) Connect(ctx context.Context, address string) error {
+	f.calls++
+	return ErrPoweredOff{}
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
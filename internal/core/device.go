@@ -1,6 +1,10 @@
 package core
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Device struct {
 	Name    string `json:"name"`
@@ -10,4 +14,62 @@ type Device struct {
 
 	Connected       bool       `json:"connected"`
 	LastConnectedAt *time.Time `json:"lastConnectedAt,omitempty"`
+	Paired          bool       `json:"paired"`
+	// Favourite is macOS's "favourite" flag; nil where the backend has no such notion.
+	Favourite *bool `json:"favourite,omitempty"`
+
+	// Details below are only known to some backends or come from an EnricherPort.
+	Class     ClassOfDevice `json:"class,omitempty"`
+	Battery   *Battery      `json:"battery,omitempty"`
+	VendorID  string        `json:"vendorId,omitempty"`
+	ProductID string        `json:"productId,omitempty"`
+	Firmware  string        `json:"firmware,omitempty"`
+	Services  []string      `json:"services,omitempty"`
+
+	// User metadata from the local registry (see Registry).
+	Alias  string   `json:"alias,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Pinned bool     `json:"pinned,omitempty"`
+	Hidden bool     `json:"hidden,omitempty"`
+}
+
+// DisplayName is the alias when the user set one, otherwise the device name.
+func (d Device) DisplayName() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Name
+}
+
+// Battery holds charge levels in percent. Earbuds report Left/Right/Case
+// instead of (or in addition to) a single Main level.
+type Battery struct {
+	Main  *int `json:"main,omitempty"`
+	Left  *int `json:"left,omitempty"`
+	Right *int `json:"right,omitempty"`
+	Case  *int `json:"case,omitempty"`
+}
+
+// Lowest returns the lowest reported level; ok is false when none is known.
+func (b Battery) Lowest() (level int, ok bool) {
+	for _, l := range []*int{b.Main, b.Left, b.Right, b.Case} {
+		if l != nil && (!ok || *l < level) {
+			level, ok = *l, true
+		}
+	}
+	return level, ok
+}
+
+// String summarizes the levels, e.g. "85%" or "L 90% R 88% case 40%".
+func (b Battery) String() string {
+	parts := make([]string, 0, 4)
+	for _, l := range []struct {
+		label string
+		level *int
+	}{{"", b.Main}, {"L ", b.Left}, {"R ", b.Right}, {"case ", b.Case}} {
+		if l.level != nil {
+			parts = append(parts, fmt.Sprintf("%s%d%%", l.label, *l.level))
+		}
+	}
+	return strings.Join(parts, " ")
 }
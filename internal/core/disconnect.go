@@ -1,9 +1,6 @@
 package core
 
-import (
-	"context"
-	"fmt"
-)
+import "context"
 
 type Disconnector struct {
 	Bluetooth BluetoothPort
@@ -24,66 +21,21 @@ func (d Disconnector) DisconnectByNameOrInteractive(ctx context.Context, p Disco
 		return Device{}, err
 	}
 
-	if p.Name == "" {
-		if d.Picker == nil {
-			return Device{}, ErrNotFound{Query: ""}
-		}
-		selected, err := d.Picker.PickDevice(ctx, "Disconnect", devices)
-		if err != nil {
-			return Device{}, err
-		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := d.Bluetooth.Disconnect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
-		return selected, nil
+	selected, err := resolveDevice(ctx, d.Picker, devices, resolveParams{
+		Title:       "Disconnect",
+		Name:        p.Name,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return Device{}, err
 	}
-
-	matches := findByName(devices, p.Name, p.Exact)
-	switch len(matches) {
-	case 0:
-		return Device{}, ErrNotFound{Query: p.Name}
-	case 1:
-		selected := matches[0]
-		if p.Interactive {
-			if !p.IsTTY {
-				return Device{}, fmt.Errorf("interactive mode requires a TTY")
-			}
-			if d.Picker == nil {
-				return Device{}, ErrNotFound{Query: p.Name}
-			}
-			selected2, err := d.Picker.PickDevice(ctx, "Disconnect", matches)
-			if err != nil {
-				return Device{}, err
-			}
-			selected = selected2
-		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := d.Bluetooth.Disconnect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
-		return selected, nil
-	default:
-		if !p.IsTTY {
-			return Device{}, ErrAmbiguous{Query: p.Name, Count: len(matches)}
-		}
-		if d.Picker == nil {
-			return Device{}, ErrAmbiguous{Query: p.Name, Count: len(matches)}
-		}
-		selected, err := d.Picker.PickDevice(ctx, "Disconnect", matches)
-		if err != nil {
-			return Device{}, err
-		}
-		if p.DryRun {
-			return selected, nil
-		}
-		if err := d.Bluetooth.Disconnect(ctx, selected.Address); err != nil {
-			return Device{}, err
-		}
+	if p.DryRun {
 		return selected, nil
 	}
+	if err := d.Bluetooth.Disconnect(ctx, selected.Address); err != nil {
+		return Device{}, err
+	}
+	return selected, nil
 }
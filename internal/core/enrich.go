@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"strings"
+)
+
+// Enrich merges details from e into devices, matched by address. Fields the
+// backend already filled in are kept. On error the devices are returned
+// unchanged together with the error, so callers can treat enrichment as
+// best-effort.
+func Enrich(ctx context.Context, e EnricherPort, devices []Device) ([]Device, error) {
+	if e == nil || len(devices) == 0 {
+		return devices, nil
+	}
+	details, err := e.Details(ctx)
+	if err != nil {
+		return devices, err
+	}
+
+	byAddr := make(map[string]Device, len(details))
+	for _, d := range details {
+		byAddr[strings.ToLower(d.Address)] = d
+	}
+	out := make([]Device, len(devices))
+	for i, d := range devices {
+		if extra, ok := byAddr[strings.ToLower(d.Address)]; ok {
+			d = mergeDetails(d, extra)
+		}
+		out[i] = d
+	}
+	return out, nil
+}
+
+func mergeDetails(d, extra Device) Device {
+	if d.Type == "" {
+		d.Type = extra.Type
+	}
+	if d.VendorID == "" {
+		d.VendorID = extra.VendorID
+	}
+	if d.ProductID == "" {
+		d.ProductID = extra.ProductID
+	}
+	if d.Firmware == "" {
+		d.Firmware = extra.Firmware
+	}
+	if len(d.Services) == 0 && len(extra.Services) > 0 {
+		d.Services = append([]string(nil), extra.Services...)
+	}
+	if extra.Battery != nil {
+		b := Battery{}
+		if d.Battery != nil {
+			b = *d.Battery
+		}
+		b.Main = firstLevel(b.Main, extra.Battery.Main)
+		b.Left = firstLevel(b.Left, extra.Battery.Left)
+		b.Right = firstLevel(b.Right, extra.Battery.Right)
+		b.Case = firstLevel(b.Case, extra.Battery.Case)
+		d.Battery = &b
+	}
+	return d
+}
+
+func firstLevel(a, b *int) *int {
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+// EnrichingPicker is a PickerPort decorator that enriches the devices before
+// they are shown, so the picker can display type and battery information.
+type EnrichingPicker struct {
+	Picker   PickerPort
+	Enricher EnricherPort
+}
+
+func (p EnrichingPicker) enrich(ctx context.Context, devices []Device) []Device {
+	enriched, _ := Enrich(ctx, p.Enricher, devices)
+	return enriched
+}
+
+func (p EnrichingPicker) PickDevice(ctx context.Context, title string, devices []Device) (Device, error) {
+	return p.Picker.PickDevice(ctx, title, p.enrich(ctx, devices))
+}
+
+func (p EnrichingPicker) PickDevices(ctx context.Context, title string, devices []Device) ([]Device, error) {
+	return p.Picker.PickDevices(ctx, title, p.enrich(ctx, devices))
+}
+
+func (p EnrichingPicker) PickDeviceStream(ctx context.Context, title string, updates <-chan []Device) (Device, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	enriched := make(chan []Device)
+	go func() {
+		defer close(enriched)
+		for ds := range updates {
+			select {
+			case enriched <- p.enrich(ctx, ds):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p.Picker.PickDeviceStream(ctx, title, enriched)
+}
@@ -39,3 +39,38 @@ func (e ErrDependencyMissing) Error() string {
 	}
 	return fmt.Sprintf("dependency missing: %s", e.Dependency)
 }
+
+type ErrUnsupportedPlatform struct {
+	Platform string
+}
+
+func (e ErrUnsupportedPlatform) Error() string {
+	if e.Platform == "" {
+		return "unsupported platform"
+	}
+	return fmt.Sprintf("unsupported platform: %s", e.Platform)
+}
+
+// ErrDependencyTooOld reports an installed dependency that lacks a feature bt-manage needs.
+type ErrDependencyTooOld struct {
+	Dependency string
+	Feature    string
+	Have       string
+	Need       string
+}
+
+func (e ErrDependencyTooOld) Error() string {
+	msg := fmt.Sprintf("%s too old: need >= %s", e.Dependency, e.Need)
+	if e.Feature != "" {
+		msg += " for " + e.Feature
+	}
+	if e.Have != "" {
+		msg += fmt.Sprintf(" (have %s)", e.Have)
+	}
+	return msg
+}
+
+// ErrPoweredOff reports an operation that failed because the Bluetooth adapter is off.
+type ErrPoweredOff struct{}
+
+func (e ErrPoweredOff) Error() string { return "Bluetooth is powered off" }
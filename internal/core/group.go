@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExclusiveGroup is a set of devices of which at most one should be connected
+// at a time (e.g. two keyboards). Members are addresses, aliases or exact names.
+type ExclusiveGroup struct {
+	Name    string
+	Members []string
+}
+
+// Has reports whether d is a member of the group.
+func (g ExclusiveGroup) Has(d Device) bool {
+	addr := normalizeQueryAddress(d.Address)
+	for _, m := range g.Members {
+		m = strings.TrimSpace(m)
+		switch {
+		case m == "":
+		case addr != "" && normalizeQueryAddress(m) == addr:
+			return true
+		case m == d.Name || (d.Alias != "" && m == d.Alias):
+			return true
+		}
+	}
+	return false
+}
+
+// ErrGroupConflict reports an attempt to connect two members of one exclusive group.
+type ErrGroupConflict struct {
+	Group string
+	A, B  Device
+}
+
+func (e ErrGroupConflict) Error() string {
+	return fmt.Sprintf("%s and %s are both in exclusive group %q", e.A.DisplayName(), e.B.DisplayName(), e.Group)
+}
+
+// PlanDisplace returns the connected devices that have to be disconnected so
+// that targets are the only connected members of their groups.
+func PlanDisplace(groups []ExclusiveGroup, targets, connected []Device) ([]Device, error) {
+	isTarget := func(d Device) bool {
+		for _, t := range targets {
+			if strings.EqualFold(t.Address, d.Address) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []Device
+	seen := map[string]bool{}
+	for _, g := range groups {
+		var member *Device
+		for i, t := range targets {
+			if !g.Has(t) {
+				continue
+			}
+			if member != nil {
+				return nil, ErrGroupConflict{Group: g.Name, A: *member, B: t}
+			}
+			member = &targets[i]
+		}
+		if member == nil {
+			continue
+		}
+		for _, d := range connected {
+			key := strings.ToLower(d.Address)
+			if g.Has(d) && !isTarget(d) && !seen[key] {
+				seen[key] = true
+				out = append(out, d)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Displace disconnects the other connected members of the targets' exclusive
+// groups. It must run after the targets are connected: disconnecting first
+// could leave the user without their only keyboard or mouse if the connect
+// then fails. The returned devices are those disconnected (or, with dryRun,
+// those that would be); failures are joined into the error.
+func (c Connector) Displace(ctx context.Context, targets []Device, dryRun bool) ([]Device, error) {
+	if len(c.Groups) == 0 {
+		return nil, nil
+	}
+	connected, err := c.Bluetooth.ConnectedDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := PlanDisplace(c.Groups, targets, connected)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	var done []Device
+	var errs []error
+	for _, d := range plan {
+		if err := c.Bluetooth.Disconnect(ctx, d.Address); err != nil {
+			errs = append(errs, fmt.Errorf("disconnect %s (%s): %w", d.DisplayName(), d.Address, err))
+			continue
+		}
+		d.Connected = false
+		done = append(done, d)
+	}
+	return done, errors.Join(errs...)
+}
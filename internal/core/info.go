@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"strings"
+)
+
+// Inspector resolves a single device and gathers everything known about it.
+type Inspector struct {
+	Bluetooth BluetoothPort
+	Picker    PickerPort
+	// Enricher optionally adds details (type, battery, ...); its failures are ignored.
+	Enricher EnricherPort
+}
+
+type InspectParams struct {
+	Name        string // name prefix, exact name or address
+	Exact       bool
+	Interactive bool
+	IsTTY       bool
+}
+
+// Inspect selects a paired device with the same rules as connect and returns its details.
+func (i Inspector) Inspect(ctx context.Context, p InspectParams) (Device, error) {
+	devices, err := i.Bluetooth.List(ctx)
+	if err != nil {
+		return Device{}, err
+	}
+	selected, err := resolveDevice(ctx, i.Picker, devices, resolveParams{
+		Title:       "Info",
+		Name:        p.Name,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return Device{}, err
+	}
+	return i.enrich(ctx, selected), nil
+}
+
+// Refresh re-reads a device previously returned by Inspect.
+func (i Inspector) Refresh(ctx context.Context, address string) (Device, error) {
+	devices, err := i.Bluetooth.List(ctx)
+	if err != nil {
+		return Device{}, err
+	}
+	for _, d := range devices {
+		if strings.EqualFold(d.Address, address) {
+			return i.enrich(ctx, d), nil
+		}
+	}
+	return Device{}, ErrNotFound{Query: address}
+}
+
+func (i Inspector) enrich(ctx context.Context, d Device) Device {
+	out, _ := Enrich(ctx, i.Enricher, []Device{d})
+	return out[0]
+}
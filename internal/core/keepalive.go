@@ -0,0 +1,327 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HoldPort remembers the devices the user disconnected on purpose, so that
+// Keeper does not fight them. A hold lasts until the device is connected again.
+type HoldPort interface {
+	Hold(ctx context.Context, address string) error
+	Release(ctx context.Context, address string) error
+	Held(ctx context.Context, address string) (bool, error)
+}
+
+// HoldingBluetooth decorates a BluetoothPort so that every disconnect it
+// makes (disconnect, toggle, exclusive groups, ...) is recorded as a hold and
+// every connect releases it. Failing to record is not an error: holds only
+// inform Keeper.
+type HoldingBluetooth struct {
+	BluetoothPort
+	Holds HoldPort
+}
+
+func (h HoldingBluetooth) Disconnect(ctx context.Context, address string) error {
+	// Hold first, so that Keeper never sees the device go without it.
+	_ = h.Holds.Hold(ctx, address)
+	if err := h.BluetoothPort.Disconnect(ctx, address); err != nil {
+		_ = h.Holds.Release(ctx, address)
+		return err
+	}
+	return nil
+}
+
+func (h HoldingBluetooth) Connect(ctx context.Context, address string) error {
+	if err := h.BluetoothPort.Connect(ctx, address); err != nil {
+		return err
+	}
+	_ = h.Holds.Release(ctx, address)
+	return nil
+}
+
+// Keeper reconnects devices that drop, for devices known to disconnect on
+// their own (e.g. a Magic Trackpad on a shared Mac). Reconnects are retried
+// with exponential backoff for a budget of time per outage; after that the
+// device is optionally repaired, and otherwise left alone until it connects
+// again. Devices disconnected on purpose (see HoldPort) are left alone too.
+type Keeper struct {
+	Bluetooth BluetoothPort
+	Holds     HoldPort // optional; without it every disconnect is an outage
+	// Log receives a line for every action (optional).
+	Log io.Writer
+
+	mu sync.Mutex // serializes Log writes
+}
+
+type KeepaliveParams struct {
+	// Names select the devices, each matched like connect's name argument.
+	Names []string
+	Exact bool
+	// Interval between connection checks (default 5s).
+	Interval time.Duration
+	// Backoff is the pause after the first failed reconnect; it doubles after
+	// each one, up to MaxBackoff (defaults 2s and 1m).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Budget is how long an outage is retried before giving up (default 5m).
+	Budget time.Duration
+	// Retry shapes the attempts of each reconnect round and of a repair's
+	// connect; MaxAttempts, if set, overrides Retry.MaxAttempts (default 3).
+	Retry       RetryPolicy
+	MaxAttempts int
+	// WaitConnect (seconds) bounds verifying each reconnect, as for pair
+	// (default 10).
+	WaitConnect int
+	// Repair escalates to a repair (unpair, inquiry, pair) once the budget
+	// is spent.
+	Repair bool
+	// InquiryDuration is the inquiry window of a repair, in seconds (default 30).
+	InquiryDuration int
+}
+
+func (p *KeepaliveParams) defaults() {
+	if p.Interval <= 0 {
+		p.Interval = 5 * time.Second
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 2 * time.Second
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = max(time.Minute, p.Backoff)
+	}
+	if p.Budget <= 0 {
+		p.Budget = 5 * time.Minute
+	}
+	if p.WaitConnect <= 0 {
+		p.WaitConnect = 10
+	}
+	if p.InquiryDuration <= 0 {
+		p.InquiryDuration = 30
+	}
+}
+
+func (p KeepaliveParams) retryPolicy() RetryPolicy {
+	r := p.Retry
+	if p.MaxAttempts > 0 {
+		r.MaxAttempts = p.MaxAttempts
+	}
+	return r
+}
+
+// keepState is what Keeper last made of a device.
+type keepState int
+
+const (
+	keepUnknown keepState = iota
+	keepConnected
+	keepHeld   // disconnected on purpose
+	keepGaveUp // budget (and repair) spent
+)
+
+// Run resolves the devices and keeps them connected until ctx is done.
+func (k *Keeper) Run(ctx context.Context, p KeepaliveParams) error {
+	if len(p.Names) == 0 {
+		return ErrNotFound{}
+	}
+	p.defaults()
+	devices, err := k.Bluetooth.List(ctx)
+	if err != nil {
+		return err
+	}
+	var targets []Device
+	seen := map[string]bool{}
+	for _, name := range p.Names {
+		d, err := resolveDevice(ctx, nil, devices, resolveParams{Name: name, Exact: p.Exact})
+		if err != nil {
+			return err
+		}
+		if !seen[d.Address] {
+			seen[d.Address] = true
+			targets = append(targets, d)
+		}
+	}
+
+	names := make([]string, 0, len(targets))
+	for _, d := range targets {
+		names = append(names, d.DisplayName())
+	}
+	k.logf("keeping %s connected", strings.Join(names, ", "))
+
+	var (
+		wg    sync.WaitGroup
+		radio sync.Mutex // one connect, pair or inquiry on the radio at a time
+	)
+	for _, d := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k.keep(ctx, d, p, &radio)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// keep watches one device.
+func (k *Keeper) keep(ctx context.Context, d Device, p KeepaliveParams, radio *sync.Mutex) {
+	state := keepUnknown
+	wasConnected := false
+	for {
+		connected, err := k.Bluetooth.IsConnected(ctx, d.Address)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			k.logf("%s: %v", d.DisplayName(), err)
+		case connected:
+			if state != keepConnected {
+				k.logf("%s is connected", d.DisplayName())
+			}
+			if !wasConnected && state == keepHeld && k.Holds != nil {
+				// Connected again after a deliberate disconnect: watch it again.
+				_ = k.Holds.Release(ctx, d.Address)
+			}
+			state = keepConnected
+		case k.held(ctx, d.Address):
+			if state != keepHeld {
+				k.logf("%s was disconnected on purpose; leaving it alone until it connects again", d.DisplayName())
+			}
+			state = keepHeld
+		case state == keepGaveUp:
+			// Quiet until it connects again.
+		default:
+			if k.recover(ctx, d, p, radio) {
+				state = keepConnected
+				connected = true
+			} else if ctx.Err() == nil && !k.held(ctx, d.Address) {
+				state = keepGaveUp
+			}
+		}
+		if err == nil {
+			wasConnected = connected
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.Interval):
+		}
+	}
+}
+
+func (k *Keeper) held(ctx context.Context, address string) bool {
+	if k.Holds == nil {
+		return false
+	}
+	held, err := k.Holds.Held(ctx, address)
+	return err == nil && held
+}
+
+// recover reconnects d with backoff until the budget is spent, then repairs
+// it if asked to. It reports whether d is connected.
+func (k *Keeper) recover(ctx context.Context, d Device, p KeepaliveParams, radio *sync.Mutex) bool {
+	name := d.DisplayName()
+	k.logf("%s is disconnected; reconnecting (budget %s)", name, p.Budget)
+	deadline := time.Now().Add(p.Budget)
+	backoff := p.Backoff
+	bt := radioBluetooth{BluetoothPort: k.Bluetooth, radio: radio}
+	for attempt := 1; ; attempt++ {
+		if k.held(ctx, d.Address) {
+			k.logf("%s was disconnected on purpose; stopping reconnects", name)
+			return false
+		}
+		err := connectWithRetryVerify(ctx, bt, nil, d.Address, p.WaitConnect, p.retryPolicy())
+		if err == nil {
+			k.logf("%s reconnected (round %d)", name, attempt)
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !IsRetryable(err) {
+			k.logf("%s: reconnect round %d failed: %v; not retrying", name, attempt, err)
+			return false
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			k.logf("%s: reconnect round %d failed: %v; budget spent", name, attempt, err)
+			break
+		}
+		wait := min(backoff, left)
+		k.logf("%s: reconnect round %d failed: %v; retrying in %s", name, attempt, err, wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, p.MaxBackoff)
+	}
+
+	if !p.Repair {
+		k.logf("%s: giving up until it connects again", name)
+		return false
+	}
+	k.logf("%s: repairing (unpair, inquiry, pair)", name)
+	_, _, err := Repairer{Bluetooth: bt}.Repair(ctx, RepairParams{
+		Address:         d.Address,
+		InquiryDuration: p.InquiryDuration,
+		WaitConnect:     p.WaitConnect,
+		MaxAttempts:     p.MaxAttempts,
+		Retry:           p.Retry,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			k.logf("%s: repair failed: %v; giving up until it connects again", name, err)
+		}
+		return false
+	}
+	k.logf("%s repaired and connected", name)
+	return true
+}
+
+// radioBluetooth serialises the calls that drive the radio, so devices are
+// reconnected one attempt at a time. Waits and backoff sleeps run unlocked,
+// and one device retrying does not hold the others back.
+type radioBluetooth struct {
+	BluetoothPort
+	radio *sync.Mutex
+}
+
+func (b radioBluetooth) Connect(ctx context.Context, address string) error {
+	b.radio.Lock()
+	defer b.radio.Unlock()
+	return b.BluetoothPort.Connect(ctx, address)
+}
+
+func (b radioBluetooth) Pair(ctx context.Context, address string, pin string) error {
+	b.radio.Lock()
+	defer b.radio.Unlock()
+	return b.BluetoothPort.Pair(ctx, address, pin)
+}
+
+func (b radioBluetooth) Unpair(ctx context.Context, address string) error {
+	b.radio.Lock()
+	defer b.radio.Unlock()
+	return b.BluetoothPort.Unpair(ctx, address)
+}
+
+func (b radioBluetooth) Inquiry(ctx context.Context, durationSeconds int) ([]Device, error) {
+	b.radio.Lock()
+	defer b.radio.Unlock()
+	return b.BluetoothPort.Inquiry(ctx, durationSeconds)
+}
+
+func (k *Keeper) logf(format string, args ...any) {
+	if k.Log == nil {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fmt.Fprintf(k.Log, "%s  keepalive: "+format+"\n", append([]any{time.Now().Format(time.RFC3339)}, args...)...)
+}
@@ -7,6 +7,10 @@ import (
 
 type Lister struct {
 	Bluetooth BluetoothPort
+	// Enricher optionally adds details (type, battery, ...); its failures are ignored.
+	Enricher EnricherPort
+	// ShowHidden keeps the devices hidden in the registry (see AnnotatedBluetooth).
+	ShowHidden bool
 }
 
 func (l Lister) ListDevices(ctx context.Context) ([]Device, error) {
@@ -14,8 +18,15 @@ func (l Lister) ListDevices(ctx context.Context) ([]Device, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !l.ShowHidden {
+		devices = Visible(devices)
+	}
+	devices, _ = Enrich(ctx, l.Enricher, devices)
 
 	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].Pinned != devices[j].Pinned {
+			return devices[i].Pinned
+		}
 		a := devices[i].LastConnectedAt
 		b := devices[j].LastConnectedAt
 		if a == nil && b == nil {
@@ -2,24 +2,65 @@ package core
 
 import "strings"
 
-func findByName(devices []Device, query string, exact bool) []Device {
+// findByName returns the devices whose name or alias starts with query (or
+// equals it when exact). A query that is a full device address, with ':' or
+// '-' in any case, selects that device alone whatever exact says; this rule
+// is shared by every command that takes a device name. Hidden devices are
+// only matched by address or by their full name or alias, unless hidden is set.
+func findByName(devices []Device, query string, exact, hidden bool) []Device {
 	q := strings.TrimSpace(query)
 	if q == "" {
 		return nil
 	}
 
+	if addr := normalizeQueryAddress(q); addr != "" {
+		for _, d := range devices {
+			if normalizeQueryAddress(d.Address) == addr {
+				return []Device{d}
+			}
+		}
+	}
+
 	matches := make([]Device, 0)
 	for _, d := range devices {
 		if exact {
-			if d.Name == q {
+			if d.Name == q || (d.Alias != "" && d.Alias == q) {
 				matches = append(matches, d)
 			}
 			continue
 		}
 
-		if strings.HasPrefix(d.Name, q) {
+		if d.Hidden && !hidden {
+			if d.Name == q || (d.Alias != "" && d.Alias == q) {
+				matches = append(matches, d)
+			}
+			continue
+		}
+		if strings.HasPrefix(d.Name, q) || (d.Alias != "" && strings.HasPrefix(d.Alias, q)) {
 			matches = append(matches, d)
 		}
 	}
 	return matches
 }
+
+// normalizeQueryAddress returns s as a lower-case, ':'-separated address,
+// or "" if s is not a Bluetooth address.
+func normalizeQueryAddress(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, "-", ":"))
+	if len(s) != 17 {
+		return ""
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if i%3 == 2 {
+			if c != ':' {
+				return ""
+			}
+			continue
+		}
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return ""
+		}
+	}
+	return s
+}
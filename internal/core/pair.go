@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,12 +16,25 @@ type Pairer struct {
 }
 
 type PairParams struct {
+	// Address pairs that device without a picker: inquiry runs until it is
+	// found (or InquiryDuration ends), so Interactive and IsTTY are not needed.
+	Address         string
 	Interactive     bool
 	IsTTY           bool
 	InquiryDuration int // seconds
 	Pin             string
 	WaitConnect     int // seconds
-	MaxAttempts     int // default 3
+	MaxAttempts     int // default 3; overrides Retry.MaxAttempts
+	// Retry shapes the connect attempts after pairing.
+	Retry RetryPolicy
+}
+
+func (params PairParams) retryPolicy() RetryPolicy {
+	r := params.Retry
+	if params.MaxAttempts > 0 {
+		r.MaxAttempts = params.MaxAttempts
+	}
+	return r
 }
 
 func (p Pairer) progressf(format string, args ...any) {
@@ -61,12 +75,13 @@ func pickByInquiryStream(
 	totalSeconds int,
 ) (Device, error) {
 	// Wrap progressf so that we can silence output once the TUI starts.
-	uiActive := false
+	// Read from the scan goroutine, so it must be atomic.
+	var uiActive atomic.Bool
 	pf := func(format string, args ...any) {
 		if progressf == nil {
 			return
 		}
-		if uiActive {
+		if uiActive.Load() {
 			// Bubble Tea uses the terminal; writing logs while it's running corrupts the UI.
 			return
 		}
@@ -145,7 +160,7 @@ func pickByInquiryStream(
 	}
 
 	close(uiStarted)
-	uiActive = true
+	uiActive.Store(true)
 
 	uiUpdates := make(chan []Device, 16)
 	uiUpdates <- first
@@ -172,7 +187,7 @@ func pickByInquiryStream(
 
 	picked, err := picker.PickDeviceStream(ctx, title, uiUpdates)
 	uiCancel()
-	uiActive = false
+	uiActive.Store(false)
 	if err != nil {
 		return Device{}, err
 	}
@@ -182,115 +197,141 @@ func pickByInquiryStream(
 	return picked, nil
 }
 
+// connectWithRetryVerify connects address and verifies the connection, retrying
+// per policy. waitConnectSeconds is spread over the attempts, each one waiting
+// for the device to report a connection before it is checked.
 func connectWithRetryVerify(
 	ctx context.Context,
 	bluetooth BluetoothPort,
 	progressf func(string, ...any),
 	address string,
 	waitConnectSeconds int,
-	maxAttempts int,
+	policy RetryPolicy,
 ) error {
-	const waitConnectChunkSeconds = 5
-
-	attempts := maxAttempts
-	if attempts <= 0 {
-		attempts = 3
+	if progressf == nil {
+		progressf = func(string, ...any) {}
 	}
-	var lastErr error
-	remainingWaitSeconds := waitConnectSeconds
-
-	for i := 1; i <= attempts; i++ {
-		if progressf != nil {
-			progressf("Connecting (attempt %d/%d)...\n", i, attempts)
+	policy = policy.withDefaults()
+	attempts := policy.MaxAttempts
+	remainingWait := time.Duration(waitConnectSeconds) * time.Second
+	onRetry := policy.OnRetry
+	policy.OnRetry = func(attempt int, err error, pause time.Duration) {
+		progressf("  retrying in %s...\n", pause.Round(time.Millisecond))
+		if onRetry != nil {
+			onRetry(attempt, err, pause)
 		}
+	}
+
+	return policy.Do(ctx, func(ctx context.Context, attempt int) error {
+		progressf("Connecting (attempt %d/%d)...\n", attempt, attempts)
 		if err := bluetooth.Connect(ctx, address); err != nil {
-			lastErr = err
-			if progressf != nil {
-				progressf("  connect failed: %v\n", err)
-			}
-			continue
+			progressf("  connect failed: %v\n", err)
+			return err
 		}
 
-		if remainingWaitSeconds > 0 {
-			attemptWaitSeconds := remainingWaitSeconds
-			if attemptWaitSeconds > waitConnectChunkSeconds {
-				attemptWaitSeconds = waitConnectChunkSeconds
-			}
-			if progressf != nil {
-				progressf(
-					"  waiting for connection (up to %ds now; remaining budget %ds)...\n",
-					attemptWaitSeconds,
-					remainingWaitSeconds,
-				)
-			}
-			start := time.Now()
-			if err := bluetooth.WaitConnect(ctx, address, attemptWaitSeconds); err != nil {
-				lastErr = err
-				if progressf != nil {
-					progressf("  wait-connect failed: %v\n", err)
-					if ok, e := bluetooth.IsConnected(ctx, address); e == nil {
-						progressf("  is-connected=%v\n", ok)
-					}
-					if cds, e := bluetooth.ConnectedDevices(ctx); e == nil {
-						progressf("  connected devices: %d\n", len(cds))
-					}
+		if remainingWait > 0 {
+			// What is left of the budget, shared by the attempts left.
+			wait := remainingWait / time.Duration(attempts-attempt+1)
+			waitSeconds := max(int((wait+time.Second-1)/time.Second), 1)
+			progressf(
+				"  waiting for connection (up to %ds now; remaining budget %ds)...\n",
+				waitSeconds,
+				int(remainingWait.Round(time.Second)/time.Second),
+			)
+			start := policy.Clock.Now()
+			err := bluetooth.WaitConnect(ctx, address, waitSeconds)
+			remainingWait = max(remainingWait-policy.Clock.Now().Sub(start), 0)
+			if err != nil {
+				progressf("  wait-connect failed: %v\n", err)
+				if ok, e := bluetooth.IsConnected(ctx, address); e == nil {
+					progressf("  is-connected=%v\n", ok)
 				}
-				elapsed := int(time.Since(start).Seconds())
-				remainingWaitSeconds -= elapsed
-				if remainingWaitSeconds < 0 {
-					remainingWaitSeconds = 0
+				if cds, e := bluetooth.ConnectedDevices(ctx); e == nil {
+					progressf("  connected devices: %d\n", len(cds))
 				}
-				continue
-			}
-			elapsed := int(time.Since(start).Seconds())
-			remainingWaitSeconds -= elapsed
-			if remainingWaitSeconds < 0 {
-				remainingWaitSeconds = 0
+				return err
 			}
 		}
 
 		ok, err := bluetooth.IsConnected(ctx, address)
-		if err == nil && ok {
-			if progressf != nil {
-				progressf("  connected confirmed\n")
-			}
-			return nil
-		}
 		if err != nil {
-			lastErr = err
-			if progressf != nil {
-				progressf("  connect verification failed: %v\n", err)
-			}
-		} else {
-			lastErr = fmt.Errorf("device is not connected")
-			if progressf != nil {
-				progressf("  connect verification failed: device is not connected\n")
-			}
+			progressf("  connect verification failed: %v\n", err)
+			return err
 		}
-	}
-
-	if lastErr == nil {
-		lastErr = fmt.Errorf("failed to connect")
-	}
-	return lastErr
+		if !ok {
+			progressf("  connect verification failed: device is not connected\n")
+			return fmt.Errorf("device is not connected")
+		}
+		progressf("  connected confirmed\n")
+		return nil
+	})
 }
 
 func (p Pairer) pairPickedAndConnect(ctx context.Context, picked Device, params PairParams) (Device, error) {
 	if strings.TrimSpace(picked.Address) == "" {
 		return Device{}, fmt.Errorf("selected device has empty address")
 	}
+	// On failure the picked device is returned with the error, so that
+	// callers can tell which device failed to pair.
 	if err := p.Bluetooth.Pair(ctx, picked.Address, params.Pin); err != nil {
-		return Device{}, err
+		return picked, err
 	}
-	if err := connectWithRetryVerify(ctx, p.Bluetooth, p.progressf, picked.Address, params.WaitConnect, params.MaxAttempts); err != nil {
-		return Device{}, err
+	if err := connectWithRetryVerify(ctx, p.Bluetooth, p.progressf, picked.Address, params.WaitConnect, params.retryPolicy()); err != nil {
+		return picked, err
 	}
 	return picked, nil
 }
 
+// discoverAddress runs inquiry until the device with address shows up, for up
+// to totalSeconds; ErrNotFound if it doesn't.
+func discoverAddress(
+	ctx context.Context,
+	bluetooth BluetoothPort,
+	progressf func(string, ...any),
+	address string,
+	totalSeconds int,
+) (Device, error) {
+	want := normalizeQueryAddress(address)
+	if want == "" {
+		return Device{}, fmt.Errorf("invalid device address: %q", address)
+	}
+	total := normalizeInquiryTotalSeconds(totalSeconds)
+	progressf("Searching for %s (up to %ds)...\n", address, total)
+
+	scanCtx, cancel := context.WithTimeout(ctx, time.Duration(total)*time.Second)
+	defer cancel()
+	for scanCtx.Err() == nil {
+		found, err := bluetooth.Inquiry(scanCtx, 3)
+		if err != nil {
+			if scanCtx.Err() != nil {
+				break
+			}
+			return Device{}, err
+		}
+		for _, d := range found {
+			if normalizeQueryAddress(d.Address) == want {
+				progressf("  found %s (%s)\n", d.Name, d.Address)
+				return d, nil
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return Device{}, err
+	}
+	return Device{}, ErrNotFound{Query: address}
+}
+
 // Pair performs: inquiry(loop) -> pick discovered device (streaming) -> pair -> connect(wait/retry).
 // It is intended for the situation where a device was already unpaired but connection is not yet established.
+// If pairing or connecting the picked device fails, that device is returned along with the error.
 func (p Pairer) Pair(ctx context.Context, params PairParams) (Device, error) {
+	if params.Address != "" {
+		found, err := discoverAddress(ctx, p.Bluetooth, p.progressf, params.Address, params.InquiryDuration)
+		if err != nil {
+			return Device{}, err
+		}
+		return p.pairPickedAndConnect(ctx, found, params)
+	}
 	if err := p.ensureInteractivePairing(params); err != nil {
 		return Device{}, err
 	}
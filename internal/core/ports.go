@@ -30,3 +30,21 @@ type PickerPort interface {
 	// PickDeviceStream opens UI and updates device list as updates are received.
 	PickDeviceStream(ctx context.Context, title string, updates <-chan []Device) (Device, error)
 }
+
+// EnricherPort supplies device details the BluetoothPort doesn't report
+// (type, battery, vendor/product IDs, firmware, services).
+// Only the fields it knows need to be set; devices are matched by address.
+type EnricherPort interface {
+	Details(ctx context.Context) ([]Device, error)
+}
+
+// AdapterPort controls the local Bluetooth adapter (controller) itself.
+// It is optional; backends that can switch the adapter implement it next to BluetoothPort.
+type AdapterPort interface {
+	// Power reports whether the adapter is powered on.
+	Power(ctx context.Context) (bool, error)
+	SetPower(ctx context.Context, on bool) error
+	// Discoverable reports whether other devices can find the adapter.
+	Discoverable(ctx context.Context) (bool, error)
+	SetDiscoverable(ctx context.Context, on bool) error
+}
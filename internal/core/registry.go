@@ -0,0 +1,248 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeviceMeta is what the user recorded about a device in the local registry.
+type DeviceMeta struct {
+	Alias  string   `json:"alias,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Pinned bool     `json:"pinned,omitempty"`
+	Hidden bool     `json:"hidden,omitempty"`
+}
+
+func (m DeviceMeta) isZero() bool {
+	return m.Alias == "" && len(m.Tags) == 0 && !m.Pinned && !m.Hidden
+}
+
+// HasTag reports whether tag is among the tags (case-insensitive).
+func (m DeviceMeta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTags adds tags that are not present yet, keeping them sorted.
+func (m *DeviceMeta) AddTags(tags ...string) {
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t != "" && !m.HasTag(t) {
+			m.Tags = append(m.Tags, t)
+		}
+	}
+	sort.Strings(m.Tags)
+}
+
+// RemoveTags removes tags (case-insensitive).
+func (m *DeviceMeta) RemoveTags(tags ...string) {
+	kept := m.Tags[:0]
+	for _, t := range m.Tags {
+		drop := false
+		for _, r := range tags {
+			if strings.EqualFold(t, strings.TrimSpace(r)) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	m.Tags = kept
+}
+
+// Registry is the local device registry, keyed by lower-case ':'-separated address.
+type Registry map[string]DeviceMeta
+
+// RegistryPort loads and saves the registry. Load of a registry that was never
+// saved returns an empty Registry.
+type RegistryPort interface {
+	Load(ctx context.Context) (Registry, error)
+	Save(ctx context.Context, r Registry) error
+}
+
+func registryKey(address string) string {
+	if k := normalizeQueryAddress(address); k != "" {
+		return k
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// Get returns the metadata recorded for address (zero if none).
+func (r Registry) Get(address string) DeviceMeta {
+	return r[registryKey(address)]
+}
+
+// Set records m for address; an empty m removes the entry.
+func (r Registry) Set(address string, m DeviceMeta) {
+	if m.isZero() {
+		delete(r, registryKey(address))
+		return
+	}
+	r[registryKey(address)] = m
+}
+
+// CheckAlias fails when alias is already used by a device other than address.
+func (r Registry) CheckAlias(address, alias string) error {
+	for addr, m := range r {
+		if addr != registryKey(address) && m.Alias != "" && strings.EqualFold(m.Alias, alias) {
+			return fmt.Errorf("alias %q is already used by %s", alias, addr)
+		}
+	}
+	return nil
+}
+
+// Annotate copies each device's registry metadata onto it.
+func (r Registry) Annotate(devices []Device) []Device {
+	out := make([]Device, len(devices))
+	for i, d := range devices {
+		m := r.Get(d.Address)
+		d.Alias = m.Alias
+		d.Tags = append([]string(nil), m.Tags...)
+		d.Pinned = m.Pinned
+		d.Hidden = m.Hidden
+		out[i] = d
+	}
+	return out
+}
+
+// AnnotatedBluetooth decorates a BluetoothPort with the registry: listed
+// devices carry their alias, tags and flags. Hidden devices are still
+// listed, so that they stay reachable by address and exact name and are
+// seen by exclusive groups; lists, pickers and prefix matching leave them
+// out (see Visible).
+type AnnotatedBluetooth struct {
+	BluetoothPort
+	Registry Registry
+}
+
+func (a AnnotatedBluetooth) annotate(devices []Device, err error) ([]Device, error) {
+	if err != nil {
+		return nil, err
+	}
+	return a.Registry.Annotate(devices), nil
+}
+
+func (a AnnotatedBluetooth) List(ctx context.Context) ([]Device, error) {
+	return a.annotate(a.BluetoothPort.List(ctx))
+}
+
+func (a AnnotatedBluetooth) Inquiry(ctx context.Context, durationSeconds int) ([]Device, error) {
+	return a.annotate(a.BluetoothPort.Inquiry(ctx, durationSeconds))
+}
+
+func (a AnnotatedBluetooth) ConnectedDevices(ctx context.Context) ([]Device, error) {
+	return a.annotate(a.BluetoothPort.ConnectedDevices(ctx))
+}
+
+// Visible returns the devices that are not hidden in the registry.
+func Visible(devices []Device) []Device {
+	out := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		if !d.Hidden {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// VisiblePicker is a PickerPort decorator that leaves hidden devices out of
+// the choices.
+type VisiblePicker struct {
+	Picker PickerPort
+}
+
+func (p VisiblePicker) PickDevice(ctx context.Context, title string, devices []Device) (Device, error) {
+	return p.Picker.PickDevice(ctx, title, Visible(devices))
+}
+
+func (p VisiblePicker) PickDevices(ctx context.Context, title string, devices []Device) ([]Device, error) {
+	return p.Picker.PickDevices(ctx, title, Visible(devices))
+}
+
+func (p VisiblePicker) PickDeviceStream(ctx context.Context, title string, updates <-chan []Device) (Device, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	visible := make(chan []Device)
+	go func() {
+		defer close(visible)
+		for ds := range updates {
+			select {
+			case visible <- Visible(ds):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p.Picker.PickDeviceStream(ctx, title, visible)
+}
+
+// RegistryEditor changes registry entries of devices selected like connect selects them.
+type RegistryEditor struct {
+	Bluetooth BluetoothPort
+	Picker    PickerPort
+	Registry  RegistryPort
+}
+
+type EditParams struct {
+	Name        string // name prefix, alias, exact name or address
+	Exact       bool
+	Interactive bool
+	IsTTY       bool
+}
+
+// Edit resolves the device, applies change to its metadata and saves the
+// registry. An address that the backend does not list (e.g. a device that
+// is not paired yet) can still be edited. change sees the whole registry so
+// that it can check for conflicts; an error aborts the edit.
+func (e RegistryEditor) Edit(ctx context.Context, p EditParams, change func(r Registry, address string, m *DeviceMeta) error) (Device, DeviceMeta, error) {
+	devices, err := e.Bluetooth.List(ctx)
+	if err != nil {
+		return Device{}, DeviceMeta{}, err
+	}
+	dev, err := resolveDevice(ctx, e.Picker, devices, resolveParams{
+		Title:       "Edit",
+		Name:        p.Name,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+		ShowHidden:  true, // unhide must find them
+	})
+	if err != nil {
+		addr := normalizeQueryAddress(p.Name)
+		var nf ErrNotFound
+		if addr == "" || !errors.As(err, &nf) {
+			return Device{}, DeviceMeta{}, err
+		}
+		dev = Device{Address: addr}
+	}
+
+	reg, err := e.Registry.Load(ctx)
+	if err != nil {
+		return Device{}, DeviceMeta{}, err
+	}
+	if reg == nil {
+		reg = Registry{}
+	}
+	m := reg.Get(dev.Address)
+	if err := change(reg, dev.Address, &m); err != nil {
+		return Device{}, DeviceMeta{}, err
+	}
+	reg.Set(dev.Address, m)
+	if err := e.Registry.Save(ctx, reg); err != nil {
+		return Device{}, DeviceMeta{}, err
+	}
+	return dev, m, nil
+}
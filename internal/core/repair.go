@@ -14,13 +14,18 @@ type Repairer struct {
 }
 
 type RepairParams struct {
+	// Address repairs that paired device without a picker: it is unpaired,
+	// found again by inquiry and paired, so Interactive and IsTTY are not needed.
+	Address         string
 	Interactive     bool
 	IsTTY           bool
 	InquiryDuration int // seconds (total window)
 	Pin             string
 	SkipUnpair      bool
 	WaitConnect     int // seconds (0 disables)
-	MaxAttempts     int // default 3
+	MaxAttempts     int // default 3; overrides Retry.MaxAttempts
+	// Retry shapes the connect attempts after pairing.
+	Retry RetryPolicy
 }
 
 func (r Repairer) progressf(format string, args ...any) {
@@ -31,7 +36,11 @@ func (r Repairer) progressf(format string, args ...any) {
 }
 
 // Repair performs: select paired device -> (optional) unpair -> inquiry(loop) -> pick discovered device (streaming) -> pair -> connect.
+// With p.Address set, that device is repaired in place without a picker.
 func (r Repairer) Repair(ctx context.Context, p RepairParams) (from Device, to Device, err error) {
+	if p.Address != "" {
+		return r.repairAddress(ctx, p)
+	}
 	if !p.Interactive {
 		return Device{}, Device{}, fmt.Errorf("repair requires --interactive (TTY only)")
 	}
@@ -74,18 +83,53 @@ func (r Repairer) Repair(ctx context.Context, p RepairParams) (from Device, to D
 		return from, Device{}, err
 	}
 
+	to, err = r.pairAndConnect(ctx, picked, p)
+	if err != nil {
+		return from, to, err
+	}
+
+	return from, to, nil
+}
+
+// repairAddress repairs the paired device with p.Address in place: unpair ->
+// inquiry until it shows up again -> pair -> connect.
+func (r Repairer) repairAddress(ctx context.Context, p RepairParams) (from Device, to Device, err error) {
+	paired, err := r.Bluetooth.List(ctx)
+	if err != nil {
+		return Device{}, Device{}, err
+	}
+	if normalizeQueryAddress(p.Address) == "" {
+		return Device{}, Device{}, fmt.Errorf("invalid device address: %q", p.Address)
+	}
+	matches := findByName(paired, p.Address, true, true)
+	if len(matches) != 1 {
+		return Device{}, Device{}, ErrNotFound{Query: p.Address}
+	}
+	from = matches[0]
+
+	if !p.SkipUnpair {
+		r.progressf("Unpairing %s (%s)...\n", from.DisplayName(), from.Address)
+		if err := r.Bluetooth.Unpair(ctx, from.Address); err != nil {
+			return from, Device{}, err
+		}
+	}
+	found, err := discoverAddress(ctx, r.Bluetooth, r.progressf, from.Address, p.InquiryDuration)
+	if err != nil {
+		return from, Device{}, err
+	}
+	to, err = r.pairAndConnect(ctx, found, p)
+	return from, to, err
+}
+
+func (r Repairer) pairAndConnect(ctx context.Context, picked Device, p RepairParams) (Device, error) {
 	pairer := Pairer{Bluetooth: r.Bluetooth, Picker: r.Picker, ProgressWriter: r.ProgressWriter}
-	to, err = pairer.pairPickedAndConnect(ctx, picked, PairParams{
+	return pairer.pairPickedAndConnect(ctx, picked, PairParams{
 		Interactive:     p.Interactive,
 		IsTTY:           p.IsTTY,
 		InquiryDuration: p.InquiryDuration,
 		Pin:             p.Pin,
 		WaitConnect:     p.WaitConnect,
 		MaxAttempts:     p.MaxAttempts,
+		Retry:           p.Retry,
 	})
-	if err != nil {
-		return from, to, err
-	}
-
-	return from, to, nil
 }
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// resolveParams are the selection rules shared by connect, disconnect and info.
+type resolveParams struct {
+	Title       string // picker title
+	Name        string // name prefix (or exact name) or address; empty opens the picker
+	Exact       bool
+	Interactive bool
+	IsTTY       bool
+	// ShowHidden lets name prefixes match hidden devices too; otherwise
+	// only their address or full name or alias does.
+	ShowHidden bool
+}
+
+// resolveDevice selects one device: by name/address when given (the picker
+// settles ambiguous matches in a TTY, or is forced by Interactive), otherwise
+// through the picker.
+func resolveDevice(ctx context.Context, picker PickerPort, devices []Device, p resolveParams) (Device, error) {
+	if p.Name == "" {
+		if picker == nil {
+			return Device{}, ErrNotFound{Query: ""}
+		}
+		return picker.PickDevice(ctx, p.Title, devices)
+	}
+
+	matches := findByName(devices, p.Name, p.Exact, p.ShowHidden)
+	switch len(matches) {
+	case 0:
+		return Device{}, ErrNotFound{Query: p.Name}
+	case 1:
+		if !p.Interactive {
+			return matches[0], nil
+		}
+		if !p.IsTTY {
+			return Device{}, fmt.Errorf("interactive mode requires a TTY")
+		}
+		if picker == nil {
+			return Device{}, ErrNotFound{Query: p.Name}
+		}
+		return picker.PickDevice(ctx, p.Title, matches)
+	default:
+		if !p.IsTTY || picker == nil {
+			return Device{}, ErrAmbiguous{Query: p.Name, Count: len(matches)}
+		}
+		return picker.PickDevice(ctx, p.Title, matches)
+	}
+}
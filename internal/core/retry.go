@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Clock is the time source of RetryPolicy, so that tests can run retries
+// without waiting.
+type Clock interface {
+	Now() time.Time
+	// Sleep pauses for d, or returns ctx.Err() if ctx is done first.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// SystemClock is the real clock.
+var SystemClock Clock = systemClock{}
+
+// Defaults for RetryPolicy.
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 5 * time.Second
+	DefaultRetryJitter     = 0.2
+)
+
+// RetryPolicy decides how an operation that failed is tried again: how many
+// times, how long to pause in between, and which errors are worth it. The
+// zero value uses the defaults.
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts, the first one included (default 3).
+	MaxAttempts int
+	// Backoff is the pause after the first failed attempt; it doubles after
+	// each one, up to MaxBackoff (defaults 500ms and 5s).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes each pause by up to ±Jitter of it, so that devices
+	// retried together don't retry in lockstep (default 0.2; negative: none).
+	Jitter float64
+	// Deadline bounds the attempts and pauses altogether (zero: no limit).
+	Deadline time.Duration
+
+	// Retryable classifies errors; those it rejects fail right away
+	// (default IsRetryable).
+	Retryable func(error) bool
+	// OnRetry, if set, is told about each pause before it starts.
+	OnRetry func(attempt int, err error, pause time.Duration)
+	// Clock defaults to SystemClock; Rand, the jitter source returning
+	// values in [0, 1), to math/rand.
+	Clock Clock
+	Rand  func() float64
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultRetryBackoff
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = max(DefaultRetryMaxBackoff, p.Backoff)
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultRetryJitter
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+	if p.Clock == nil {
+		p.Clock = SystemClock
+	}
+	if p.Rand == nil {
+		p.Rand = rand.Float64
+	}
+	return p
+}
+
+// Pause returns the pause after the given failed attempt (1-based), before
+// jitter.
+func (p RetryPolicy) Pause(attempt int) time.Duration {
+	p = p.withDefaults()
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+func (p RetryPolicy) jittered(d time.Duration) time.Duration {
+	if p.Jitter == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*p.Rand()-1)))
+}
+
+// Do calls op until it succeeds, returns an error that is not retryable, or
+// the attempts or the deadline run out; then it returns op's last error.
+// op gets the 1-based attempt number.
+func (p RetryPolicy) Do(ctx context.Context, op func(ctx context.Context, attempt int) error) error {
+	p = p.withDefaults()
+	start := p.Clock.Now()
+	if p.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Deadline)
+		defer cancel()
+	}
+	for attempt := 1; ; attempt++ {
+		err := op(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !p.Retryable(err) {
+			return err
+		}
+		pause := p.jittered(p.Pause(attempt))
+		if p.Deadline > 0 && p.Clock.Now().Add(pause).Sub(start) >= p.Deadline {
+			return err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, pause)
+		}
+		if p.Clock.Sleep(ctx, pause) != nil {
+			return err
+		}
+	}
+}
+
+// IsRetryable is the default classification of RetryPolicy. Errors that
+// retrying can't fix fail fast: a missing or too old dependency, an
+// unsupported platform, Bluetooth being off, an unknown or ambiguous device,
+// a canceled operation and permission errors. Everything else, timeouts and
+// "busy" devices first of all, is retried.
+func IsRetryable(err error) bool {
+	var (
+		dm  ErrDependencyMissing
+		old ErrDependencyTooOld
+		up  ErrUnsupportedPlatform
+		off ErrPoweredOff
+		nf  ErrNotFound
+		am  ErrAmbiguous
+		ce  ErrCanceled
+	)
+	switch {
+	case errors.As(err, &dm), errors.As(err, &old), errors.As(err, &up), errors.As(err, &off),
+		errors.As(err, &nf), errors.As(err, &am), errors.As(err, &ce):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, fs.ErrPermission):
+		return false
+	}
+	// Backends often only say it in words.
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"permission denied", "not permitted", "notpermitted", "not authorized", "access denied", "accessdenied",
+		"powered off", "org.bluez.error.notready",
+	} {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Switcher moves from one connected device to another (e.g. headphones)
+// without leaving the user with neither: the new device is connected and
+// verified before the old one is disconnected.
+type Switcher struct {
+	Bluetooth      BluetoothPort
+	Picker         PickerPort
+	ProgressWriter io.Writer
+	// Retry shapes the connect attempts to the new device, within the budget.
+	Retry RetryPolicy
+}
+
+type SwitchParams struct {
+	From        string // name prefix, alias, exact name or address; empty opens the picker
+	To          string
+	Exact       bool
+	Interactive bool
+	IsTTY       bool
+	// Budget bounds connecting and verifying To (default 15s).
+	Budget time.Duration
+}
+
+// Results of the devices in a switch.
+const (
+	SwitchConnected       = "connected"        // To: connected and verified
+	SwitchDisconnected    = "disconnected"     // From: disconnected after To was verified
+	SwitchFailed          = "failed"           // To: could not be verified within the budget
+	SwitchKept            = "kept"             // From: still connected after a failed switch
+	SwitchRestored        = "restored"         // From: dropped during the switch and reconnected
+	SwitchRestoreFailed   = "restore-failed"   // From: dropped and could not be reconnected
+	SwitchDisconnectError = "disconnect-error" // From: To is connected but From could not be disconnected
+)
+
+// SwitchOutcome is what happened to one device.
+type SwitchOutcome struct {
+	Device Device
+	Result string
+	Err    error
+}
+
+type SwitchResult struct {
+	From SwitchOutcome
+	To   SwitchOutcome
+}
+
+// ErrSwitchFailed reports a switch whose new device could not be connected.
+type ErrSwitchFailed struct {
+	To       Device
+	Err      error
+	Restored bool // the old device is connected again (or never dropped)
+}
+
+func (e ErrSwitchFailed) Error() string {
+	s := fmt.Sprintf("switch to %s failed: %v", e.To.DisplayName(), e.Err)
+	if e.Restored {
+		return s + " (previous device kept)"
+	}
+	return s
+}
+
+func (e ErrSwitchFailed) Unwrap() error { return e.Err }
+
+func (s Switcher) progressf(format string, args ...any) {
+	if s.ProgressWriter == nil {
+		return
+	}
+	fmt.Fprintf(s.ProgressWriter, format, args...)
+}
+
+// Resolve selects the devices to switch between (the From picker only offers
+// connected devices). It is separate from Switch so that callers can show
+// the plan first.
+func (s Switcher) Resolve(ctx context.Context, p SwitchParams) (from, to Device, err error) {
+	devices, err := s.Bluetooth.List(ctx)
+	if err != nil {
+		return Device{}, Device{}, err
+	}
+
+	fromCandidates := devices
+	if p.From == "" {
+		fromCandidates = nil
+		for _, d := range devices {
+			if d.Connected {
+				fromCandidates = append(fromCandidates, d)
+			}
+		}
+	}
+	from, err = resolveDevice(ctx, s.Picker, fromCandidates, resolveParams{
+		Title:       "Switch from",
+		Name:        p.From,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return Device{}, Device{}, err
+	}
+
+	toCandidates := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		if !strings.EqualFold(d.Address, from.Address) {
+			toCandidates = append(toCandidates, d)
+		}
+	}
+	to, err = resolveDevice(ctx, s.Picker, toCandidates, resolveParams{
+		Title:       "Switch to",
+		Name:        p.To,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return Device{}, Device{}, err
+	}
+	return from, to, nil
+}
+
+// Switch connects to, verifies it within the budget and only then disconnects
+// from. If to cannot be verified, from is restored (reconnected if connecting
+// to dropped it) and ErrSwitchFailed is returned along with the result.
+func (s Switcher) Switch(ctx context.Context, from, to Device, budget time.Duration) (SwitchResult, error) {
+	if budget <= 0 {
+		budget = 15 * time.Second
+	}
+	res := SwitchResult{From: SwitchOutcome{Device: from}, To: SwitchOutcome{Device: to}}
+
+	s.progressf("Switching from %s (%s) to %s (%s)...\n", from.DisplayName(), from.Address, to.DisplayName(), to.Address)
+	cctx, cancel := context.WithTimeout(ctx, budget)
+	err := connectWithRetryVerify(cctx, s.Bluetooth, s.progressf, to.Address, int(budget.Seconds()), s.Retry)
+	cancel()
+	if err != nil {
+		res.To.Result, res.To.Err = SwitchFailed, err
+		if !to.Connected {
+			// Don't leave a half-connected device behind; best effort. A
+			// device that was connected before the switch is left as it was.
+			_ = s.Bluetooth.Disconnect(ctx, to.Address)
+		}
+		res.From = s.restore(ctx, from)
+		return res, ErrSwitchFailed{To: to, Err: err, Restored: res.From.Result != SwitchRestoreFailed}
+	}
+	res.To.Result = SwitchConnected
+	res.To.Device.Connected = true
+
+	s.progressf("Disconnecting %s (%s)...\n", from.DisplayName(), from.Address)
+	if err := s.Bluetooth.Disconnect(ctx, from.Address); err != nil {
+		res.From.Result, res.From.Err = SwitchDisconnectError, err
+		return res, fmt.Errorf("switched to %s, but disconnecting %s failed: %w", to.DisplayName(), from.DisplayName(), err)
+	}
+	res.From.Result = SwitchDisconnected
+	res.From.Device.Connected = false
+	return res, nil
+}
+
+// restore makes sure from is connected again after a failed switch; it is
+// only reported restored once the connection is verified.
+func (s Switcher) restore(ctx context.Context, from Device) SwitchOutcome {
+	out := SwitchOutcome{Device: from}
+	if ok, err := s.Bluetooth.IsConnected(ctx, from.Address); err == nil && ok {
+		out.Result = SwitchKept
+		return out
+	}
+	if !from.Connected {
+		// It wasn't connected before the switch either; nothing to restore.
+		out.Result = SwitchKept
+		return out
+	}
+	s.progressf("Restoring %s (%s)...\n", from.DisplayName(), from.Address)
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := s.Bluetooth.Connect(rctx, from.Address); err != nil {
+		out.Result, out.Err = SwitchRestoreFailed, err
+		return out
+	}
+	if err := s.Bluetooth.WaitConnect(rctx, from.Address, 10); err != nil {
+		if ok, e := s.Bluetooth.IsConnected(ctx, from.Address); e != nil || !ok {
+			out.Result, out.Err = SwitchRestoreFailed, err
+			return out
+		}
+	}
+	out.Result = SwitchRestored
+	out.Device.Connected = true
+	return out
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Toggler connects a device if it is disconnected and disconnects it if it
+// is connected. It is meant for hotkeys, so it never waits on a picker
+// unless one is given: without a TTY an ambiguous name fails with ErrAmbiguous.
+type Toggler struct {
+	Bluetooth BluetoothPort
+	Picker    PickerPort
+	// Groups are applied when toggling connects a device, as with Connector.
+	Groups []ExclusiveGroup
+	// PollInterval is how often a disconnect is re-checked when verifying (default 250ms).
+	PollInterval time.Duration
+}
+
+type ToggleParams struct {
+	Name        string
+	Exact       bool
+	Interactive bool
+	IsTTY       bool
+	DryRun      bool
+	// Verify, when positive, waits up to this long for the new state to be reported.
+	Verify time.Duration
+}
+
+// ToggleResult is the toggled device with its new Connected state, and the
+// group members displaced if it was connected.
+type ToggleResult struct {
+	Device    Device
+	Displaced []Device
+}
+
+func (t Toggler) Toggle(ctx context.Context, p ToggleParams) (ToggleResult, error) {
+	devices, err := t.Bluetooth.List(ctx)
+	if err != nil {
+		return ToggleResult{}, err
+	}
+	selected, err := resolveDevice(ctx, t.Picker, devices, resolveParams{
+		Title:       "Toggle",
+		Name:        p.Name,
+		Exact:       p.Exact,
+		Interactive: p.Interactive,
+		IsTTY:       p.IsTTY,
+	})
+	if err != nil {
+		return ToggleResult{}, err
+	}
+
+	// The listed state may be stale (or cached, as with blueutil --paired).
+	connected, err := t.Bluetooth.IsConnected(ctx, selected.Address)
+	if err != nil {
+		return ToggleResult{}, err
+	}
+	c := Connector{Bluetooth: t.Bluetooth, Groups: t.Groups}
+
+	if connected {
+		selected.Connected = false
+		if p.DryRun {
+			return ToggleResult{Device: selected}, nil
+		}
+		if err := t.Bluetooth.Disconnect(ctx, selected.Address); err != nil {
+			return ToggleResult{}, err
+		}
+		if p.Verify > 0 {
+			if err := t.waitDisconnected(ctx, selected.Address, p.Verify); err != nil {
+				return ToggleResult{}, err
+			}
+		}
+		return ToggleResult{Device: selected}, nil
+	}
+
+	selected.Connected = true
+	if p.DryRun {
+		displaced, err := c.Displace(ctx, []Device{selected}, true)
+		return ToggleResult{Device: selected, Displaced: displaced}, err
+	}
+	if p.Verify > 0 {
+		vctx, cancel := context.WithTimeout(ctx, p.Verify)
+		err = connectWithRetryVerify(vctx, t.Bluetooth, nil, selected.Address, int(p.Verify.Seconds()), RetryPolicy{MaxAttempts: 1})
+		cancel()
+	} else {
+		err = t.Bluetooth.Connect(ctx, selected.Address)
+	}
+	if err != nil {
+		return ToggleResult{}, err
+	}
+	displaced, err := c.Displace(ctx, []Device{selected}, false)
+	return ToggleResult{Device: selected, Displaced: displaced}, err
+}
+
+func (t Toggler) waitDisconnected(ctx context.Context, address string, timeout time.Duration) error {
+	interval := t.PollInterval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		connected, err := t.Bluetooth.IsConnected(ctx, address)
+		if err != nil {
+			return err
+		}
+		if !connected {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("device is still connected after %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
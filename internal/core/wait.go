@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Waiter blocks until paired devices reach a connection state, for scripts
+// that must not go on before e.g. a headset is really connected.
+type Waiter struct {
+	Bluetooth BluetoothPort
+	// PollInterval is how often the devices are checked (default 500ms).
+	PollInterval time.Duration
+}
+
+type WaitParams struct {
+	// Names select the devices, each matched like connect's name argument
+	// (an ambiguous name fails with ErrAmbiguous; there is no picker).
+	Names []string
+	Exact bool
+	// Disconnected waits for the devices to be disconnected instead of connected.
+	Disconnected bool
+	// All waits for every device; otherwise the first one to reach the state ends the wait.
+	All bool
+	// Timeout bounds the wait (zero: no limit).
+	Timeout time.Duration
+}
+
+// ErrTimeout reports a wait that ran out of time, with the devices that had
+// not reached the state.
+type ErrTimeout struct {
+	Timeout time.Duration
+	Pending []Device
+}
+
+func (e ErrTimeout) Error() string {
+	names := make([]string, 0, len(e.Pending))
+	for _, d := range e.Pending {
+		names = append(names, d.DisplayName())
+	}
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, strings.Join(names, ", "))
+}
+
+// Wait returns the devices in the wanted state once the wait is over: all of
+// them with p.All, otherwise those that were in it when the first one got there.
+func (w Waiter) Wait(ctx context.Context, p WaitParams) ([]Device, error) {
+	if len(p.Names) == 0 {
+		return nil, ErrNotFound{}
+	}
+	devices, err := w.Bluetooth.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Device
+	seen := map[string]bool{}
+	for _, name := range p.Names {
+		d, err := resolveDevice(ctx, nil, devices, resolveParams{Name: name, Exact: p.Exact})
+		if err != nil {
+			return nil, err
+		}
+		if !seen[d.Address] {
+			seen[d.Address] = true
+			targets = append(targets, d)
+		}
+	}
+
+	errTimeout := ErrTimeout{Timeout: p.Timeout, Pending: targets}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.Timeout, errTimeout)
+		defer cancel()
+	}
+	interval := w.PollInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	want := !p.Disconnected
+	for {
+		var done, pending []Device
+		for _, d := range targets {
+			connected, err := w.Bluetooth.IsConnected(ctx, d.Address)
+			if err != nil {
+				if ctx.Err() == nil {
+					return nil, err
+				}
+				pending = append(pending, d)
+				continue
+			}
+			d.Connected = connected
+			if connected == want {
+				done = append(done, d)
+			} else {
+				pending = append(pending, d)
+			}
+		}
+		if ctx.Err() == nil && len(done) > 0 && (!p.All || len(pending) == 0) {
+			return done, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if _, ok := context.Cause(ctx).(ErrTimeout); ok {
+				if len(pending) > 0 {
+					errTimeout.Pending = pending
+				}
+				return nil, errTimeout
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
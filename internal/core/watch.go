@@ -0,0 +1,221 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NotifierPort is implemented by backends that can tell when devices change
+// (e.g. BlueZ's D-Bus signals), so that watchers need not rely on polling alone.
+type NotifierPort interface {
+	// Changes signals whenever device state may have changed, until ctx is
+	// done. Signals carry no details and may be coalesced.
+	Changes(ctx context.Context) (<-chan struct{}, error)
+}
+
+type EventType string
+
+const (
+	EventConnected      EventType = "connected"
+	EventDisconnected   EventType = "disconnected"
+	EventAppeared       EventType = "appeared" // newly paired / listed
+	EventRemoved        EventType = "removed"  // no longer listed (e.g. unpaired)
+	EventRSSIChanged    EventType = "rssi-changed"
+	EventBatteryChanged EventType = "battery-changed"
+)
+
+// EventTypes lists all event types, in the order they are reported for one device.
+var EventTypes = []EventType{
+	EventAppeared, EventConnected, EventDisconnected, EventRSSIChanged, EventBatteryChanged, EventRemoved,
+}
+
+// Event is a change between two snapshots of the device list.
+type Event struct {
+	Type   EventType `json:"type"`
+	Time   time.Time `json:"time"`
+	Device Device    `json:"device"` // the current state (for removed: the last known one)
+	// Previous is the state before the change, for connected/disconnected
+	// and the *-changed events.
+	Previous *Device `json:"previous,omitempty"`
+}
+
+// Diff returns the events that turn prev into cur, ordered by device address.
+// RSSI changes smaller than minRSSIDelta dBm are ignored; they are mostly noise.
+func Diff(prev, cur []Device, now time.Time, minRSSIDelta int) []Event {
+	before := make(map[string]Device, len(prev))
+	for _, d := range prev {
+		before[strings.ToLower(d.Address)] = d
+	}
+	after := make(map[string]Device, len(cur))
+	for _, d := range cur {
+		after[strings.ToLower(d.Address)] = d
+	}
+
+	var events []Event
+	add := func(t EventType, d Device, p *Device) {
+		events = append(events, Event{Type: t, Time: now, Device: d, Previous: p})
+	}
+	for key, d := range after {
+		p, ok := before[key]
+		if !ok {
+			add(EventAppeared, d, nil)
+			if d.Connected {
+				add(EventConnected, d, nil)
+			}
+			continue
+		}
+		switch {
+		case d.Connected && !p.Connected:
+			add(EventConnected, d, &p)
+		case !d.Connected && p.Connected:
+			add(EventDisconnected, d, &p)
+		}
+		if rssiChanged(p.RSSI, d.RSSI, minRSSIDelta) {
+			add(EventRSSIChanged, d, &p)
+		}
+		if batteryChanged(p.Battery, d.Battery) {
+			add(EventBatteryChanged, d, &p)
+		}
+	}
+	for key, p := range before {
+		if _, ok := after[key]; !ok {
+			add(EventRemoved, p, nil)
+		}
+	}
+
+	order := make(map[EventType]int, len(EventTypes))
+	for i, t := range EventTypes {
+		order[t] = i
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := strings.ToLower(events[i].Device.Address), strings.ToLower(events[j].Device.Address)
+		if a != b {
+			return a < b
+		}
+		return order[events[i].Type] < order[events[j].Type]
+	})
+	return events
+}
+
+func rssiChanged(a, b *int, minDelta int) bool {
+	if a == nil || b == nil {
+		return (a == nil) != (b == nil)
+	}
+	d := *a - *b
+	if d < 0 {
+		d = -d
+	}
+	return d > 0 && d >= minDelta
+}
+
+func batteryChanged(a, b *Battery) bool {
+	if a == nil || b == nil {
+		return (a == nil) != (b == nil)
+	}
+	eq := func(x, y *int) bool { return (x == nil && y == nil) || (x != nil && y != nil && *x == *y) }
+	return !eq(a.Main, b.Main) || !eq(a.Left, b.Left) || !eq(a.Right, b.Right) || !eq(a.Case, b.Case)
+}
+
+// Watcher reports device changes by diffing successive device lists. It
+// re-lists every Interval, and in addition whenever Notifier signals a change.
+type Watcher struct {
+	Bluetooth BluetoothPort
+	// Notifier is optional; without it changes are only seen by polling.
+	Notifier NotifierPort
+	// Enricher optionally adds details to each list, as for Lister; it is
+	// where battery levels come from on macOS. Its failures are ignored.
+	Enricher EnricherPort
+	// Interval between polls (default 2s).
+	Interval time.Duration
+	// MinRSSIDelta is the smallest RSSI change reported (default 5 dBm).
+	MinRSSIDelta int
+	// OnError is called when listing fails; watching goes on. Optional.
+	OnError func(error)
+	// Now is the clock (default time.Now).
+	Now func() time.Time
+}
+
+// Watch emits events until ctx is done (returning nil) or emit fails
+// (returning its error). The first list only sets the baseline; it is passed
+// to initial (optional), e.g. to check a condition that already holds.
+func (w Watcher) Watch(ctx context.Context, initial func([]Device) error, emit func(Event) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	minDelta := w.MinRSSIDelta
+	if minDelta <= 0 {
+		minDelta = 5
+	}
+	now := w.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	prev, err := w.list(ctx)
+	if err != nil {
+		return err
+	}
+	if initial != nil {
+		if err := initial(prev); err != nil {
+			return err
+		}
+	}
+
+	var changes <-chan struct{}
+	if w.Notifier != nil {
+		// Polling still works without notifications, so a failure is only reported.
+		if ch, err := w.Notifier.Changes(ctx); err != nil {
+			w.report(err)
+		} else {
+			changes = ch
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+		}
+
+		cur, err := w.list(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.report(err)
+			continue
+		}
+		for _, ev := range Diff(prev, cur, now(), minDelta) {
+			if err := emit(ev); err != nil {
+				return err
+			}
+		}
+		prev = cur
+	}
+}
+
+func (w Watcher) list(ctx context.Context) ([]Device, error) {
+	devices, err := w.Bluetooth.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices, _ = Enrich(ctx, w.Enricher, devices)
+	return devices, nil
+}
+
+func (w Watcher) report(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/rpc"
+)
+
+// Client talks to a daemon on Socket. Each call uses its own connection,
+// which is closed when the call's context is done so that the daemon
+// cancels the backend call as well.
+type Client struct {
+	Socket string
+}
+
+var (
+	_ core.BluetoothPort = Client{}
+	_ core.AdapterPort   = Client{}
+	_ core.EnricherPort  = Client{}
+)
+
+// ErrNotRunning is returned when no daemon listens on the socket.
+var ErrNotRunning = errors.New("daemon is not running")
+
+func (c Client) call(ctx context.Context, method string, params, result any) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", c.Socket)
+	if err != nil {
+		return fmt.Errorf("%w (%s)", ErrNotRunning, c.Socket)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := rpc.Call(ctx, conn, method, params, result, nil); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var re *rpc.Error
+		if !errors.As(err, &re) {
+			return fmt.Errorf("daemon: %s: %w", method, err)
+		}
+		return rpc.DecodeCoreError(err)
+	}
+	return nil
+}
+
+// Probe returns the status of the daemon on socket, waiting at most timeout;
+// it fails fast when none is running.
+func Probe(ctx context.Context, socket string, timeout time.Duration) (Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return Client{Socket: socket}.Status(ctx)
+}
+
+func (c Client) Status(ctx context.Context) (Status, error) {
+	var st Status
+	err := c.call(ctx, methodStatus, struct{}{}, &st)
+	return st, err
+}
+
+// Shutdown asks the daemon to exit. The daemon may close the connection
+// before answering; the shutdown succeeded if it no longer accepts calls.
+func (c Client) Shutdown(ctx context.Context) error {
+	err := c.call(ctx, methodShutdown, struct{}{}, nil)
+	if err == nil || errors.Is(err, ErrNotRunning) {
+		return err
+	}
+	if _, perr := c.Status(ctx); errors.Is(perr, ErrNotRunning) {
+		return nil
+	}
+	return err
+}
+
+func (c Client) List(ctx context.Context) ([]core.Device, error) {
+	var devices []core.Device
+	err := c.call(ctx, methodList, struct{}{}, &devices)
+	return devices, err
+}
+
+func (c Client) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	var devices []core.Device
+	err := c.call(ctx, methodConnectedDevices, struct{}{}, &devices)
+	return devices, err
+}
+
+func (c Client) Connect(ctx context.Context, address string) error {
+	return c.call(ctx, methodConnect, addressParams{Address: address}, nil)
+}
+
+func (c Client) Disconnect(ctx context.Context, address string) error {
+	return c.call(ctx, methodDisconnect, addressParams{Address: address}, nil)
+}
+
+func (c Client) Pair(ctx context.Context, address string, pin string) error {
+	return c.call(ctx, methodPair, pairParams{Address: address, PIN: pin}, nil)
+}
+
+func (c Client) Unpair(ctx context.Context, address string) error {
+	return c.call(ctx, methodUnpair, addressParams{Address: address}, nil)
+}
+
+func (c Client) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	var devices []core.Device
+	err := c.call(ctx, methodInquiry, inquiryParams{Duration: durationSeconds}, &devices)
+	return devices, err
+}
+
+func (c Client) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	return c.call(ctx, methodWaitConnect, waitParams{Address: address, Timeout: timeoutSeconds}, nil)
+}
+
+func (c Client) IsConnected(ctx context.Context, address string) (bool, error) {
+	var ok bool
+	err := c.call(ctx, methodIsConnected, addressParams{Address: address}, &ok)
+	return ok, err
+}
+
+func (c Client) Details(ctx context.Context) ([]core.Device, error) {
+	var devices []core.Device
+	err := c.call(ctx, methodDetails, struct{}{}, &devices)
+	return devices, err
+}
+
+func (c Client) Power(ctx context.Context) (bool, error) {
+	var on bool
+	err := c.call(ctx, methodPower, struct{}{}, &on)
+	return on, err
+}
+
+func (c Client) SetPower(ctx context.Context, on bool) error {
+	return c.call(ctx, methodSetPower, switchParams{On: on}, nil)
+}
+
+func (c Client) Discoverable(ctx context.Context) (bool, error) {
+	var on bool
+	err := c.call(ctx, methodDiscoverable, struct{}{}, &on)
+	return on, err
+}
+
+func (c Client) SetDiscoverable(ctx context.Context, on bool) error {
+	return c.call(ctx, methodSetDiscoverable, switchParams{On: on}, nil)
+}
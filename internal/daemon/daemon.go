@@ -0,0 +1,554 @@
+// Package daemon keeps one Bluetooth backend open in a long-running process
+// and serves it to CLI invocations over a Unix socket (JSON-RPC, see package
+// rpc). The daemon serializes backend calls (blueutil and bluetoothctl don't
+// like running concurrently) and keeps the device list cached, refreshed in
+// the background, so that commands bound to hotkeys don't wait for a list.
+//
+// Client implements core.BluetoothPort (and AdapterPort, EnricherPort) on top
+// of the socket, so commands work the same with or without a daemon.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/config"
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/hooks"
+	"github.com/fumihumi/bt-manage/internal/rpc"
+)
+
+// SocketEnvVar overrides the socket path.
+const SocketEnvVar = "BT_MANAGE_SOCKET"
+
+// DefaultSocket returns $BT_MANAGE_SOCKET, or daemon.sock in
+// $XDG_RUNTIME_DIR/bt-manage, or else in the config directory.
+func DefaultSocket() (string, error) {
+	if p := os.Getenv(SocketEnvVar); p != "" {
+		return p, nil
+	}
+	if d := os.Getenv("XDG_RUNTIME_DIR"); d != "" && filepath.IsAbs(d) {
+		return filepath.Join(d, "bt-manage", "daemon.sock"), nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// Defaults for Server.
+const (
+	DefaultInterval   = 5 * time.Second
+	DefaultDetailsTTL = 30 * time.Second
+)
+
+// waitConnectPoll is how often waitConnect checks the connection.
+const waitConnectPoll = 200 * time.Millisecond
+
+// Methods served on the socket.
+const (
+	methodStatus           = "status"
+	methodShutdown         = "shutdown"
+	methodList             = "list"
+	methodConnectedDevices = "connectedDevices"
+	methodConnect          = "connect"
+	methodDisconnect       = "disconnect"
+	methodPair             = "pair"
+	methodUnpair           = "unpair"
+	methodInquiry          = "inquiry"
+	methodWaitConnect      = "waitConnect"
+	methodIsConnected      = "isConnected"
+	methodDetails          = "details"
+	methodPower            = "power"
+	methodSetPower         = "setPower"
+	methodDiscoverable     = "discoverable"
+	methodSetDiscoverable  = "setDiscoverable"
+)
+
+type addressParams struct {
+	Address string `json:"address"`
+}
+
+type pairParams struct {
+	Address string `json:"address"`
+	PIN     string `json:"pin,omitempty"`
+}
+
+type inquiryParams struct {
+	Duration int `json:"duration"` // seconds
+}
+
+type waitParams struct {
+	Address string `json:"address"`
+	Timeout int    `json:"timeout"` // seconds
+}
+
+type switchParams struct {
+	On bool `json:"on"`
+}
+
+// Status describes a running daemon.
+type Status struct {
+	Backend   string     `json:"backend"`
+	PID       int        `json:"pid"`
+	Started   time.Time  `json:"started"`
+	Devices   int        `json:"devices"`             // in the cache
+	Refreshed *time.Time `json:"refreshed,omitempty"` // when the cache was last listed
+	Adapter   bool       `json:"adapter"`             // the backend controls the adapter
+	Enricher  bool       `json:"enricher"`            // device details are available
+	Notifier  bool       `json:"notifier"`            // the backend reports changes
+	Hooks     int        `json:"hooks"`               // hooks run on connect/disconnect
+}
+
+// Server serves one backend. The zero value is not usable: Backend and
+// Bluetooth are required.
+type Server struct {
+	Backend   string
+	Bluetooth core.BluetoothPort
+	Adapter   core.AdapterPort  // optional
+	Notifier  core.NotifierPort // optional; changes trigger a refresh
+	Enricher  core.EnricherPort // optional
+	// Interval between background refreshes of the device list (default DefaultInterval).
+	Interval time.Duration
+	// DetailsTTL is how long enricher details are reused (default DefaultDetailsTTL).
+	DetailsTTL time.Duration
+	// Hooks, if set, run when a refresh sees a device connect or disconnect.
+	// Registry (optional) adds aliases and tags to the devices they get.
+	Hooks    *hooks.Runner
+	Registry core.RegistryPort
+	// Log receives refresh errors and hook results (optional).
+	Log io.Writer
+
+	mu sync.Mutex // serializes backend calls
+	em sync.Mutex // serializes enricher calls
+	im sync.Mutex // serializes inquiries, which take seconds and so don't hold mu
+
+	cm        sync.Mutex // guards the cache below
+	devices   []core.Device
+	listed    time.Time
+	stale     bool
+	details   []core.Device
+	detailsAt time.Time
+	lastErr   string
+
+	started time.Time
+	kick    chan struct{}
+	stop    context.CancelFunc
+}
+
+// ErrRunning is returned by Listen when another daemon serves the socket.
+var ErrRunning = errors.New("daemon is already running")
+
+// Listen creates the socket at path, replacing a stale one left by a daemon
+// that did not exit cleanly. Only the current user can connect.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w on %s", ErrRunning, path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("daemon: %w", err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections on ln until ctx is done or a client asks the
+// daemon to shut down, then closes ln and waits for open connections.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, s.stop = context.WithCancel(ctx)
+	defer s.stop() // before waiting for connections
+	s.started = time.Now()
+	s.kick = make(chan struct{}, 1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.refreshLoop(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	srv := rpc.Server{Handler: s.handle, EncodeError: rpc.EncodeCoreError}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("daemon: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			// Unblock the read when shutting down.
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			_ = srv.Serve(ctx, conn, conn)
+		}()
+	}
+}
+
+func (s *Server) refreshLoop(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	var changes <-chan struct{}
+	if s.Notifier != nil {
+		// Polling still works without notifications, so a failure is only reported.
+		if ch, err := s.Notifier.Changes(ctx); err != nil {
+			s.logf("daemon: %v", err)
+		} else {
+			changes = ch
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+			s.reportRefreshError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+			}
+		}
+	}
+}
+
+// reportRefreshError logs err unless it is the same as the last one, so that
+// e.g. a powered-off adapter is reported once rather than on every refresh.
+func (s *Server) reportRefreshError(err error) {
+	s.cm.Lock()
+	repeated := err.Error() == s.lastErr
+	s.lastErr = err.Error()
+	s.cm.Unlock()
+	if !repeated {
+		s.logf("daemon: refresh: %v", err)
+	}
+}
+
+// refresh lists devices, updates the cache and fires hooks for the changes.
+func (s *Server) refresh(ctx context.Context) ([]core.Device, error) {
+	s.mu.Lock()
+	devices, err := s.Bluetooth.List(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.cm.Lock()
+	prev, hadPrev := s.devices, !s.listed.IsZero()
+	s.devices, s.listed, s.stale, s.lastErr = devices, time.Now(), false, ""
+	s.cm.Unlock()
+
+	if hadPrev && s.Hooks != nil {
+		s.fireHooks(ctx, core.Diff(prev, devices, time.Now(), 0))
+	}
+	return cloneDevices(devices), nil
+}
+
+func (s *Server) fireHooks(ctx context.Context, events []core.Event) {
+	var reg core.Registry
+	for _, ev := range events {
+		he, ok := hooks.FromWatch(ev.Type)
+		if !ok {
+			continue
+		}
+		if reg == nil {
+			reg = core.Registry{}
+			if s.Registry != nil {
+				if r, err := s.Registry.Load(ctx); err == nil {
+					reg = r
+				}
+			}
+		}
+		dev := reg.Annotate([]core.Device{ev.Device})[0]
+		s.Hooks.Fire(ctx, hooks.Payload{Event: he, Time: ev.Time, Device: dev})
+	}
+}
+
+// cached returns the cached devices if they are current.
+func (s *Server) cached() ([]core.Device, bool) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	s.cm.Lock()
+	defer s.cm.Unlock()
+	// Twice the interval: a refresh may be waiting for a slow backend call.
+	if s.listed.IsZero() || s.stale || time.Since(s.listed) > 2*interval {
+		return nil, false
+	}
+	return cloneDevices(s.devices), true
+}
+
+// invalidate marks the cache stale after a call that changes devices, so
+// that the next list sees the change, and asks for a refresh (for hooks).
+func (s *Server) invalidate() {
+	s.cm.Lock()
+	s.stale = true
+	s.details = nil
+	s.cm.Unlock()
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) list(ctx context.Context) ([]core.Device, error) {
+	if devices, ok := s.cached(); ok {
+		return devices, nil
+	}
+	return s.refresh(ctx)
+}
+
+func (s *Server) connectedDevices(ctx context.Context) ([]core.Device, error) {
+	if devices, ok := s.cached(); ok {
+		connected := make([]core.Device, 0, len(devices))
+		for _, d := range devices {
+			if d.Connected {
+				connected = append(connected, d)
+			}
+		}
+		return connected, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Bluetooth.ConnectedDevices(ctx)
+}
+
+func (s *Server) detailsOf(ctx context.Context) ([]core.Device, error) {
+	ttl := s.DetailsTTL
+	if ttl <= 0 {
+		ttl = DefaultDetailsTTL
+	}
+	s.em.Lock()
+	defer s.em.Unlock()
+
+	s.cm.Lock()
+	if s.details != nil && time.Since(s.detailsAt) < ttl {
+		d := cloneDevices(s.details)
+		s.cm.Unlock()
+		return d, nil
+	}
+	s.cm.Unlock()
+
+	details, err := s.Enricher.Details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cm.Lock()
+	s.details, s.detailsAt = details, time.Now()
+	s.cm.Unlock()
+	return cloneDevices(details), nil
+}
+
+// waitConnect polls IsConnected rather than calling the backend's
+// WaitConnect, which would keep the backend to itself until the device
+// connects: the connect being waited for, other clients and the refresh
+// all need it meanwhile.
+func (s *Server) waitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	wctx := ctx
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		wctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(waitConnectPoll)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		ok, err := s.Bluetooth.IsConnected(wctx, address)
+		s.mu.Unlock()
+		if err == nil && ok {
+			return nil
+		}
+		if err != nil && wctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-wctx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("daemon: timed out waiting for %s to connect", address)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) status() Status {
+	st := Status{
+		Backend:  s.Backend,
+		PID:      os.Getpid(),
+		Started:  s.started,
+		Adapter:  s.Adapter != nil,
+		Enricher: s.Enricher != nil,
+		Notifier: s.Notifier != nil,
+	}
+	if s.Hooks != nil {
+		st.Hooks = len(s.Hooks.Hooks)
+	}
+	s.cm.Lock()
+	defer s.cm.Unlock()
+	st.Devices = len(s.devices)
+	if !s.listed.IsZero() {
+		t := s.listed
+		st.Refreshed = &t
+	}
+	return st
+}
+
+func (s *Server) handle(ctx context.Context, method string, raw json.RawMessage) (any, error) {
+	// serial runs a backend call that changes device state.
+	serial := func(f func() error) (any, error) {
+		s.mu.Lock()
+		err := f()
+		s.mu.Unlock()
+		s.invalidate()
+		return nil, err
+	}
+
+	switch method {
+	case methodStatus:
+		return s.status(), nil
+	case methodShutdown:
+		s.stop()
+		return nil, nil
+	case methodList:
+		return s.list(ctx)
+	case methodConnectedDevices:
+		return s.connectedDevices(ctx)
+	case methodConnect, methodDisconnect, methodUnpair:
+		var p addressParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		return serial(func() error {
+			switch method {
+			case methodConnect:
+				return s.Bluetooth.Connect(ctx, p.Address)
+			case methodDisconnect:
+				return s.Bluetooth.Disconnect(ctx, p.Address)
+			default:
+				return s.Bluetooth.Unpair(ctx, p.Address)
+			}
+		})
+	case methodPair:
+		var p pairParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		return serial(func() error { return s.Bluetooth.Pair(ctx, p.Address, p.PIN) })
+	case methodInquiry:
+		var p inquiryParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		s.im.Lock()
+		defer s.im.Unlock()
+		return s.Bluetooth.Inquiry(ctx, p.Duration)
+	case methodWaitConnect:
+		var p waitParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		err := s.waitConnect(ctx, p.Address, p.Timeout)
+		s.invalidate()
+		return nil, err
+	case methodIsConnected:
+		var p addressParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.Bluetooth.IsConnected(ctx, p.Address)
+	case methodDetails:
+		if s.Enricher == nil {
+			return nil, rpc.Errorf(rpc.CodeMethodNotFound, "%s: backend %s has no device details", method, s.Backend)
+		}
+		return s.detailsOf(ctx)
+	case methodPower, methodDiscoverable:
+		if s.Adapter == nil {
+			return nil, rpc.Errorf(rpc.CodeMethodNotFound, "%s: backend %s cannot control the adapter", method, s.Backend)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if method == methodPower {
+			return s.Adapter.Power(ctx)
+		}
+		return s.Adapter.Discoverable(ctx)
+	case methodSetPower, methodSetDiscoverable:
+		if s.Adapter == nil {
+			return nil, rpc.Errorf(rpc.CodeMethodNotFound, "%s: backend %s cannot control the adapter", method, s.Backend)
+		}
+		var p switchParams
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		return serial(func() error {
+			if method == methodSetPower {
+				return s.Adapter.SetPower(ctx, p.On)
+			}
+			return s.Adapter.SetDiscoverable(ctx, p.On)
+		})
+	}
+	return nil, rpc.Errorf(rpc.CodeMethodNotFound, "unknown method %q", method)
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return rpc.Errorf(rpc.CodeInvalidParams, "missing params")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return rpc.Errorf(rpc.CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Log == nil {
+		return
+	}
+	fmt.Fprintf(s.Log, strings.TrimSuffix(format, "\n")+"\n", args...)
+}
+
+func cloneDevices(devices []core.Device) []core.Device {
+	return append(make([]core.Device, 0, len(devices)), devices...)
+}
@@ -0,0 +1,280 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// countingBluetooth is a backend that counts calls and fails if two run at once.
+type countingBluetooth struct {
+	mu        sync.Mutex
+	devices   []core.Device
+	lists     int
+	running   int
+	overlap   bool
+	callDelay time.Duration
+	powered   bool
+}
+
+func (b *countingBluetooth) enter() func() {
+	b.mu.Lock()
+	b.running++
+	if b.running > 1 {
+		b.overlap = true
+	}
+	b.mu.Unlock()
+	time.Sleep(b.callDelay)
+	return func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}
+}
+
+func (b *countingBluetooth) List(ctx context.Context) ([]core.Device, error) {
+	defer b.enter()()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lists++
+	return append([]core.Device(nil), b.devices...), nil
+}
+
+func (b *countingBluetooth) setConnected(address string, on bool) error {
+	defer b.enter()()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.powered {
+		return fmt.Errorf("connect %s: %w", address, core.ErrPoweredOff{})
+	}
+	for i := range b.devices {
+		if b.devices[i].Address == address {
+			b.devices[i].Connected = on
+			return nil
+		}
+	}
+	return core.ErrNotFound{Query: address}
+}
+
+func (b *countingBluetooth) Connect(ctx context.Context, address string) error {
+	return b.setConnected(address, true)
+}
+
+func (b *countingBluetooth) Disconnect(ctx context.Context, address string) error {
+	return b.setConnected(address, false)
+}
+
+func (b *countingBluetooth) Pair(ctx context.Context, address, pin string) error { return nil }
+func (b *countingBluetooth) Unpair(ctx context.Context, address string) error    { return nil }
+func (b *countingBluetooth) Inquiry(ctx context.Context, seconds int) ([]core.Device, error) {
+	return nil, nil
+}
+
+func (b *countingBluetooth) WaitConnect(ctx context.Context, address string, seconds int) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *countingBluetooth) IsConnected(ctx context.Context, address string) (bool, error) {
+	defer b.enter()()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, d := range b.devices {
+		if d.Address == address {
+			return d.Connected, nil
+		}
+	}
+	return false, nil
+}
+
+func (b *countingBluetooth) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	return nil, errors.New("not used while the cache is fresh")
+}
+
+func startServer(t *testing.T, s *Server) Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	ln, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return Client{Socket: socket}
+}
+
+func TestServer(t *testing.T) {
+	bt := &countingBluetooth{
+		devices: []core.Device{
+			{Name: "AirPods", Address: "aa:00", Paired: true},
+			{Name: "Mouse", Address: "bb:00", Paired: true, Connected: true},
+		},
+		powered:   true,
+		callDelay: 5 * time.Millisecond,
+	}
+	c := startServer(t, &Server{Backend: "fake", Bluetooth: bt, Interval: time.Hour})
+	ctx := context.Background()
+
+	st, err := c.Status(ctx)
+	if err != nil || st.Backend != "fake" || st.Adapter || st.Enricher {
+		t.Fatalf("Status = %+v, %v", st, err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); st.Refreshed == nil; st, _ = c.Status(ctx) {
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not filled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Lists are served from the cache filled in the background.
+	for i := 0; i < 5; i++ {
+		if _, err := c.List(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bt.mu.Lock()
+	lists := bt.lists
+	bt.mu.Unlock()
+	if lists != 1 {
+		t.Fatalf("backend listed %d times, want 1", lists)
+	}
+	connected, err := c.ConnectedDevices(ctx)
+	if err != nil || len(connected) != 1 || connected[0].Name != "Mouse" {
+		t.Fatalf("ConnectedDevices = %v, %v", connected, err)
+	}
+
+	// Concurrent calls reach the backend one at a time, and a change is
+	// seen by the next list.
+	var wg sync.WaitGroup
+	for _, addr := range []string{"aa:00", "bb:00", "aa:00"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.IsConnected(ctx, addr); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := c.Connect(ctx, "aa:00"); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := c.List(ctx)
+	if err != nil || !devices[0].Connected {
+		t.Fatalf("List after connect = %v, %v", devices, err)
+	}
+	bt.mu.Lock()
+	overlap := bt.overlap
+	bt.mu.Unlock()
+	if overlap {
+		t.Fatal("backend calls overlapped")
+	}
+
+	// Core errors keep their type and message.
+	var nf core.ErrNotFound
+	if err := c.Connect(ctx, "cc:00"); !errors.As(err, &nf) || nf.Query != "cc:00" {
+		t.Fatalf("Connect(unknown) = %#v", err)
+	}
+	bt.mu.Lock()
+	bt.powered = false
+	bt.mu.Unlock()
+	err = c.Disconnect(ctx, "aa:00")
+	if !errors.As(err, new(core.ErrPoweredOff)) || err.Error() != "connect aa:00: Bluetooth is powered off" {
+		t.Fatalf("Disconnect(powered off) = %v", err)
+	}
+
+	// The adapter is not served by a backend that can't control it.
+	if _, err := c.Power(ctx); err == nil || !strings.Contains(err.Error(), "cannot control the adapter") {
+		t.Fatalf("Power = %v", err)
+	}
+}
+
+func TestClient_CancelsCall(t *testing.T) {
+	bt := &countingBluetooth{powered: true}
+	c := startServer(t, &Server{Backend: "fake", Bluetooth: bt, Interval: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.WaitConnect(ctx, "aa:00", 60); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitConnect = %v", err)
+	}
+	// The daemon gave up the call too: the backend is free again.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if _, err := c.IsConnected(ctx2, "aa:00"); err != nil {
+		t.Fatalf("IsConnected after cancel: %v", err)
+	}
+}
+
+func TestServer_ConnectWhileWaiting(t *testing.T) {
+	bt := &countingBluetooth{devices: []core.Device{{Name: "AirPods", Address: "aa:00", Paired: true}}, powered: true}
+	c := startServer(t, &Server{Backend: "fake", Bluetooth: bt, Interval: time.Hour})
+	ctx := context.Background()
+
+	waited := make(chan error, 1)
+	go func() { waited <- c.WaitConnect(ctx, "aa:00", 10) }()
+	time.Sleep(50 * time.Millisecond) // let the wait start
+
+	// The pending wait must not keep the backend from the connect.
+	cctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := c.Connect(cctx, "aa:00"); err != nil {
+		t.Fatalf("Connect while waiting: %v", err)
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("WaitConnect = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitConnect did not see the connect")
+	}
+}
+
+func TestListenAndShutdown(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	c := Client{Socket: socket}
+	if _, err := Probe(context.Background(), socket, time.Second); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Probe without daemon = %v", err)
+	}
+
+	ln, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	s := &Server{Backend: "fake", Bluetooth: &countingBluetooth{}, Interval: time.Hour}
+	go func() { done <- s.Serve(context.Background(), ln) }()
+
+	if _, err := Listen(socket); !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Listen = %v", err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if _, err := c.Status(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Status after shutdown = %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// Package hooks runs user commands when devices connect, disconnect or fail
+// to pair. Hooks are executables in per-event directories under the config
+// directory (hooks/on-connect.d/, ...) or commands declared in config.yaml.
+//
+// A hook gets the device in BT_MANAGE_* environment variables and the whole
+// event as JSON on stdin. Hooks run concurrently up to a limit, each with a
+// timeout, and their exit codes are logged.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Event is what a hook runs on.
+type Event string
+
+const (
+	OnConnect    Event = "connect"
+	OnDisconnect Event = "disconnect"
+	OnPairFailed Event = "pair-failed"
+)
+
+// Events lists all hook events.
+var Events = []Event{OnConnect, OnDisconnect, OnPairFailed}
+
+// ParseEvent accepts an event name, with or without the "on-" prefix.
+func ParseEvent(s string) (Event, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "on-")
+	for _, e := range Events {
+		if s == string(e) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hook event %q (want connect, disconnect or pair-failed)", s)
+}
+
+// FromWatch maps a watch event to a hook event; ok is false for events hooks don't run on.
+func FromWatch(t core.EventType) (Event, bool) {
+	switch t {
+	case core.EventConnected:
+		return OnConnect, true
+	case core.EventDisconnected:
+		return OnDisconnect, true
+	}
+	return "", false
+}
+
+// DirName is the hooks directory inside the config directory.
+const DirName = "hooks"
+
+// Defaults for Runner.
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultConcurrency = 4
+)
+
+// Hook is one command run on an event.
+type Hook struct {
+	Name string // for logs: the file name, or the command
+	On   Event
+	// Device optionally restricts the hook to a device: a name or alias
+	// prefix, or an address. Tag restricts it to devices with that tag.
+	Device string
+	Tag    string
+	// Command is run directly (no shell); Shell, if set instead, is run with sh -c.
+	Command []string
+	Shell   string
+	Timeout time.Duration // 0: the runner's default
+}
+
+// Matches reports whether the hook runs for event ev on device d.
+func (h Hook) Matches(ev Event, d core.Device) bool {
+	if h.On != ev {
+		return false
+	}
+	if h.Tag != "" && !(core.DeviceMeta{Tags: d.Tags}).HasTag(h.Tag) {
+		return false
+	}
+	return (core.Condition{Device: h.Device}).MatchDevice(d)
+}
+
+// Payload is the event passed to hooks (as JSON on stdin).
+type Payload struct {
+	Event  Event       `json:"event"`
+	Time   time.Time   `json:"time"`
+	Device core.Device `json:"device"`
+	Error  string      `json:"error,omitempty"` // pair-failed: why
+}
+
+// LoadDir returns the executables in dir/on-<event>.d/ as hooks, in name
+// order. Missing directories are fine; non-executable files are skipped so
+// that READMEs and disabled hooks (chmod -x) can live next to them.
+func LoadDir(dir string) ([]Hook, error) {
+	var out []Hook
+	for _, ev := range Events {
+		sub := filepath.Join(dir, "on-"+string(ev)+".d")
+		entries, err := os.ReadDir(sub)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("hooks: %w", err)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		for _, ent := range entries {
+			if ent.IsDir() || strings.HasPrefix(ent.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(sub, ent.Name())
+			st, err := os.Stat(path)
+			if err != nil || st.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			out = append(out, Hook{
+				Name:    filepath.Join("on-"+string(ev)+".d", ent.Name()),
+				On:      ev,
+				Command: []string{path},
+			})
+		}
+	}
+	return out, nil
+}
+
+// Runner runs the hooks matching fired events in the background.
+type Runner struct {
+	Hooks       []Hook
+	Timeout     time.Duration // per hook, unless set on the hook (default DefaultTimeout)
+	Concurrency int           // hooks running at once (default DefaultConcurrency)
+	// Log receives one line per finished hook (optional).
+	Log io.Writer
+
+	once sync.Once
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex // serializes Log writes
+}
+
+func (r *Runner) init() {
+	r.once.Do(func() {
+		n := r.Concurrency
+		if n <= 0 {
+			n = DefaultConcurrency
+		}
+		r.sem = make(chan struct{}, n)
+	})
+}
+
+// Fire starts the hooks matching p and returns how many, without waiting
+// for them. Cancelling ctx does not stop started hooks, only their timeouts
+// do, so that a hook fired right before exit still completes (see Wait).
+func (r *Runner) Fire(ctx context.Context, p Payload) int {
+	if r == nil {
+		return 0
+	}
+	r.init()
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	stdin, err := json.Marshal(p)
+	if err != nil {
+		r.logf("hooks: %v", err)
+		return 0
+	}
+
+	n := 0
+	for _, h := range r.Hooks {
+		if !h.Matches(p.Event, p.Device) {
+			continue
+		}
+		n++
+		h := h
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.sem <- struct{}{}
+			defer func() { <-r.sem }()
+			r.run(context.WithoutCancel(ctx), h, p, stdin)
+		}()
+	}
+	return n
+}
+
+// Wait blocks until all fired hooks have finished.
+func (r *Runner) Wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
+
+func (r *Runner) run(ctx context.Context, h Hook, p Payload, stdin []byte) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = r.Timeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var c *exec.Cmd
+	if h.Shell != "" {
+		c = exec.CommandContext(ctx, "/bin/sh", "-c", h.Shell)
+	} else if len(h.Command) > 0 {
+		c = exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	} else {
+		r.logf("hook %s: nothing to run", h.Name)
+		return
+	}
+	c.Env = append(os.Environ(), Env(p)...)
+	c.Stdin = bytes.NewReader(stdin)
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	c.WaitDelay = time.Second
+
+	start := time.Now()
+	err := c.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var status string
+	var ee *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		status = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(err, &ee):
+		status = fmt.Sprintf("exit %d", ee.ExitCode())
+	case err != nil:
+		status = err.Error()
+	default:
+		status = "exit 0"
+	}
+	line := fmt.Sprintf("hook %s (%s %s): %s in %s", h.Name, p.Event, p.Device.Address, status, elapsed)
+	if err != nil {
+		if s := strings.TrimSpace(out.String()); s != "" {
+			line += ": " + lastLine(s)
+		}
+	}
+	r.logf("%s", line)
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Log == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.Log, format+"\n", args...)
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// Env returns the BT_MANAGE_* variables describing p.
+func Env(p Payload) []string {
+	d := p.Device
+	env := []string{
+		"BT_MANAGE_EVENT=" + string(p.Event),
+		"BT_MANAGE_DEVICE_NAME=" + d.Name,
+		"BT_MANAGE_DEVICE_ALIAS=" + d.Alias,
+		"BT_MANAGE_DEVICE_DISPLAY_NAME=" + d.DisplayName(),
+		"BT_MANAGE_DEVICE_ADDRESS=" + d.Address,
+		"BT_MANAGE_DEVICE_TYPE=" + d.Type,
+		"BT_MANAGE_DEVICE_TAGS=" + strings.Join(d.Tags, ","),
+		"BT_MANAGE_DEVICE_CONNECTED=" + strconv.FormatBool(d.Connected),
+	}
+	if d.Battery != nil {
+		if level, ok := d.Battery.Lowest(); ok {
+			env = append(env, "BT_MANAGE_DEVICE_BATTERY="+strconv.Itoa(level))
+		}
+	}
+	if p.Error != "" {
+		env = append(env, "BT_MANAGE_ERROR="+p.Error)
+	}
+	return env
+}
@@ -0,0 +1,112 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, filepath.Join(dir, "on-connect.d", "20-second"), "true\n")
+	writeScript(t, filepath.Join(dir, "on-connect.d", "10-first"), "true\n")
+	writeScript(t, filepath.Join(dir, "on-pair-failed.d", "notify"), "true\n")
+	if err := os.WriteFile(filepath.Join(dir, "on-connect.d", "README"), []byte("docs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	var names []string
+	for _, h := range got {
+		names = append(names, string(h.On)+" "+h.Name)
+	}
+	want := "connect on-connect.d/10-first, connect on-connect.d/20-second, pair-failed on-pair-failed.d/notify"
+	if strings.Join(names, ", ") != want {
+		t.Fatalf("hooks = %v", names)
+	}
+
+	if got, err := LoadDir(filepath.Join(dir, "missing")); err != nil || len(got) != 0 {
+		t.Fatalf("missing dir: %v, %v", got, err)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	var log bytes.Buffer
+	r := &Runner{
+		Hooks: []Hook{
+			{Name: "record", On: OnConnect, Device: "AirPods", Shell: `printf '%s|%s|' "$BT_MANAGE_EVENT" "$BT_MANAGE_DEVICE_ADDRESS" > "` + out + `"; cat >> "` + out + `"`},
+			{Name: "other-device", On: OnConnect, Device: "Mouse", Shell: "exit 1"},
+			{Name: "fails", On: OnConnect, Shell: "echo broken >&2; exit 3"},
+			{Name: "slow", On: OnConnect, Command: []string{"sleep", "5"}, Timeout: 100 * time.Millisecond},
+		},
+		Log: &log,
+	}
+
+	dev := core.Device{Name: "AirPods Pro", Address: "aa:bb:cc:dd:ee:01", Connected: true}
+	if n := r.Fire(context.Background(), Payload{Event: OnConnect, Device: dev}); n != 3 {
+		t.Fatalf("Fire started %d hooks, want 3", n)
+	}
+	r.Wait()
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	env, stdin, _ := strings.Cut(string(b), "|aa:bb:cc:dd:ee:01|")
+	if env != "connect" {
+		t.Fatalf("env = %q", b)
+	}
+	var p Payload
+	if err := json.Unmarshal([]byte(stdin), &p); err != nil || p.Event != OnConnect || p.Device.Name != "AirPods Pro" {
+		t.Fatalf("stdin = %q (%v)", stdin, err)
+	}
+
+	logs := log.String()
+	for _, want := range []string{"hook record (connect aa:bb:cc:dd:ee:01): exit 0", "hook fails (connect aa:bb:cc:dd:ee:01): exit 3 in", ": broken", "hook slow (connect aa:bb:cc:dd:ee:01): timed out after 100ms"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("log lacks %q:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "other-device") {
+		t.Errorf("hook for another device ran:\n%s", logs)
+	}
+}
+
+func TestRunner_Concurrency(t *testing.T) {
+	dir := t.TempDir()
+	// Each hook fails if another one is running at the same time.
+	lock := filepath.Join(dir, "lock")
+	script := `mkdir "` + lock + `" || exit 9; sleep 0.05; rmdir "` + lock + `"`
+	var log bytes.Buffer
+	r := &Runner{Concurrency: 1, Log: &log}
+	for i := 0; i < 4; i++ {
+		r.Hooks = append(r.Hooks, Hook{Name: "h", On: OnDisconnect, Shell: script})
+	}
+	r.Fire(context.Background(), Payload{Event: OnDisconnect, Device: core.Device{Address: "aa"}})
+	r.Wait()
+	if strings.Contains(log.String(), "exit 9") || strings.Count(log.String(), "exit 0") != 4 {
+		t.Fatalf("hooks overlapped:\n%s", log.String())
+	}
+}
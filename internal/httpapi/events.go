@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// keepAlive is how often an idle event stream gets a comment, so that
+// proxies and clients don't time it out.
+const keepAlive = 15 * time.Second
+
+type sequenced struct {
+	id int
+	ev core.Event
+}
+
+// hub fans watcher events out to the /events streams.
+type hub struct {
+	mu   sync.Mutex
+	seq  int
+	subs map[chan sequenced]struct{}
+}
+
+func (h *hub) subscribe() (<-chan sequenced, func()) {
+	ch := make(chan sequenced, 64)
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = map[chan sequenced]struct{}{}
+	}
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+// publish sends ev to every stream; a stream too slow to keep up misses it
+// rather than holding up the others.
+func (h *hub) publish(ev core.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	for ch := range h.subs {
+		select {
+		case ch <- sequenced{id: h.seq, ev: ev}:
+		default:
+		}
+	}
+}
+
+// events streams device changes as Server-Sent Events: the event name is
+// the change type and the data is the event as JSON (see `watch --format
+// ndjson`). ?type=connected,disconnected limits the types sent.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	show := map[core.EventType]bool{}
+	if v := r.URL.Query().Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			c, err := core.ParseCondition(strings.TrimSpace(t))
+			if err != nil || c.Type == "" || c.Device != "" {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("unknown event type: %s", t))
+				return
+			}
+			show[c.Type] = true
+		}
+	}
+
+	events, unsubscribe := s.hub.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": bt-manage events\n\n")
+	flusher.Flush()
+
+	ping := time.NewTicker(keepAlive)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-events:
+			if len(show) > 0 && !show[e.ev.Type] {
+				continue
+			}
+			data, err := json.Marshal(e.ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, e.ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,204 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/jobs"
+	"github.com/fumihumi/bt-manage/internal/platform/sim"
+)
+
+const testScenario = `
+timeScale: 0
+devices:
+  - name: AirPods Pro
+    address: aa:bb:cc:00:00:01
+    type: Headphones
+    paired: true
+  - name: Desk Speaker
+    address: aa:bb:cc:00:00:02
+    type: Speaker
+    paired: true
+    connected: true
+  - name: New Keyboard
+    address: aa:bb:cc:00:00:03
+    type: Keyboard
+    pin: "1234"
+`
+
+type apiClient struct {
+	t     *testing.T
+	base  string
+	token string
+}
+
+func (c apiClient) do(method, path, body string) (*http.Response, string) {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.base+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func startServer(t *testing.T) (apiClient, *sim.Simulator) {
+	t.Helper()
+	scenario, err := sim.ParseScenario([]byte(testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bt := sim.New(scenario)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		Bluetooth: bt,
+		Groups:    []core.ExclusiveGroup{{Name: "audio", Members: []string{"AirPods Pro", "Desk Speaker"}}},
+		Token:     "secret",
+		Interval:  10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return apiClient{t: t, base: "http://" + ln.Addr().String(), token: "secret"}, bt
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	c, _ := startServer(t)
+	for _, token := range []string{"", "wrong"} {
+		c.token = token
+		resp, _ := c.do("GET", "/devices", "")
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Fatalf("token %q: status %d", token, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_Devices(t *testing.T) {
+	c, _ := startServer(t)
+
+	resp, body := c.do("GET", "/devices", "")
+	var devices []core.Device
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(body), &devices) != nil || len(devices) != 2 {
+		t.Fatalf("GET /devices: %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = c.do("GET", "/devices/AA-BB-CC-00-00-02", "")
+	var dev core.Device
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(body), &dev) != nil || dev.Name != "Desk Speaker" || !dev.Connected {
+		t.Fatalf("GET /devices/{addr}: %d %s", resp.StatusCode, body)
+	}
+	if resp, body := c.do("GET", "/devices/Desk", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("a name prefix should not match: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestServer_ConnectStreamsEvents(t *testing.T) {
+	c, _ := startServer(t)
+
+	req, _ := http.NewRequest("GET", c.base+"/events?type=connected,disconnected", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	lines := bufio.NewScanner(stream.Body)
+	lines.Scan() // the opening comment
+	// Let the watcher take its baseline before changing anything.
+	time.Sleep(50 * time.Millisecond)
+
+	resp, body := c.do("POST", "/devices/AirPods%20Pro/connect", "")
+	var entries []struct {
+		Name   string `json:"name"`
+		Action string `json:"action"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(body), &entries) != nil {
+		t.Fatalf("POST connect: %d %s", resp.StatusCode, body)
+	}
+	if len(entries) != 2 || entries[0].Name != "AirPods Pro" || entries[0].Action != "connect" ||
+		entries[1].Name != "Desk Speaker" || entries[1].Action != "disconnect" {
+		t.Fatalf("connect result = %+v", entries)
+	}
+
+	got := map[string]bool{}
+	deadline := time.AfterFunc(5*time.Second, func() { stream.Body.Close() })
+	defer deadline.Stop()
+	var event string
+	for len(got) < 2 && lines.Scan() {
+		line := lines.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			var ev core.Event
+			if err := json.Unmarshal([]byte(v), &ev); err != nil || string(ev.Type) != event {
+				t.Fatalf("data %q for event %q: %v", v, event, err)
+			}
+			got[event+" "+ev.Device.Name] = true
+		}
+	}
+	if !got["connected AirPods Pro"] || !got["disconnected Desk Speaker"] {
+		t.Fatalf("events = %v", got)
+	}
+}
+
+func TestServer_PairJob(t *testing.T) {
+	c, bt := startServer(t)
+
+	if resp, body := c.do("POST", "/pair", `{"pin": "1234"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("pair without address: %d %s", resp.StatusCode, body)
+	}
+	resp, body := c.do("POST", "/pair", `{"address": "aa:bb:cc:00:00:03", "pin": "1234"}`)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") == "" {
+		t.Fatalf("POST /pair: %d %s", resp.StatusCode, body)
+	}
+
+	var job jobs.Job
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		_, body := c.do("GET", resp.Header.Get("Location"), "")
+		if err := json.Unmarshal([]byte(body), &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != jobs.Running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Status != jobs.Succeeded || job.Device == nil || job.Device.Name != "New Keyboard" || len(job.Progress) == 0 {
+		t.Fatalf("job = %+v", job)
+	}
+	if ok, _ := bt.IsConnected(context.Background(), "aa:bb:cc:00:00:03"); !ok {
+		t.Fatal("keyboard should be connected after pairing")
+	}
+	if resp, _ := c.do("GET", "/jobs/99", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown job: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/fumihumi/bt-manage/internal/jobs"
+)
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// startPair takes a jobs.PairRequest.
+func (s *Server) startPair(w http.ResponseWriter, r *http.Request) {
+	var req jobs.PairRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	run, err := req.Pair(s.Bluetooth, s.Retry)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.runJob(w, "pair", req.Address, run)
+}
+
+// startRepair takes a jobs.RepairRequest.
+func (s *Server) startRepair(w http.ResponseWriter, r *http.Request) {
+	var req jobs.RepairRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	run, err := req.Repair(s.Bluetooth, s.Retry)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.runJob(w, "repair", req.Address, run)
+}
+
+// runJob starts run in the background and responds 202 with the job.
+func (s *Server) runJob(w http.ResponseWriter, kind, address string, run jobs.Func) {
+	j, begin, err := s.jobs.Start(s.base, kind, address, run, jobs.Observer{
+		Finished: func(j jobs.Job, err error) {
+			if err != nil && s.OnPairFailed != nil && j.Device != nil {
+				s.OnPairFailed(*j.Device, err)
+			}
+		},
+	})
+	if err != nil { // jobs.ErrBusy
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	begin()
+
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.List())
+}
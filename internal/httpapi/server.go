@@ -0,0 +1,281 @@
+// Package httpapi serves bt-manage over local HTTP for integrations such as
+// Stream Deck buttons and home automation:
+//
+//	GET  /devices                    paired devices, like `list --format json`
+//	GET  /devices/{device}           one device, like `info --format json`
+//	POST /devices/{device}/connect   like `connect` (exclusive groups apply)
+//	POST /devices/{device}/disconnect
+//	POST /pair, POST /repair         start a job; poll GET /jobs/{id}
+//	GET  /events                     device changes as Server-Sent Events
+//
+// {device} is an address, or an exact name or alias. Every request must
+// carry the token as "Authorization: Bearer <token>".
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/jobs"
+	"github.com/fumihumi/bt-manage/internal/output"
+)
+
+// Defaults for Server.
+const (
+	DefaultInterval  = 2 * time.Second
+	DefaultOpTimeout = 30 * time.Second
+)
+
+// Server is the HTTP API over one Bluetooth env.
+type Server struct {
+	Bluetooth core.BluetoothPort
+	Enricher  core.EnricherPort // optional
+	Notifier  core.NotifierPort // optional; /events then sees changes right away
+	Groups    []core.ExclusiveGroup
+	// Token is the bearer token clients must present; it must not be empty.
+	Token string
+	// Interval between device polls for /events (default DefaultInterval).
+	Interval time.Duration
+	// OpTimeout bounds connect and disconnect requests (default DefaultOpTimeout).
+	OpTimeout time.Duration
+	// Retry shapes connects, including those of pair and repair jobs.
+	Retry core.RetryPolicy
+	// OnPairFailed is called when a pair or repair job fails for a device (optional).
+	OnPairFailed func(dev core.Device, err error)
+	// Log receives request errors and watcher errors (optional).
+	Log io.Writer
+
+	once sync.Once
+	jobs jobs.Manager
+	hub  hub
+	base context.Context // jobs outlive their request, not the server
+	mu   sync.Mutex      // serializes Log writes
+}
+
+func (s *Server) init() {
+	s.once.Do(func() {
+		if s.base == nil {
+			s.base = context.Background()
+		}
+	})
+}
+
+// Serve serves the API on ln and watches devices for /events until ctx is
+// done; running jobs are then cancelled.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.Token == "" {
+		return errors.New("httpapi: no token")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.base = ctx
+	s.init()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.watch(ctx)
+	}()
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer scancel()
+		_ = srv.Shutdown(sctx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// watch feeds /events. A failing watcher is restarted, so that e.g. an
+// adapter that is off at startup doesn't end the stream for good.
+func (s *Server) watch(ctx context.Context) {
+	interval := s.interval()
+	w := core.Watcher{
+		Bluetooth: s.Bluetooth,
+		Notifier:  s.Notifier,
+		Enricher:  s.Enricher,
+		Interval:  interval,
+		OnError:   func(err error) { s.logf("events: %v", err) },
+	}
+	for ctx.Err() == nil {
+		err := w.Watch(ctx, nil, func(ev core.Event) error {
+			s.hub.publish(ev)
+			return nil
+		})
+		if err != nil {
+			s.logf("events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *Server) interval() time.Duration {
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	return DefaultInterval
+}
+
+// Handler returns the API's routes behind the token check.
+func (s *Server) Handler() http.Handler {
+	s.init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices", s.listDevices)
+	mux.HandleFunc("GET /devices/{device}", s.getDevice)
+	mux.HandleFunc("POST /devices/{device}/connect", s.connect)
+	mux.HandleFunc("POST /devices/{device}/disconnect", s.disconnect)
+	mux.HandleFunc("POST /pair", s.startPair)
+	mux.HandleFunc("POST /repair", s.startRepair)
+	mux.HandleFunc("GET /jobs", s.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.getJob)
+	mux.HandleFunc("GET /events", s.events)
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if s.Token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bt-manage"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := core.Lister{Bluetooth: s.Bluetooth, Enricher: s.Enricher}.ListDevices(r.Context())
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = output.WriteJSON(w, devices)
+}
+
+func (s *Server) getDevice(w http.ResponseWriter, r *http.Request) {
+	dev, err := core.Inspector{Bluetooth: s.Bluetooth, Enricher: s.Enricher}.Inspect(r.Context(), core.InspectParams{
+		Name:  r.PathValue("device"),
+		Exact: true,
+	})
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dev)
+}
+
+func (s *Server) opContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := s.OpTimeout
+	if timeout <= 0 {
+		timeout = DefaultOpTimeout
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// connect responds like `connect --format json`: the device, and the group
+// members it displaced, each with an "action".
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.opContext(r)
+	defer cancel()
+	c := core.Connector{Bluetooth: s.Bluetooth, Groups: s.Groups, Retry: s.Retry}
+	res, err := c.Connect(ctx, core.ConnectParams{Name: r.PathValue("device"), Exact: true})
+	if err != nil && res.Device.Address == "" {
+		s.fail(w, r, err)
+		return
+	}
+	if err != nil {
+		// Connected, but displacing group members failed: still a success for the device.
+		s.logf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = output.WriteConnectJSON(w, []core.Device{res.Device}, res.Displaced)
+}
+
+func (s *Server) disconnect(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.opContext(r)
+	defer cancel()
+	d := core.Disconnector{Bluetooth: s.Bluetooth}
+	dev, err := d.DisconnectByNameOrInteractive(ctx, core.DisconnectParams{Name: r.PathValue("device"), Exact: true})
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	dev.Connected = false
+	w.Header().Set("Content-Type", "application/json")
+	_ = output.WriteConnectJSON(w, nil, []core.Device{dev})
+}
+
+// fail writes err with the status that fits it.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusFor(err)
+	if status >= 500 {
+		s.logf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	writeError(w, status, err)
+}
+
+func statusFor(err error) int {
+	var (
+		nf core.ErrNotFound
+		am core.ErrAmbiguous
+		po core.ErrPoweredOff
+	)
+	switch {
+	case errors.As(err, &nf):
+		return http.StatusNotFound
+	case errors.As(err, &am):
+		return http.StatusConflict
+	case errors.As(err, &po):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Log == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.Log, "http: "+strings.TrimSuffix(format, "\n")+"\n", args...)
+}
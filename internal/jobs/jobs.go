@@ -0,0 +1,227 @@
+// Package jobs runs pairs and repairs started over an API in the
+// background. Pairing involves a scan and retries that take longer than a
+// request should, so API clients start a job and then poll it or follow its
+// notifications.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Job states.
+const (
+	Running   = "running"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+)
+
+// DefaultTimeout bounds a job, like the pair and repair commands.
+const DefaultTimeout = 3 * time.Minute
+
+// maxFinished bounds how many finished jobs are kept for polling.
+const maxFinished = 50
+
+// Job is a pair or repair run by a Manager.
+type Job struct {
+	ID       string       `json:"id"`
+	Kind     string       `json:"kind"` // "pair" or "repair"
+	Address  string       `json:"address"`
+	Status   string       `json:"status"`
+	Device   *core.Device `json:"device,omitempty"` // the paired device, once known
+	Error    string       `json:"error,omitempty"`
+	Progress []string     `json:"progress"`
+	Started  time.Time    `json:"started"`
+	Finished *time.Time   `json:"finished,omitempty"`
+}
+
+// Func does the work of a job, writing progress lines to progress.
+type Func func(ctx context.Context, progress io.Writer) (core.Device, error)
+
+// Observer follows a job as it runs. Both callbacks are optional and are
+// called from the job's goroutine.
+type Observer struct {
+	// Progress gets each progress line of the job.
+	Progress func(id, line string)
+	// Finished gets the finished job and, if it failed, its error.
+	Finished func(j Job, err error)
+}
+
+// ErrBusy is returned by Start while another job is running.
+type ErrBusy struct {
+	Job Job
+}
+
+func (e ErrBusy) Error() string {
+	return fmt.Sprintf("%s job %s is still running", e.Job.Kind, e.Job.ID)
+}
+
+// Manager keeps the jobs of one API server. The zero value is ready to use.
+type Manager struct {
+	// Timeout bounds each job (default DefaultTimeout).
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	next  int
+	byID  map[string]*Job
+	order []string
+	wg    sync.WaitGroup
+}
+
+// Start registers a job, unless a pair or repair is already running (they
+// would fight over the radio), and returns it with a function that runs it
+// in the background under ctx. Callers that must answer with the job before
+// any progress is reported call begin once they have.
+func (m *Manager) Start(ctx context.Context, kind, address string, run Func, obs Observer) (j Job, begin func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.byID == nil {
+		m.byID = map[string]*Job{}
+	}
+	for _, id := range m.order {
+		if r := m.byID[id]; r.Status == Running {
+			return Job{}, nil, ErrBusy{Job: snapshot(r)}
+		}
+	}
+	m.next++
+	job := &Job{
+		ID:       strconv.Itoa(m.next),
+		Kind:     kind,
+		Address:  address,
+		Status:   Running,
+		Progress: []string{},
+		Started:  time.Now(),
+	}
+	m.byID[job.ID] = job
+	m.order = append(m.order, job.ID)
+	m.prune()
+
+	m.wg.Add(1)
+	var once sync.Once
+	begin = func() {
+		once.Do(func() { go m.run(ctx, job, run, obs) })
+	}
+	return snapshot(job), begin, nil
+}
+
+func (m *Manager) run(ctx context.Context, job *Job, run Func, obs Observer) {
+	defer m.wg.Done()
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dev, err := run(ctx, &progressWriter{m: m, job: job, obs: obs})
+	now := time.Now()
+	m.mu.Lock()
+	job.Finished = &now
+	if dev.Address != "" {
+		job.Device = &dev
+	}
+	if err != nil {
+		job.Status, job.Error = Failed, err.Error()
+	} else {
+		job.Status = Succeeded
+	}
+	done := snapshot(job)
+	m.mu.Unlock()
+	if obs.Finished != nil {
+		obs.Finished(done, err)
+	}
+}
+
+// prune drops the oldest finished jobs beyond maxFinished. Caller holds m.mu.
+func (m *Manager) prune() {
+	finished := 0
+	for _, id := range m.order {
+		if m.byID[id].Status != Running {
+			finished++
+		}
+	}
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > maxFinished && m.byID[id].Status != Running {
+			delete(m.byID, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+// snapshot copies j, so that it can be encoded while the job runs. Caller
+// holds m.mu.
+func snapshot(j *Job) Job {
+	c := *j
+	c.Progress = append([]string{}, j.Progress...)
+	return c
+}
+
+// Get returns the job with the given ID.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.byID[id]
+	if !ok {
+		return Job{}, false
+	}
+	return snapshot(j), true
+}
+
+// List returns the kept jobs, oldest first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.order))
+	for _, id := range m.order {
+		out = append(out, snapshot(m.byID[id]))
+	}
+	return out
+}
+
+// Wait waits for the started jobs to finish.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// progressWriter appends the progress lines of a running job.
+type progressWriter struct {
+	m   *Manager
+	job *Job
+	obs Observer
+	buf bytes.Buffer
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write.
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		line = strings.TrimRight(line, "\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		w.m.mu.Lock()
+		w.job.Progress = append(w.job.Progress, line)
+		w.m.mu.Unlock()
+		if w.obs.Progress != nil {
+			w.obs.Progress(w.job.ID, line)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// PairRequest starts a pair job for the device at Address. Durations are in
+// seconds; zero values take the same defaults as the pair command. A
+// MaxAttempts given overrides the one of the server's retry policy.
+type PairRequest struct {
+	Address     string `json:"address"`
+	PIN         string `json:"pin,omitempty"`
+	Inquiry     int    `json:"inquirySeconds,omitempty"`
+	WaitConnect int    `json:"waitConnectSeconds,omitempty"`
+	MaxAttempts int    `json:"maxAttempts,omitempty"`
+}
+
+// RepairRequest starts a repair job: the paired device at Address is
+// unpaired (unless SkipUnpair), found again and paired.
+type RepairRequest struct {
+	PairRequest
+	SkipUnpair bool `json:"skipUnpair,omitempty"`
+}
+
+// normalize checks r and fills in the defaults.
+func (r *PairRequest) normalize() error {
+	if r.Address == "" {
+		return errors.New("address is required")
+	}
+	if r.Inquiry <= 0 {
+		r.Inquiry = 60
+	}
+	if r.WaitConnect <= 0 {
+		r.WaitConnect = 10
+	}
+	return nil
+}
+
+// retry is policy with r's attempts, or the pair command's default of 6 if
+// neither sets them.
+func (r PairRequest) retry(policy core.RetryPolicy) core.RetryPolicy {
+	switch {
+	case r.MaxAttempts > 0:
+		policy.MaxAttempts = r.MaxAttempts
+	case policy.MaxAttempts <= 0:
+		policy.MaxAttempts = 6
+	}
+	return policy
+}
+
+// Pair returns the job for r, or an error if r is invalid. Its connect
+// attempts follow retry.
+func (r PairRequest) Pair(bt core.BluetoothPort, retry core.RetryPolicy) (Func, error) {
+	if err := r.normalize(); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, progress io.Writer) (core.Device, error) {
+		p := core.Pairer{Bluetooth: bt, ProgressWriter: progress}
+		return p.Pair(ctx, core.PairParams{
+			Address:         r.Address,
+			InquiryDuration: r.Inquiry,
+			Pin:             r.PIN,
+			WaitConnect:     r.WaitConnect,
+			Retry:           r.retry(retry),
+		})
+	}, nil
+}
+
+// Repair returns the job for r, or an error if r is invalid. Its connect
+// attempts follow retry.
+func (r RepairRequest) Repair(bt core.BluetoothPort, retry core.RetryPolicy) (Func, error) {
+	if err := r.normalize(); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, progress io.Writer) (core.Device, error) {
+		rp := core.Repairer{Bluetooth: bt, ProgressWriter: progress}
+		_, to, err := rp.Repair(ctx, core.RepairParams{
+			Address:         r.Address,
+			InquiryDuration: r.Inquiry,
+			Pin:             r.PIN,
+			SkipUnpair:      r.SkipUnpair,
+			WaitConnect:     r.WaitConnect,
+			Retry:           r.retry(retry),
+		})
+		return to, err
+	}, nil
+}
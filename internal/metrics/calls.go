@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the backend call
+// latency histogram. blueutil calls take tens of milliseconds when all is
+// well and seconds when the radio is busy.
+var DurationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Calls counts the calls made to one backend. The zero value is ready to
+// use; Wrap a port to have its calls counted.
+type Calls struct {
+	// Backend is the backend label of the call metrics.
+	Backend string
+
+	mu      sync.Mutex
+	methods map[string]*callStats
+}
+
+type callStats struct {
+	count   uint64
+	errors  uint64
+	sum     float64
+	buckets []uint64 // per DurationBuckets, not cumulative
+}
+
+// Observe records a call to method that took d and returned err.
+func (c *Calls) Observe(method string, d time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.methods == nil {
+		c.methods = map[string]*callStats{}
+	}
+	s, ok := c.methods[method]
+	if !ok {
+		s = &callStats{buckets: make([]uint64, len(DurationBuckets))}
+		c.methods[method] = s
+	}
+	s.count++
+	if err != nil {
+		s.errors++
+	}
+	secs := d.Seconds()
+	s.sum += secs
+	for i, le := range DurationBuckets {
+		if secs <= le {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+// write writes the call metrics, methods in name order.
+func (c *Calls) write(w *writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	methods := make([]string, 0, len(c.methods))
+	for m := range c.methods {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+
+	w.family(BackendCalls, "counter", "Calls made to the Bluetooth backend.")
+	for _, m := range methods {
+		w.sample(BackendCalls, float64(c.methods[m].count), "backend", c.Backend, "method", m)
+	}
+	w.family(BackendCallErrors, "counter", "Calls to the Bluetooth backend that returned an error.")
+	for _, m := range methods {
+		w.sample(BackendCallErrors, float64(c.methods[m].errors), "backend", c.Backend, "method", m)
+	}
+	w.family(BackendCallSeconds, "histogram", "Latency of calls to the Bluetooth backend.")
+	for _, m := range methods {
+		s := c.methods[m]
+		var cum uint64
+		for i, le := range DurationBuckets {
+			cum += s.buckets[i]
+			w.sample(BackendCallSeconds+"_bucket", float64(cum), "backend", c.Backend, "method", m, "le", formatValue(le))
+		}
+		w.sample(BackendCallSeconds+"_bucket", float64(s.count), "backend", c.Backend, "method", m, "le", "+Inf")
+		w.sample(BackendCallSeconds+"_sum", s.sum, "backend", c.Backend, "method", m)
+		w.sample(BackendCallSeconds+"_count", float64(s.count), "backend", c.Backend, "method", m)
+	}
+}
+
+// Wrap returns bt with its calls counted in c. The method label is the
+// BluetoothPort method name in lower camel case (list, connect, waitConnect, ...).
+func (c *Calls) Wrap(bt core.BluetoothPort) core.BluetoothPort {
+	return instrumented{bt: bt, calls: c}
+}
+
+type instrumented struct {
+	bt    core.BluetoothPort
+	calls *Calls
+}
+
+func (p instrumented) observe(method string, start time.Time, err *error) {
+	p.calls.Observe(method, time.Since(start), *err)
+}
+
+func (p instrumented) List(ctx context.Context) (devices []core.Device, err error) {
+	defer p.observe("list", time.Now(), &err)
+	return p.bt.List(ctx)
+}
+
+func (p instrumented) Connect(ctx context.Context, address string) (err error) {
+	defer p.observe("connect", time.Now(), &err)
+	return p.bt.Connect(ctx, address)
+}
+
+func (p instrumented) Disconnect(ctx context.Context, address string) (err error) {
+	defer p.observe("disconnect", time.Now(), &err)
+	return p.bt.Disconnect(ctx, address)
+}
+
+func (p instrumented) Pair(ctx context.Context, address, pin string) (err error) {
+	defer p.observe("pair", time.Now(), &err)
+	return p.bt.Pair(ctx, address, pin)
+}
+
+func (p instrumented) Unpair(ctx context.Context, address string) (err error) {
+	defer p.observe("unpair", time.Now(), &err)
+	return p.bt.Unpair(ctx, address)
+}
+
+func (p instrumented) Inquiry(ctx context.Context, durationSeconds int) (devices []core.Device, err error) {
+	defer p.observe("inquiry", time.Now(), &err)
+	return p.bt.Inquiry(ctx, durationSeconds)
+}
+
+func (p instrumented) WaitConnect(ctx context.Context, address string, timeoutSeconds int) (err error) {
+	defer p.observe("waitConnect", time.Now(), &err)
+	return p.bt.WaitConnect(ctx, address, timeoutSeconds)
+}
+
+func (p instrumented) IsConnected(ctx context.Context, address string) (ok bool, err error) {
+	defer p.observe("isConnected", time.Now(), &err)
+	return p.bt.IsConnected(ctx, address)
+}
+
+func (p instrumented) ConnectedDevices(ctx context.Context) (devices []core.Device, err error) {
+	defer p.observe("connectedDevices", time.Now(), &err)
+	return p.bt.ConnectedDevices(ctx)
+}
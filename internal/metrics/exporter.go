@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Defaults for Exporter.
+const (
+	DefaultInterval = 15 * time.Second
+	// refreshTimeout bounds one device listing, enrichment included.
+	refreshTimeout = 30 * time.Second
+)
+
+// Exporter lists devices periodically and serves the result, with the call
+// statistics of Calls, as Prometheus metrics.
+type Exporter struct {
+	// Bluetooth is listed every Interval; wrap it with Calls.Wrap to export
+	// its call statistics.
+	Bluetooth core.BluetoothPort
+	Enricher  core.EnricherPort // optional; battery levels come from here on macOS
+	Calls     *Calls            // optional
+	// Interval between device listings (default DefaultInterval).
+	Interval time.Duration
+	// Log receives listing errors (optional).
+	Log io.Writer
+
+	mu        sync.Mutex
+	devices   []core.Device
+	seen      map[string]time.Time // when each device was last seen connected
+	up        bool
+	refreshed time.Time
+	failures  uint64
+}
+
+// Refresh lists the devices once.
+func (x *Exporter) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+	devices, err := core.Lister{Bluetooth: x.Bluetooth, Enricher: x.Enricher}.ListDevices(ctx)
+	now := time.Now()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil {
+		x.up = false
+		x.failures++
+		return err
+	}
+	seen := make(map[string]time.Time, len(devices))
+	for _, d := range devices {
+		if d.Connected {
+			seen[d.Address] = now
+		} else if t, ok := x.seen[d.Address]; ok {
+			seen[d.Address] = t
+		}
+	}
+	x.devices, x.seen = devices, seen
+	x.up, x.refreshed = true, now
+	return nil
+}
+
+// Run refreshes every Interval until ctx is done.
+func (x *Exporter) Run(ctx context.Context) {
+	interval := x.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := x.Refresh(ctx); err != nil && ctx.Err() == nil && x.Log != nil {
+			fmt.Fprintf(x.Log, "exporter: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// WriteMetrics writes the metrics of the last refresh.
+func (x *Exporter) WriteMetrics(w io.Writer) error {
+	mw := newWriter(w)
+	now := time.Now()
+
+	x.mu.Lock()
+	up := 0.0
+	if x.up {
+		up = 1
+	}
+	mw.family(Up, "gauge", "Whether the last device listing succeeded.")
+	mw.sample(Up, up)
+	mw.family(RefreshTimestamp, "gauge", "Unix time of the last successful device listing.")
+	if !x.refreshed.IsZero() {
+		mw.sample(RefreshTimestamp, float64(x.refreshed.UnixMilli())/1000)
+	}
+	mw.family(RefreshErrors, "counter", "Device listings that failed.")
+	mw.sample(RefreshErrors, float64(x.failures))
+
+	mw.family(DeviceConnected, "gauge", "Whether the device is connected.")
+	for _, d := range x.devices {
+		v := 0.0
+		if d.Connected {
+			v = 1
+		}
+		mw.sample(DeviceConnected, v, "address", d.Address, "name", d.DisplayName(), "type", d.Type)
+	}
+	mw.family(DeviceRSSI, "gauge", "Signal strength of the device in dBm.")
+	for _, d := range x.devices {
+		if d.RSSI != nil {
+			mw.sample(DeviceRSSI, float64(*d.RSSI), "address", d.Address, "name", d.DisplayName())
+		}
+	}
+	mw.family(DeviceBattery, "gauge", "Battery level of the device in percent.")
+	for _, d := range x.devices {
+		if d.Battery == nil {
+			continue
+		}
+		for _, p := range []struct {
+			part  string
+			level *int
+		}{{"main", d.Battery.Main}, {"left", d.Battery.Left}, {"right", d.Battery.Right}, {"case", d.Battery.Case}} {
+			if p.level != nil {
+				mw.sample(DeviceBattery, float64(*p.level), "address", d.Address, "name", d.DisplayName(), "part", p.part)
+			}
+		}
+	}
+	mw.family(DeviceLastSeen, "gauge", "Seconds since the device was last seen connected (0 while connected).")
+	for _, d := range x.devices {
+		if d.Connected {
+			mw.sample(DeviceLastSeen, 0, "address", d.Address, "name", d.DisplayName())
+			continue
+		}
+		last, ok := x.seen[d.Address]
+		if d.LastConnectedAt != nil && (!ok || d.LastConnectedAt.After(last)) {
+			last, ok = *d.LastConnectedAt, true
+		}
+		if ok {
+			mw.sample(DeviceLastSeen, now.Sub(last).Seconds(), "address", d.Address, "name", d.DisplayName())
+		}
+	}
+	x.mu.Unlock()
+
+	if x.Calls != nil {
+		x.Calls.write(mw)
+	}
+	return mw.flush()
+}
+
+// ServeHTTP serves the metrics.
+func (x *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = x.WriteMetrics(w)
+}
+
+// Serve refreshes devices and serves the metrics on ln, at /metrics, until
+// ctx is done.
+func (x *Exporter) Serve(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // before waiting for Run
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		x.Run(ctx)
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", x)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "bt-manage exporter: metrics are at /metrics")
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer scancel()
+		_ = srv.Shutdown(sctx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package metrics exports Bluetooth state and backend call statistics in
+// the Prometheus text format (see `bt-manage exporter`). The metric names
+// and labels are part of the interface and only ever get added to:
+//
+//	bt_manage_up                                   1 if the last device listing succeeded
+//	bt_manage_refresh_timestamp_seconds            when devices were last listed successfully
+//	bt_manage_refresh_errors_total                 failed device listings
+//	bt_manage_device_connected{address,name,type}  1 if connected, else 0
+//	bt_manage_device_rssi_dbm{address,name}        signal strength, when reported
+//	bt_manage_device_battery_percent{address,name,part}
+//	                                               charge per part (main, left, right, case), when known
+//	bt_manage_device_last_seen_seconds{address,name}
+//	                                               time since the device was last seen connected (0 while
+//	                                               connected), when known
+//	bt_manage_backend_calls_total{backend,method}         backend calls
+//	bt_manage_backend_call_errors_total{backend,method}   backend calls that failed
+//	bt_manage_backend_call_duration_seconds{backend,method}
+//	                                               histogram of backend call latency
+//
+// name is the alias when one is set (see `bt-manage alias`), otherwise the
+// device name; address identifies the device.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metric names.
+const (
+	Up                 = "bt_manage_up"
+	RefreshTimestamp   = "bt_manage_refresh_timestamp_seconds"
+	RefreshErrors      = "bt_manage_refresh_errors_total"
+	DeviceConnected    = "bt_manage_device_connected"
+	DeviceRSSI         = "bt_manage_device_rssi_dbm"
+	DeviceBattery      = "bt_manage_device_battery_percent"
+	DeviceLastSeen     = "bt_manage_device_last_seen_seconds"
+	BackendCalls       = "bt_manage_backend_calls_total"
+	BackendCallErrors  = "bt_manage_backend_call_errors_total"
+	BackendCallSeconds = "bt_manage_backend_call_duration_seconds"
+)
+
+// ContentType is the content type of the text format written here.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// writer writes metric families in the Prometheus text format.
+type writer struct {
+	w *bufio.Writer
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w)}
+}
+
+// family starts a metric family.
+func (w *writer) family(name, typ, help string) {
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one sample; labels are name/value pairs.
+func (w *writer) sample(name string, value float64, labels ...string) {
+	w.w.WriteString(name)
+	if len(labels) > 0 {
+		w.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.w.WriteByte(',')
+			}
+			fmt.Fprintf(w.w, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		w.w.WriteByte('}')
+	}
+	w.w.WriteByte(' ')
+	w.w.WriteString(formatValue(value))
+	w.w.WriteByte('\n')
+}
+
+func (w *writer) flush() error {
+	return w.w.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// fakeBluetooth lists devices, or fails with err; other calls are no-ops.
+type fakeBluetooth struct {
+	mu      sync.Mutex
+	devices []core.Device
+	err     error
+}
+
+func (f *fakeBluetooth) set(devices []core.Device, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices, f.err = devices, err
+}
+
+func (f *fakeBluetooth) List(ctx context.Context) ([]core.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]core.Device(nil), f.devices...), f.err
+}
+
+func (f *fakeBluetooth) Connect(ctx context.Context, address string) error    { return nil }
+func (f *fakeBluetooth) Disconnect(ctx context.Context, address string) error { return nil }
+func (f *fakeBluetooth) Pair(ctx context.Context, address, pin string) error  { return nil }
+func (f *fakeBluetooth) Unpair(ctx context.Context, address string) error     { return nil }
+func (f *fakeBluetooth) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	return nil, nil
+}
+func (f *fakeBluetooth) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	return nil
+}
+func (f *fakeBluetooth) IsConnected(ctx context.Context, address string) (bool, error) {
+	return false, nil
+}
+func (f *fakeBluetooth) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	return nil, nil
+}
+
+func intp(v int) *int { return &v }
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+func TestExporter_Scrape(t *testing.T) {
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	bt := &fakeBluetooth{devices: []core.Device{
+		{Name: "Jabra Evolve", Alias: "Room 4 \"Oak\"", Address: "aa:bb:cc:00:00:01", Type: "Headset", Connected: true,
+			RSSI: intp(-71), Battery: &core.Battery{Main: intp(80)}},
+		{Name: "AirPods Pro", Address: "aa:bb:cc:00:00:02", Type: "Headphones", LastConnectedAt: &lastWeek,
+			Battery: &core.Battery{Left: intp(40), Right: intp(35)}},
+	}}
+	calls := &Calls{Backend: "blueutil"}
+	x := &Exporter{Bluetooth: calls.Wrap(bt), Calls: calls, Interval: 10 * time.Millisecond}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- x.Serve(ctx, ln) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}()
+	url := "http://" + ln.Addr().String() + "/metrics"
+
+	var body string
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		body = scrape(t, url)
+		if strings.Contains(body, "bt_manage_up 1\n") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, want := range []string{
+		"# TYPE bt_manage_device_connected gauge\n",
+		`bt_manage_device_connected{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\"",type="Headset"} 1` + "\n",
+		`bt_manage_device_connected{address="aa:bb:cc:00:00:02",name="AirPods Pro",type="Headphones"} 0` + "\n",
+		`bt_manage_device_rssi_dbm{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\""} -71` + "\n",
+		`bt_manage_device_battery_percent{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\"",part="main"} 80` + "\n",
+		`bt_manage_device_battery_percent{address="aa:bb:cc:00:00:02",name="AirPods Pro",part="right"} 35` + "\n",
+		`bt_manage_device_last_seen_seconds{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\""} 0` + "\n",
+		`bt_manage_device_last_seen_seconds{address="aa:bb:cc:00:00:02",name="AirPods Pro"} 6048`,
+		`bt_manage_backend_call_errors_total{backend="blueutil",method="list"} 0` + "\n",
+		`bt_manage_backend_call_duration_seconds_bucket{backend="blueutil",method="list",le="+Inf"} `,
+		"bt_manage_refresh_errors_total 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `bt_manage_device_rssi_dbm{address="aa:bb:cc:00:00:02"`) {
+		t.Errorf("unknown RSSI should have no sample:\n%s", body)
+	}
+
+	// The headset drops off: it is disconnected, and "last seen" starts counting.
+	bt.set([]core.Device{{Name: "Jabra Evolve", Alias: "Room 4 \"Oak\"", Address: "aa:bb:cc:00:00:01", Type: "Headset"}}, nil)
+	if err := x.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	body = scrape(t, url)
+	if !strings.Contains(body, `bt_manage_device_connected{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\"",type="Headset"} 0`) ||
+		strings.Contains(body, `last_seen_seconds{address="aa:bb:cc:00:00:01",name="Room 4 \"Oak\""} 0`+"\n") ||
+		!strings.Contains(body, `last_seen_seconds{address="aa:bb:cc:00:00:01"`) ||
+		strings.Contains(body, "AirPods Pro") {
+		t.Fatalf("after disconnect:\n%s", body)
+	}
+
+	// A failing backend is reported as down and counted as an error.
+	bt.set(nil, errors.New("blueutil: exit status 1"))
+	if err := x.Refresh(ctx); err == nil {
+		t.Fatal("Refresh should fail")
+	}
+	body = scrape(t, url)
+	if !strings.Contains(body, "bt_manage_up 0\n") || strings.Contains(body, "bt_manage_refresh_errors_total 0\n") ||
+		strings.Contains(body, `bt_manage_backend_call_errors_total{backend="blueutil",method="list"} 0`+"\n") {
+		t.Fatalf("after failure:\n%s", body)
+	}
+}
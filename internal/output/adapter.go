@@ -0,0 +1,33 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// WriteAdapterTSV writes the adapter state as one row: Powered, Discoverable.
+func WriteAdapterTSV(w io.Writer, s core.AdapterState, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Powered\tDiscoverable")
+	}
+	fmt.Fprintf(tw, "%s\t%s\n", yesNo(s.Powered), yesNo(s.Discoverable))
+	return tw.Flush()
+}
+
+func WriteAdapterJSON(w io.Writer, s core.AdapterState) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
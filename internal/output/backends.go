@@ -0,0 +1,44 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// BackendInfo is one row of `bt-manage backends`.
+type BackendInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Platforms   []string `json:"platforms"`
+	Usable      bool     `json:"usable"`
+	Selected    bool     `json:"selected"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func WriteBackendsTSV(w io.Writer, backends []BackendInfo, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Name\tSelected\tUsable\tPlatforms\tDescription\tError")
+	}
+	for _, b := range backends {
+		selected := ""
+		if b.Selected {
+			selected = "*"
+		}
+		usable := "no"
+		if b.Usable {
+			usable = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", b.Name, selected, usable, strings.Join(b.Platforms, ","), b.Description, b.Error)
+	}
+	return tw.Flush()
+}
+
+func WriteBackendsJSON(w io.Writer, backends []BackendInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(backends)
+}
@@ -0,0 +1,21 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// WriteBatteryTSV writes one row per device: Name, Address, Type, Connected and Battery.
+func WriteBatteryTSV(w io.Writer, devices []core.Device, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Name\tAddress\tType\tConnected\tBattery\tAlias")
+	}
+	for _, d := range devices {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, d.Address, d.Type, yesNo(d.Connected), batteryString(d.Battery), d.Alias)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Connect actions, as reported in connect's JSON output.
+const (
+	ActionConnect    = "connect"
+	ActionDisconnect = "disconnect"
+)
+
+type connectEntry struct {
+	core.Device
+	Action string `json:"action"`
+}
+
+// WriteConnectJSON writes connected and disconnected devices as one array,
+// like WriteJSON but with an "action" field telling them apart.
+func WriteConnectJSON(w io.Writer, connected, disconnected []core.Device) error {
+	entries := make([]connectEntry, 0, len(connected)+len(disconnected))
+	for _, d := range connected {
+		entries = append(entries, connectEntry{Device: d, Action: ActionConnect})
+	}
+	for _, d := range disconnected {
+		entries = append(entries, connectEntry{Device: d, Action: ActionDisconnect})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// WriteStateTSV writes devices with their connection state.
+func WriteStateTSV(w io.Writer, devices []core.Device, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Name\tAddress\tState")
+	}
+	for _, d := range devices {
+		state := "disconnected"
+		if d.Connected {
+			state = "connected"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.DisplayName(), d.Address, state)
+	}
+	return tw.Flush()
+}
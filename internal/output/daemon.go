@@ -0,0 +1,42 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// DaemonInfo is the output of `bt-manage daemon status`.
+type DaemonInfo struct {
+	Socket    string     `json:"socket"`
+	Backend   string     `json:"backend"`
+	PID       int        `json:"pid"`
+	Started   time.Time  `json:"started"`
+	Devices   int        `json:"devices"`
+	Refreshed *time.Time `json:"refreshed,omitempty"`
+	Hooks     int        `json:"hooks"`
+}
+
+// WriteDaemonTSV writes the daemon as one row: Backend, PID, Uptime, Devices, Refreshed, Hooks, Socket.
+// Uptime and Refreshed are relative to now.
+func WriteDaemonTSV(w io.Writer, d DaemonInfo, now time.Time, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Backend\tPID\tUptime\tDevices\tRefreshed\tHooks\tSocket")
+	}
+	refreshed := "never"
+	if d.Refreshed != nil {
+		refreshed = now.Sub(*d.Refreshed).Round(time.Second).String() + " ago"
+	}
+	fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%d\t%s\n",
+		d.Backend, d.PID, now.Sub(d.Started).Round(time.Second), d.Devices, refreshed, d.Hooks, d.Socket)
+	return tw.Flush()
+}
+
+func WriteDaemonJSON(w io.Writer, d DaemonInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
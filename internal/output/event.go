@@ -0,0 +1,58 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// WriteEventJSON writes ev as one line of JSON (NDJSON when called repeatedly).
+func WriteEventJSON(w io.Writer, ev core.Event) error {
+	return json.NewEncoder(w).Encode(ev)
+}
+
+// WriteEventText writes ev as one human-readable line, e.g.
+// "2026-01-03T09:59:42+09:00  rssi-changed  AirPods Pro (aa:bb:cc:dd:ee:ff)  -70 -> -52 dBm".
+func WriteEventText(w io.Writer, ev core.Event) error {
+	name := ev.Device.DisplayName()
+	if name == "" {
+		name = "(unknown)"
+	}
+	line := fmt.Sprintf("%s  %s  %s (%s)", ev.Time.Format(time.RFC3339), ev.Type, name, ev.Device.Address)
+	if detail := eventDetail(ev); detail != "" {
+		line += "  " + detail
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+func eventDetail(ev core.Event) string {
+	var prev core.Device
+	if ev.Previous != nil {
+		prev = *ev.Previous
+	}
+	switch ev.Type {
+	case core.EventRSSIChanged:
+		return fmt.Sprintf("%s -> %s dBm", intString(prev.RSSI), intString(ev.Device.RSSI))
+	case core.EventBatteryChanged:
+		return fmt.Sprintf("%s -> %s", batteryOrDash(prev.Battery), batteryOrDash(ev.Device.Battery))
+	}
+	return ""
+}
+
+func intString(n *int) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *n)
+}
+
+func batteryOrDash(b *core.Battery) string {
+	if s := batteryString(b); s != "" {
+		return s
+	}
+	return "-"
+}
@@ -0,0 +1,70 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// WriteInfoTSV writes one Field/Value row per known detail of d. Details the
+// backend and enricher did not report are left out.
+func WriteInfoTSV(w io.Writer, d core.Device, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Field\tValue")
+	}
+	row := func(field, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s\t%s\n", field, value)
+		}
+	}
+
+	row("Name", d.Name)
+	row("Alias", d.Alias)
+	row("Address", d.Address)
+	row("Type", d.Type)
+	row("Connected", yesNo(d.Connected))
+	row("Paired", yesNo(d.Paired))
+	if d.Favourite != nil {
+		row("Favourite", yesNo(*d.Favourite))
+	}
+	if d.LastConnectedAt != nil {
+		row("LastConnectedAt", d.LastConnectedAt.Local().Format(time.RFC3339))
+	}
+	if d.RSSI != nil {
+		row("RSSI", fmt.Sprintf("%d dBm", *d.RSSI))
+	}
+	row("Battery", batteryString(d.Battery))
+	if d.Class != 0 {
+		row("Class", classString(d.Class))
+	}
+	row("VendorID", d.VendorID)
+	row("ProductID", d.ProductID)
+	row("Firmware", d.Firmware)
+	row("Services", strings.Join(d.Services, ", "))
+	row("Tags", strings.Join(d.Tags, ", "))
+	if d.Pinned {
+		row("Pinned", "yes")
+	}
+	if d.Hidden {
+		row("Hidden", "yes")
+	}
+
+	return tw.Flush()
+}
+
+// classString renders a Class of Device as "0x240404 (Audio/Video: Headset)".
+func classString(c core.ClassOfDevice) string {
+	desc := c.MajorName()
+	if minor := c.MinorName(); minor != "" {
+		desc += ": " + minor
+	}
+	if desc == "" {
+		return fmt.Sprintf("%#06x", uint32(c))
+	}
+	return fmt.Sprintf("%#06x (%s)", uint32(c), desc)
+}
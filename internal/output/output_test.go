@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fumihumi/bt-manage/internal/core"
 )
@@ -49,6 +50,23 @@ func TestWriteTSV_NoHeader(t *testing.T) {
 	}
 }
 
+func TestWriteTSV_Alias(t *testing.T) {
+	devices := []core.Device{{Name: "MX Keys", Alias: "kb", Address: "AA"}}
+
+	var buf bytes.Buffer
+	if err := WriteTSV(&buf, devices, true); err != nil {
+		t.Fatalf("WriteTSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	header, row := strings.Fields(lines[0]), strings.Fields(lines[1])
+	if header[0] != "Name" || header[len(header)-1] != "Alias" {
+		t.Fatalf("header=%q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "MX Keys ") || row[len(row)-1] != "kb" {
+		t.Fatalf("row=%q", lines[1])
+	}
+}
+
 func TestWriteJSON(t *testing.T) {
 	devices := []core.Device{{Name: "MX", Address: "AA", Type: "Keyboard"}}
 
@@ -68,3 +86,61 @@ func TestWriteJSON(t *testing.T) {
 		t.Fatalf("name=%v", got[0]["name"])
 	}
 }
+
+func TestWriteBatteryTSV(t *testing.T) {
+	level := 12
+	devices := []core.Device{{Name: "Trackpad", Address: "AA", Type: "Mouse", Connected: true, Battery: &core.Battery{Main: &level}}}
+
+	var buf bytes.Buffer
+	if err := WriteBatteryTSV(&buf, devices, true); err != nil {
+		t.Fatalf("WriteBatteryTSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "Battery") {
+		t.Fatalf("out=%q", buf.String())
+	}
+	if !strings.Contains(lines[1], "Trackpad") || !strings.Contains(lines[1], "yes") || !strings.Contains(lines[1], "12%") {
+		t.Fatalf("row=%q", lines[1])
+	}
+}
+
+func TestWriteInfoTSV(t *testing.T) {
+	fav := true
+	devices := core.Device{Name: "Keys", Address: "AA", Paired: true, Favourite: &fav, Class: 0x002540, Services: []string{"HID", "GATT"}}
+
+	var buf bytes.Buffer
+	if err := WriteInfoTSV(&buf, devices, false); err != nil {
+		t.Fatalf("WriteInfoTSV: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Favourite  yes", "Class      0x002540 (Peripheral: Keyboard)", "Services   HID, GATT"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "RSSI") || strings.Contains(out, "Firmware") {
+		t.Fatalf("unknown details should be left out:\n%s", out)
+	}
+}
+
+func TestWriteEventText(t *testing.T) {
+	before, after := -70, -52
+	prev := core.Device{Name: "AirPods Pro", Address: "aa:bb:cc:dd:ee:01", RSSI: &before}
+	cur := prev
+	cur.RSSI = &after
+	ev := core.Event{
+		Type:     core.EventRSSIChanged,
+		Time:     time.Date(2026, 1, 3, 9, 59, 42, 0, time.UTC),
+		Device:   cur,
+		Previous: &prev,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEventText(&buf, ev); err != nil {
+		t.Fatalf("WriteEventText: %v", err)
+	}
+	want := "2026-01-03T09:59:42Z  rssi-changed  AirPods Pro (aa:bb:cc:dd:ee:01)  -70 -> -52 dBm\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,48 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+type switchEntry struct {
+	core.Device
+	Role   string `json:"role"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+func switchEntries(r core.SwitchResult) []switchEntry {
+	entry := func(role string, o core.SwitchOutcome) switchEntry {
+		e := switchEntry{Device: o.Device, Role: role, Result: o.Result}
+		if o.Err != nil {
+			e.Error = o.Err.Error()
+		}
+		return e
+	}
+	return []switchEntry{entry("from", r.From), entry("to", r.To)}
+}
+
+// WriteSwitchTSV writes one row per device of a switch: its role (from/to) and what happened to it.
+func WriteSwitchTSV(w io.Writer, r core.SwitchResult, withHeader bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(tw, "Role\tName\tAddress\tResult\tError")
+	}
+	for _, e := range switchEntries(r) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Role, e.DisplayName(), e.Address, e.Result, e.Error)
+	}
+	return tw.Flush()
+}
+
+// WriteSwitchJSON writes the devices of a switch as an array (from, then to),
+// with "role", "result" and, if any, "error" fields.
+func WriteSwitchJSON(w io.Writer, r core.SwitchResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(switchEntries(r))
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/fumihumi/bt-manage/internal/core"
 )
 
+// WriteTSV writes devices one per row. Name is the device's own name; the
+// alias has a column of its own, last so that existing columns keep their place.
 func WriteTSV(w io.Writer, devices []core.Device, withHeader bool) error {
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	if withHeader {
-		fmt.Fprintln(tw, "Name\tAddress\tType\tRSSI")
+		fmt.Fprintln(tw, "Name\tAddress\tType\tRSSI\tBattery\tAlias")
 	}
 
 	for _, d := range devices {
@@ -19,8 +21,15 @@ func WriteTSV(w io.Writer, devices []core.Device, withHeader bool) error {
 		if d.RSSI != nil {
 			rssi = fmt.Sprintf("%d", *d.RSSI)
 		}
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Name, d.Address, d.Type, rssi)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, d.Address, d.Type, rssi, batteryString(d.Battery), d.Alias)
 	}
 
 	return tw.Flush()
 }
+
+func batteryString(b *core.Battery) string {
+	if b == nil {
+		return ""
+	}
+	return b.String()
+}
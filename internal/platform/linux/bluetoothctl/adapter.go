@@ -0,0 +1,49 @@
+package bluetoothctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func (c Client) controller(ctx context.Context) (controllerInfo, error) {
+	stdout, err := c.run(ctx, "show")
+	if err != nil {
+		if _, perr := parseController(stdout); errors.Is(perr, errNoController) {
+			return controllerInfo{}, fmt.Errorf("bluetoothctl: %w", perr)
+		}
+		return controllerInfo{}, err
+	}
+	info, err := parseController(stdout)
+	if err != nil {
+		return controllerInfo{}, fmt.Errorf("bluetoothctl: %w", err)
+	}
+	return info, nil
+}
+
+// Power implements core.AdapterPort via `bluetoothctl show` / `power on|off`.
+func (c Client) Power(ctx context.Context) (bool, error) {
+	info, err := c.controller(ctx)
+	return info.Powered, err
+}
+
+func (c Client) SetPower(ctx context.Context, on bool) error {
+	return c.command(ctx, "power", onOff(on))
+}
+
+// Discoverable implements core.AdapterPort via `bluetoothctl show` / `discoverable on|off`.
+func (c Client) Discoverable(ctx context.Context) (bool, error) {
+	info, err := c.controller(ctx)
+	return info.Discoverable, err
+}
+
+func (c Client) SetDiscoverable(ctx context.Context, on bool) error {
+	return c.command(ctx, "discoverable", onOff(on))
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
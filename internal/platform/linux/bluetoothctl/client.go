@@ -0,0 +1,245 @@
+package bluetoothctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Client implements core.BluetoothPort on Linux by driving BlueZ's bluetoothctl.
+type Client struct {
+	Exec    ExecPort
+	Bin     string
+	Verbose bool
+	Logger  io.Writer
+
+	// PollInterval is used by WaitConnect, which bluetoothctl has no native command for.
+	PollInterval time.Duration
+}
+
+func (c Client) bin() string {
+	if c.Bin != "" {
+		return c.Bin
+	}
+	return "bluetoothctl"
+}
+
+func (c Client) execPort() ExecPort {
+	if c.Exec != nil {
+		return c.Exec
+	}
+	return OSExec{}
+}
+
+func (c Client) pollInterval() time.Duration {
+	if c.PollInterval > 0 {
+		return c.PollInterval
+	}
+	return 500 * time.Millisecond
+}
+
+func (c Client) logf(format string, args ...any) {
+	if !c.Verbose {
+		return
+	}
+	w := c.Logger
+	if w == nil {
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+func (c Client) run(ctx context.Context, args ...string) ([]byte, error) {
+	if _, err := lookPath(c.bin()); err != nil {
+		return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+	}
+	start := time.Now()
+	c.logf("bluetoothctl: start=%s %s %s\n", start.Format("15:04:05.000"), c.bin(), strings.Join(args, " "))
+	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), args...)
+	c.logf("bluetoothctl: done  start=%s %s elapsed=%s\n", start.Format("15:04:05.000"), args[0], time.Since(start).Truncate(time.Millisecond))
+	if err != nil {
+		// bluetoothctl reports failures on stdout; prefer that over the bare exit status.
+		if resErr := parseCommandResult(stdout); resErr != nil {
+			return stdout, c.mapExecErrWithStderr(err, []byte(resErr.Error()))
+		}
+		return stdout, c.mapExecErrWithStderr(err, stderr)
+	}
+	return stdout, nil
+}
+
+// command runs a one-shot action (connect, pair, ...) and checks its textual result.
+func (c Client) command(ctx context.Context, args ...string) error {
+	stdout, err := c.run(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if err := parseCommandResult(stdout); err != nil {
+		return fmt.Errorf("bluetoothctl: %w", err)
+	}
+	return nil
+}
+
+func (c Client) info(ctx context.Context, address string) (deviceInfo, error) {
+	stdout, err := c.run(ctx, "info", denormalizeAddress(address))
+	if err != nil {
+		// `info` exits non-zero for unknown devices.
+		if info, perr := parseInfo(stdout); errors.Is(perr, errNotAvailable) {
+			return info, fmt.Errorf("bluetoothctl: %w: %s", perr, address)
+		}
+		return deviceInfo{}, err
+	}
+	info, err := parseInfo(stdout)
+	if err != nil {
+		return deviceInfo{}, fmt.Errorf("bluetoothctl: %w", err)
+	}
+	return info, nil
+}
+
+// devices lists every device BlueZ knows about and resolves details via `info`.
+// `devices Paired` / `paired-devices` differ across BlueZ versions, so filtering is done here instead.
+func (c Client) devices(ctx context.Context, keep func(deviceInfo) bool) ([]core.Device, error) {
+	stdout, err := c.run(ctx, "devices")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]core.Device, 0)
+	for _, e := range parseDevices(stdout) {
+		info, err := c.info(ctx, e.Address)
+		if err != nil {
+			if errors.Is(err, errNotAvailable) {
+				// Removed between `devices` and `info`.
+				continue
+			}
+			return nil, err
+		}
+		if info.Name == "" && info.Alias == "" {
+			info.Name = e.Name
+		}
+		if keep(info) {
+			out = append(out, info.toDevice())
+		}
+	}
+	return out, nil
+}
+
+func (c Client) List(ctx context.Context) ([]core.Device, error) {
+	return c.devices(ctx, func(i deviceInfo) bool { return i.Paired })
+}
+
+func (c Client) Connect(ctx context.Context, address string) error {
+	return c.command(ctx, "connect", denormalizeAddress(address))
+}
+
+func (c Client) Disconnect(ctx context.Context, address string) error {
+	return c.command(ctx, "disconnect", denormalizeAddress(address))
+}
+
+func (c Client) Pair(ctx context.Context, address string, pin string) error {
+	if pin != "" {
+		// bluetoothctl only answers PIN requests from an interactive agent session.
+		return fmt.Errorf("bluetoothctl: PIN entry is not supported by this backend")
+	}
+	return c.command(ctx, "pair", denormalizeAddress(address))
+}
+
+func (c Client) Unpair(ctx context.Context, address string) error {
+	return c.command(ctx, "remove", denormalizeAddress(address))
+}
+
+func (c Client) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	if durationSeconds <= 0 {
+		durationSeconds = 10
+	}
+	// `scan on` keeps running until --timeout expires. The devices it saw, and
+	// their RSSI, come from its own output: once discovery stops bluetoothd
+	// drops the RSSI, so `info` can only add the other details.
+	stdout, err := c.run(ctx, "--timeout", strconv.Itoa(durationSeconds), "scan", "on")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := parseScan(stdout)
+	out := make([]core.Device, 0, len(seen))
+	for _, s := range seen {
+		info, err := c.info(ctx, s.Address)
+		if err != nil {
+			if !errors.Is(err, errNotAvailable) {
+				return nil, err
+			}
+			// Already gone from BlueZ's cache; the scan is all there is.
+			info = deviceInfo{Address: s.Address}
+		}
+		if info.Name == "" && info.Alias == "" {
+			info.Name = s.Name
+		}
+		if s.RSSI != nil {
+			info.RSSI = s.RSSI
+		}
+		out = append(out, info.toDevice())
+	}
+	return out, nil
+}
+
+func (c Client) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(c.pollInterval())
+	defer ticker.Stop()
+	for {
+		ok, err := c.IsConnected(ctx, address)
+		if err == nil && ok {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("bluetoothctl: timed out waiting for %s to connect", address)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c Client) IsConnected(ctx context.Context, address string) (bool, error) {
+	info, err := c.info(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	return info.Connected, nil
+}
+
+func (c Client) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	return c.devices(ctx, func(i deviceInfo) bool { return i.Connected })
+}
+
+func (c Client) mapExecErr(err error) error {
+	var ee *exec.Error
+	if errors.As(err, &ee) {
+		if errors.Is(ee.Err, exec.ErrNotFound) {
+			return core.ErrDependencyMissing{Dependency: c.bin()}
+		}
+	}
+	return fmt.Errorf("bluetoothctl: %w", err)
+}
+
+func (c Client) mapExecErrWithStderr(err error, stderr []byte) error {
+	base := c.mapExecErr(err)
+	msg := strings.TrimSpace(string(stderr))
+	if msg == "" {
+		return base
+	}
+	return fmt.Errorf("%w: %s", base, msg)
+}
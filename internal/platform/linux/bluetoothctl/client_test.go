@@ -0,0 +1,239 @@
+package bluetoothctl
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// fakeExec serves canned stdout keyed by the joined argument list.
+type fakeExec struct {
+	t       *testing.T
+	outputs map[string][]byte
+	errs    map[string]error
+	calls   []string
+}
+
+func (f *fakeExec) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	key := strings.Join(args, " ")
+	f.calls = append(f.calls, key)
+	out, ok := f.outputs[key]
+	if !ok {
+		f.t.Fatalf("unexpected call: %s %s", name, key)
+	}
+	return out, nil, f.errs[key]
+}
+
+func stubLookPath(t *testing.T) {
+	t.Helper()
+	prev := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	t.Cleanup(func() { lookPath = prev })
+}
+
+func newFakeExec(t *testing.T) *fakeExec {
+	return &fakeExec{
+		t: t,
+		outputs: map[string][]byte{
+			"devices":                readFixture(t, "devices.txt"),
+			"info AA:BB:CC:DD:EE:FF": readFixture(t, "info_connected.txt"),
+			"info 11:22:33:44:55:66": readFixture(t, "info_disconnected.txt"),
+			"info 22:33:44:55:66:77": readFixture(t, "info_unpaired.txt"),
+		},
+		errs: map[string]error{},
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	c := Client{Exec: fx}
+
+	devices, err := c.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("len=%d, want 2 (paired only); devices=%+v", len(devices), devices)
+	}
+	if devices[0].Address != "aa:bb:cc:dd:ee:ff" || !devices[0].Connected {
+		t.Fatalf("devices[0]=%+v", devices[0])
+	}
+	if devices[1].Name != "Keychron K2" || devices[1].Connected {
+		t.Fatalf("devices[1]=%+v", devices[1])
+	}
+}
+
+func TestClient_ConnectedDevices(t *testing.T) {
+	stubLookPath(t)
+	c := Client{Exec: newFakeExec(t)}
+
+	devices, err := c.ConnectedDevices(context.Background())
+	if err != nil {
+		t.Fatalf("ConnectedDevices: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "MX Master 3" {
+		t.Fatalf("devices=%+v", devices)
+	}
+}
+
+func TestClient_Inquiry(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["--timeout 3 scan on"] = readFixture(t, "scan_on.txt")
+	c := Client{Exec: fx}
+
+	devices, err := c.Inquiry(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	// The devices the scan saw, with the RSSI it reported: `info` no longer
+	// has one for WH-1000XM4 once discovery stopped.
+	if len(devices) != 2 {
+		t.Fatalf("devices=%+v", devices)
+	}
+	if devices[0].Name != "WH-1000XM4" || devices[0].Paired || devices[0].RSSI == nil || *devices[0].RSSI != -71 {
+		t.Fatalf("devices[0]=%+v", devices[0])
+	}
+	if devices[1].Address != "aa:bb:cc:dd:ee:ff" || devices[1].RSSI == nil || *devices[1].RSSI != -52 {
+		t.Fatalf("devices[1]=%+v", devices[1])
+	}
+	if fx.calls[0] != "--timeout 3 scan on" || len(fx.calls) != 3 {
+		t.Fatalf("calls=%v", fx.calls)
+	}
+}
+
+func TestClient_Inquiry_DeviceGoneBeforeInfo(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["--timeout 3 scan on"] = []byte("[NEW] Device 33:44:55:66:77:88 Speaker\n[CHG] Device 33:44:55:66:77:88 RSSI: -80\n")
+	fx.outputs["info 33:44:55:66:77:88"] = readFixture(t, "info_not_available.txt")
+	fx.errs["info 33:44:55:66:77:88"] = errors.New("exit status 1")
+	c := Client{Exec: fx}
+
+	devices, err := c.Inquiry(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "Speaker" || devices[0].RSSI == nil || *devices[0].RSSI != -80 {
+		t.Fatalf("devices=%+v", devices)
+	}
+}
+
+func TestClient_Connect(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["connect AA:BB:CC:DD:EE:FF"] = readFixture(t, "connect_ok.txt")
+	c := Client{Exec: fx}
+
+	if err := c.Connect(context.Background(), "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+}
+
+func TestClient_Connect_FailureOnZeroExit(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["connect AA:BB:CC:DD:EE:FF"] = readFixture(t, "connect_failed.txt")
+	c := Client{Exec: fx}
+
+	err := c.Connect(context.Background(), "aa:bb:cc:dd:ee:ff")
+	if err == nil || !strings.Contains(err.Error(), "br-connection-page-timeout") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestClient_Pair_RejectsPin(t *testing.T) {
+	stubLookPath(t)
+	c := Client{Exec: newFakeExec(t)}
+
+	if err := c.Pair(context.Background(), "aa:bb:cc:dd:ee:ff", "0000"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestClient_WaitConnect(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	c := Client{Exec: fx, PollInterval: time.Millisecond}
+
+	if err := c.WaitConnect(context.Background(), "aa:bb:cc:dd:ee:ff", 1); err != nil {
+		t.Fatalf("WaitConnect: %v", err)
+	}
+
+	start := time.Now()
+	if err := c.WaitConnect(context.Background(), "11:22:33:44:55:66", 1); err == nil {
+		t.Fatalf("expected timeout")
+	}
+	if time.Since(start) < time.Second {
+		t.Fatalf("returned before timeout")
+	}
+}
+
+func TestClient_IsConnected_NotAvailable(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["info 33:44:55:66:77:88"] = readFixture(t, "info_not_available.txt")
+	fx.errs["info 33:44:55:66:77:88"] = errors.New("exit status 1")
+	c := Client{Exec: fx}
+
+	_, err := c.IsConnected(context.Background(), "33:44:55:66:77:88")
+	if !errors.Is(err, errNotAvailable) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestClient_DependencyMissing(t *testing.T) {
+	fx := &fakeExec{t: t, outputs: map[string][]byte{"devices": nil}, errs: map[string]error{
+		"devices": &exec.Error{Name: "bluetoothctl", Err: exec.ErrNotFound},
+	}}
+	stubLookPath(t)
+	c := Client{Exec: fx}
+
+	_, err := c.List(context.Background())
+	var dm core.ErrDependencyMissing
+	if !errors.As(err, &dm) {
+		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+}
+
+func TestClient_DependencyMissing_ByLookPath(t *testing.T) {
+	prev := lookPath
+	lookPath = func(file string) (string, error) {
+		return "", exec.ErrNotFound
+	}
+	t.Cleanup(func() { lookPath = prev })
+
+	c := Client{Exec: &fakeExec{t: t}}
+	_, err := c.List(context.Background())
+	var dm core.ErrDependencyMissing
+	if !errors.As(err, &dm) {
+		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+}
+
+func TestClient_Power(t *testing.T) {
+	stubLookPath(t)
+	fx := newFakeExec(t)
+	fx.outputs["show"] = readFixture(t, "show_off.txt")
+	fx.outputs["power on"] = readFixture(t, "power_on.txt")
+	c := Client{Exec: fx}
+
+	on, err := c.Power(context.Background())
+	if err != nil || on {
+		t.Fatalf("Power = %v, %v", on, err)
+	}
+	if err := c.SetPower(context.Background(), true); err != nil {
+		t.Fatalf("SetPower: %v", err)
+	}
+
+	fx.outputs["power on"] = readFixture(t, "power_blocked.txt")
+	if err := c.SetPower(context.Background(), true); err == nil || !strings.Contains(err.Error(), "Blocked") {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package bluetoothctl
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+type ExecPort interface {
+	Run(ctx context.Context, name string, args ...string) (stdout []byte, stderr []byte, err error)
+}
+
+type OSExec struct{}
+
+func (OSExec) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	err := cmd.Run()
+	return outBuf.Bytes(), errBuf.Bytes(), err
+}
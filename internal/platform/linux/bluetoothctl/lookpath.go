@@ -0,0 +1,5 @@
+package bluetoothctl
+
+import "os/exec"
+
+var lookPath = exec.LookPath
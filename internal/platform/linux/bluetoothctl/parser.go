@@ -0,0 +1,319 @@
+package bluetoothctl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// errNotAvailable is returned when bluetoothctl does not know the device.
+var errNotAvailable = errors.New("device not available")
+
+// ansiEscape matches the color / prompt escape sequences bluetoothctl emits
+// even when it is not attached to a terminal.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]|\x01|\x02`)
+
+type deviceEntry struct {
+	Address string
+	Name    string
+}
+
+type deviceInfo struct {
+	Address   string
+	Name      string
+	Alias     string
+	Class     string
+	Icon      string
+	Paired    bool
+	Connected bool
+	RSSI      *int
+	Battery   *int
+}
+
+func cleanLine(s string) string {
+	return strings.TrimSpace(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// parseDevices parses the output of `bluetoothctl devices`:
+//
+//	Device AA:BB:CC:DD:EE:FF MX Master 3
+func parseDevices(b []byte) []deviceEntry {
+	out := make([]deviceEntry, 0)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := cleanLine(sc.Text())
+		rest, ok := strings.CutPrefix(line, "Device ")
+		if !ok {
+			continue
+		}
+		addr, name, _ := strings.Cut(rest, " ")
+		if !isAddress(addr) {
+			continue
+		}
+		out = append(out, deviceEntry{Address: addr, Name: strings.TrimSpace(name)})
+	}
+	return out
+}
+
+// scanEntry is a device seen by `bluetoothctl scan on`.
+type scanEntry struct {
+	Address string
+	Name    string
+	RSSI    *int // the last one reported
+}
+
+// parseScan parses the output of `bluetoothctl --timeout N scan on`, in the
+// order the devices were first seen. A device counts as seen when it is new
+// or reports an RSSI; devices removed ([DEL]) before the end are left out.
+//
+//	[NEW] Device 22:33:44:55:66:77 WH-1000XM4
+//	[CHG] Device AA:BB:CC:DD:EE:FF RSSI: 0xffffffcc (-52)
+//	[DEL] Device 44:55:66:77:88:99 44-55-66-77-88-99
+func parseScan(b []byte) []scanEntry {
+	var order []string
+	byAddr := map[string]*scanEntry{}
+	see := func(addr string) *scanEntry {
+		e, ok := byAddr[addr]
+		if !ok {
+			e = &scanEntry{Address: addr}
+			byAddr[addr] = e
+			order = append(order, addr)
+		}
+		return e
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := cleanLine(sc.Text())
+		tag, rest, ok := strings.Cut(line, " Device ")
+		if !ok {
+			continue
+		}
+		addr, tail, _ := strings.Cut(rest, " ")
+		if !isAddress(addr) {
+			continue
+		}
+		addr = strings.ToUpper(addr)
+		switch tag {
+		case "[NEW]":
+			see(addr).Name = strings.TrimSpace(tail)
+		case "[CHG]":
+			key, value, ok := strings.Cut(tail, ":")
+			if !ok || strings.TrimSpace(key) != "RSSI" {
+				continue
+			}
+			if n, ok := parseTrailingInt(value); ok {
+				see(addr).RSSI = &n
+			}
+		case "[DEL]":
+			delete(byAddr, addr)
+		}
+	}
+
+	out := make([]scanEntry, 0, len(byAddr))
+	for _, addr := range order {
+		if e, ok := byAddr[addr]; ok {
+			out = append(out, *e)
+			delete(byAddr, addr) // seen again after a [DEL]: listed once
+		}
+	}
+	return out
+}
+
+// parseInfo parses the output of `bluetoothctl info <address>`.
+func parseInfo(b []byte) (deviceInfo, error) {
+	var info deviceInfo
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := cleanLine(sc.Text())
+		if line == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "Device "); ok {
+			addr, tail, _ := strings.Cut(rest, " ")
+			if strings.Contains(tail, "not available") {
+				return deviceInfo{}, errNotAvailable
+			}
+			if isAddress(addr) {
+				info.Address = addr
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			info.Name = value
+		case "Alias":
+			info.Alias = value
+		case "Class":
+			info.Class = value
+		case "Icon":
+			info.Icon = value
+		case "Paired":
+			info.Paired = value == "yes"
+		case "Connected":
+			info.Connected = value == "yes"
+		case "RSSI":
+			// Newer BlueZ prints "0xffffffcc (-52)".
+			if n, ok := parseTrailingInt(value); ok {
+				info.RSSI = &n
+			}
+		case "Battery Percentage":
+			if n, ok := parseTrailingInt(value); ok {
+				info.Battery = &n
+			}
+		}
+	}
+	if info.Address == "" {
+		return deviceInfo{}, fmt.Errorf("unexpected bluetoothctl info output")
+	}
+	return info, nil
+}
+
+// parseTrailingInt accepts "-52", "0x55 (85)" and "0xffffffcc (-52)".
+func parseTrailingInt(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "("); i >= 0 && strings.HasSuffix(s, ")") {
+		s = s[i+1 : len(s)-1]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func (i deviceInfo) toDevice() core.Device {
+	name := i.Alias
+	if name == "" {
+		name = i.Name
+	}
+	// Class is printed as "0x00240404" (BLE devices have none, only an Icon).
+	var class core.ClassOfDevice
+	if n, err := strconv.ParseUint(strings.TrimPrefix(i.Class, "0x"), 16, 32); err == nil {
+		class = core.ClassOfDevice(n)
+	}
+	typ := class.DeviceType()
+	if typ == "" {
+		typ = core.TypeFromIcon(i.Icon)
+	}
+	dev := core.Device{
+		Name:      name,
+		Address:   normalizeAddress(i.Address),
+		Type:      typ,
+		RSSI:      i.RSSI,
+		Connected: i.Connected,
+		Paired:    i.Paired,
+		Class:     class,
+	}
+	if i.Battery != nil {
+		dev.Battery = &core.Battery{Main: i.Battery}
+	}
+	return dev
+}
+
+// parseCommandResult inspects the output of a one-shot command such as
+// `bluetoothctl connect`. Old BlueZ versions exit 0 even on failure, so the
+// "Failed to ..." line is the source of truth.
+func parseCommandResult(b []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := cleanLine(sc.Text())
+		if strings.HasPrefix(line, "Failed to ") {
+			return errors.New(line)
+		}
+		if rest, ok := strings.CutPrefix(line, "Device "); ok && strings.HasSuffix(rest, "not available") {
+			return errNotAvailable
+		}
+	}
+	return nil
+}
+
+func isAddress(s string) bool {
+	if len(s) != 17 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if i%3 == 2 {
+			if c != ':' {
+				return false
+			}
+			continue
+		}
+		isHex := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+		if !isHex {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeAddress(addr string) string {
+	// bt-manage では表示上は小文字の ':' 区切りに寄せる
+	// (bluetoothctl は大文字で返す)
+	return strings.ToLower(strings.ReplaceAll(addr, "-", ":"))
+}
+
+func denormalizeAddress(addr string) string {
+	// bluetoothctl は大文字の ':' 区切りで扱う
+	return strings.ToUpper(strings.ReplaceAll(addr, "-", ":"))
+}
+
+// errNoController is returned when BlueZ has no adapter to operate on.
+var errNoController = errors.New("no default controller available")
+
+type controllerInfo struct {
+	Address      string
+	Powered      bool
+	Discoverable bool
+}
+
+// parseController parses the output of `bluetoothctl show`:
+//
+//	Controller 00:11:22:33:44:55 (public)
+//		Powered: yes
+//		Discoverable: no
+func parseController(b []byte) (controllerInfo, error) {
+	var info controllerInfo
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := cleanLine(sc.Text())
+		if strings.HasPrefix(line, "No default controller") {
+			return controllerInfo{}, errNoController
+		}
+		if rest, ok := strings.CutPrefix(line, "Controller "); ok {
+			addr, _, _ := strings.Cut(rest, " ")
+			if isAddress(addr) {
+				info.Address = addr
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Powered":
+			info.Powered = value == "yes"
+		case "Discoverable":
+			info.Discoverable = value == "yes"
+		}
+	}
+	if info.Address == "" {
+		return controllerInfo{}, fmt.Errorf("unexpected bluetoothctl show output")
+	}
+	return info, nil
+}
@@ -0,0 +1,164 @@
+package bluetoothctl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return b
+}
+
+func TestParseDevices(t *testing.T) {
+	got := parseDevices(readFixture(t, "devices.txt"))
+	if len(got) != 3 {
+		t.Fatalf("len=%d, want 3", len(got))
+	}
+	if got[0].Address != "AA:BB:CC:DD:EE:FF" || got[0].Name != "MX Master 3" {
+		t.Fatalf("got[0]=%+v", got[0])
+	}
+	if got[2].Name != "WH-1000XM4" {
+		t.Fatalf("got[2]=%+v", got[2])
+	}
+}
+
+func TestParseDevices_IgnoresNoiseAndColors(t *testing.T) {
+	in := []byte("Agent registered\n\x1b[0;94m[bluetooth]\x1b[0m# \nDevice AA:BB:CC:DD:EE:FF MX Master 3\n[NEW] Device 11:22:33:44:55:66 Other\nDevice not-an-address Foo\n")
+	got := parseDevices(in)
+	if len(got) != 1 || got[0].Address != "AA:BB:CC:DD:EE:FF" {
+		t.Fatalf("got=%+v", got)
+	}
+}
+
+func TestParseInfo_Connected(t *testing.T) {
+	info, err := parseInfo(readFixture(t, "info_connected.txt"))
+	if err != nil {
+		t.Fatalf("parseInfo: %v", err)
+	}
+	if !info.Connected || !info.Paired {
+		t.Fatalf("connected=%v paired=%v", info.Connected, info.Paired)
+	}
+	if info.RSSI == nil || *info.RSSI != -52 {
+		t.Fatalf("rssi=%v", info.RSSI)
+	}
+	if info.Battery == nil || *info.Battery != 85 {
+		t.Fatalf("battery=%v", info.Battery)
+	}
+	if info.Class != "0x00002580" || info.Icon != "input-mouse" {
+		t.Fatalf("class=%q icon=%q", info.Class, info.Icon)
+	}
+
+	d := info.toDevice()
+	if d.Address != "aa:bb:cc:dd:ee:ff" || d.Name != "MX Master 3" || !d.Connected {
+		t.Fatalf("device=%+v", d)
+	}
+	if d.Type != "Mouse" || d.Class != 0x2580 {
+		t.Fatalf("type=%q class=%#x", d.Type, uint32(d.Class))
+	}
+}
+
+func TestDeviceInfo_TypeFromIcon(t *testing.T) {
+	// BLE devices have no Class; the type comes from BlueZ's Icon.
+	d := deviceInfo{Address: "AA:BB:CC:DD:EE:FF", Icon: "input-keyboard"}.toDevice()
+	if d.Type != "Keyboard" || d.Class != 0 {
+		t.Fatalf("type=%q class=%#x", d.Type, uint32(d.Class))
+	}
+}
+
+func TestParseInfo_Disconnected(t *testing.T) {
+	info, err := parseInfo(readFixture(t, "info_disconnected.txt"))
+	if err != nil {
+		t.Fatalf("parseInfo: %v", err)
+	}
+	if info.Connected || !info.Paired {
+		t.Fatalf("connected=%v paired=%v", info.Connected, info.Paired)
+	}
+	if info.RSSI != nil {
+		t.Fatalf("rssi=%v", info.RSSI)
+	}
+}
+
+func TestParseInfo_Unpaired(t *testing.T) {
+	info, err := parseInfo(readFixture(t, "info_unpaired.txt"))
+	if err != nil {
+		t.Fatalf("parseInfo: %v", err)
+	}
+	if info.Paired || info.Name != "WH-1000XM4" {
+		t.Fatalf("info=%+v", info)
+	}
+	// Read after discovery stopped: bluetoothd no longer reports an RSSI.
+	if info.RSSI != nil {
+		t.Fatalf("rssi=%v", info.RSSI)
+	}
+}
+
+func TestParseScan(t *testing.T) {
+	got := parseScan(readFixture(t, "scan_on.txt"))
+	if len(got) != 2 {
+		t.Fatalf("entries=%+v", got)
+	}
+	if got[0].Address != "22:33:44:55:66:77" || got[0].Name != "WH-1000XM4" || got[0].RSSI == nil || *got[0].RSSI != -71 {
+		t.Fatalf("entries[0]=%+v", got[0])
+	}
+	// Colored output, hex RSSI, and no [NEW] line for a device BlueZ knew.
+	if got[1].Address != "AA:BB:CC:DD:EE:FF" || got[1].RSSI == nil || *got[1].RSSI != -52 {
+		t.Fatalf("entries[1]=%+v", got[1])
+	}
+}
+
+func TestParseInfo_NotAvailable(t *testing.T) {
+	_, err := parseInfo(readFixture(t, "info_not_available.txt"))
+	if !errors.Is(err, errNotAvailable) {
+		t.Fatalf("err=%v, want errNotAvailable", err)
+	}
+}
+
+func TestParseInfo_HexRSSI(t *testing.T) {
+	info, err := parseInfo([]byte("Device AA:BB:CC:DD:EE:FF (random)\n\tRSSI: 0xffffffcc (-52)\n"))
+	if err != nil {
+		t.Fatalf("parseInfo: %v", err)
+	}
+	if info.RSSI == nil || *info.RSSI != -52 {
+		t.Fatalf("rssi=%v", info.RSSI)
+	}
+}
+
+func TestParseCommandResult(t *testing.T) {
+	cases := []struct {
+		fixture string
+		wantErr bool
+	}{
+		{fixture: "connect_ok.txt"},
+		{fixture: "connect_failed.txt", wantErr: true},
+		{fixture: "pair_ok.txt"},
+		{fixture: "pair_failed.txt", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			err := parseCommandResult(readFixture(t, tc.fixture))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err=%v, wantErr=%v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseController(t *testing.T) {
+	got, err := parseController(readFixture(t, "show_off.txt"))
+	if err != nil {
+		t.Fatalf("parseController: %v", err)
+	}
+	if got.Address != "00:11:22:33:44:55" || got.Powered || got.Discoverable {
+		t.Fatalf("got=%+v", got)
+	}
+	if _, err := parseController([]byte("No default controller available\n")); !errors.Is(err, errNoController) {
+		t.Fatalf("err=%v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package bluez
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func (c *Client) adapterProp(ctx context.Context, name string) (bool, error) {
+	objs, adapter, err := c.objects(ctx)
+	if err != nil {
+		return false, err
+	}
+	v, _ := variantValue[bool](objs[adapter][ifaceAdapter], name)
+	return v, nil
+}
+
+func (c *Client) setAdapterProp(ctx context.Context, name string, v bool) error {
+	_, adapter, err := c.objects(ctx)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, adapter, ifaceProperties+".Set", ifaceAdapter, name, dbus.MakeVariant(v)).Err
+}
+
+// Power implements core.AdapterPort via the adapter's Powered property.
+func (c *Client) Power(ctx context.Context) (bool, error) {
+	return c.adapterProp(ctx, "Powered")
+}
+
+func (c *Client) SetPower(ctx context.Context, on bool) error {
+	return c.setAdapterProp(ctx, "Powered", on)
+}
+
+// Discoverable implements core.AdapterPort via the adapter's Discoverable property.
+func (c *Client) Discoverable(ctx context.Context) (bool, error) {
+	return c.adapterProp(ctx, "Discoverable")
+}
+
+func (c *Client) SetDiscoverable(ctx context.Context, on bool) error {
+	return c.setAdapterProp(ctx, "Discoverable", on)
+}
@@ -0,0 +1,71 @@
+package bluez
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const agentPath = dbus.ObjectPath("/org/bt_manage/agent")
+
+// agent implements org.bluez.Agent1 for the duration of a Pair call so that
+// PIN / passkey requests from bluetoothd are answered with the user's --pin.
+type agent struct {
+	pin  string
+	logf func(format string, args ...any)
+}
+
+func rejected(reason string) *dbus.Error {
+	return dbus.NewError("org.bluez.Error.Rejected", []interface{}{reason})
+}
+
+func (a agent) Release() *dbus.Error { return nil }
+
+func (a agent) RequestPinCode(device dbus.ObjectPath) (string, *dbus.Error) {
+	a.logf("bluez: agent: RequestPinCode %s\n", device)
+	if a.pin == "" {
+		return "", rejected("no PIN given (use --pin)")
+	}
+	return a.pin, nil
+}
+
+func (a agent) DisplayPinCode(device dbus.ObjectPath, pincode string) *dbus.Error {
+	a.logf("bluez: agent: DisplayPinCode %s %s\n", device, pincode)
+	return nil
+}
+
+func (a agent) RequestPasskey(device dbus.ObjectPath) (uint32, *dbus.Error) {
+	a.logf("bluez: agent: RequestPasskey %s\n", device)
+	n, err := strconv.ParseUint(a.pin, 10, 32)
+	if err != nil || n > 999999 {
+		return 0, rejected(fmt.Sprintf("passkey must be 0-999999, got %q", a.pin))
+	}
+	return uint32(n), nil
+}
+
+func (a agent) DisplayPasskey(device dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	a.logf("bluez: agent: DisplayPasskey %s %06d (entered %d)\n", device, passkey, entered)
+	return nil
+}
+
+func (a agent) RequestConfirmation(device dbus.ObjectPath, passkey uint32) *dbus.Error {
+	// The user started the pairing from bt-manage; accept numeric comparison.
+	a.logf("bluez: agent: RequestConfirmation %s %06d\n", device, passkey)
+	return nil
+}
+
+func (a agent) RequestAuthorization(device dbus.ObjectPath) *dbus.Error {
+	a.logf("bluez: agent: RequestAuthorization %s\n", device)
+	return nil
+}
+
+func (a agent) AuthorizeService(device dbus.ObjectPath, uuid string) *dbus.Error {
+	a.logf("bluez: agent: AuthorizeService %s %s\n", device, uuid)
+	return nil
+}
+
+func (a agent) Cancel() *dbus.Error {
+	a.logf("bluez: agent: Cancel\n")
+	return nil
+}
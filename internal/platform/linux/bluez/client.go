@@ -0,0 +1,309 @@
+package bluez
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/godbus/dbus/v5"
+)
+
+// Client implements core.BluetoothPort by talking to bluetoothd (org.bluez)
+// over the system D-Bus. Unlike the bluetoothctl backend it answers PIN
+// requests through an Agent1 and waits for connections via PropertiesChanged.
+type Client struct {
+	// BusAddress overrides the system bus (e.g. a private dbus-daemon in tests).
+	BusAddress string
+	// Adapter selects the adapter by name (e.g. "hci0"); empty uses the first one.
+	Adapter string
+	Verbose bool
+	Logger  io.Writer
+
+	mu   sync.Mutex
+	conn *dbus.Conn
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if !c.Verbose {
+		return
+	}
+	w := c.Logger
+	if w == nil {
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+func (c *Client) bus() (*dbus.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil && c.conn.Connected() {
+		return c.conn, nil
+	}
+	var (
+		conn *dbus.Conn
+		err  error
+	)
+	if c.BusAddress != "" {
+		conn, err = dbus.Connect(c.BusAddress)
+	} else {
+		conn, err = dbus.ConnectSystemBus()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bluez: connect to D-Bus: %w", err)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// Close releases the D-Bus connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Check reports whether bluetoothd is reachable on the bus.
+func (c *Client) Check(ctx context.Context) error {
+	conn, err := c.bus()
+	if err != nil {
+		return err
+	}
+	var hasOwner bool
+	if err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, bluezService).Store(&hasOwner); err != nil {
+		return mapErr(err)
+	}
+	if !hasOwner {
+		return core.ErrDependencyMissing{Dependency: "bluetoothd"}
+	}
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	conn, err := c.bus()
+	if err != nil {
+		return &dbus.Call{Err: err}
+	}
+	start := time.Now()
+	c.logf("bluez: start=%s %s %s\n", start.Format("15:04:05.000"), path, method)
+	call := conn.Object(bluezService, path).CallWithContext(ctx, method, 0, args...)
+	c.logf("bluez: done  start=%s %s elapsed=%s\n", start.Format("15:04:05.000"), method, time.Since(start).Truncate(time.Millisecond))
+	if call.Err != nil {
+		call.Err = mapErr(call.Err)
+	}
+	return call
+}
+
+func (c *Client) objects(ctx context.Context) (managedObjects, dbus.ObjectPath, error) {
+	var objs managedObjects
+	if err := c.call(ctx, "/", ifaceObjectManager+".GetManagedObjects").Store(&objs); err != nil {
+		return nil, "", err
+	}
+	adapter, ok := objs.adapterPath(c.Adapter)
+	if !ok {
+		if c.Adapter != "" {
+			return nil, "", fmt.Errorf("bluez: adapter %s not found", c.Adapter)
+		}
+		return nil, "", fmt.Errorf("bluez: no Bluetooth adapter found")
+	}
+	return objs, adapter, nil
+}
+
+func (c *Client) device(ctx context.Context, address string) (deviceObject, error) {
+	objs, adapter, err := c.objects(ctx)
+	if err != nil {
+		return deviceObject{}, err
+	}
+	d, ok := objs.findDevice(adapter, address)
+	if !ok {
+		return deviceObject{}, core.ErrNotFound{Query: address}
+	}
+	return d, nil
+}
+
+func (c *Client) devices(ctx context.Context, keep func(deviceObject) bool) ([]core.Device, error) {
+	objs, adapter, err := c.objects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]core.Device, 0)
+	for _, d := range objs.devices(adapter) {
+		if keep(d) {
+			out = append(out, d.toDevice())
+		}
+	}
+	return out, nil
+}
+
+func (c *Client) List(ctx context.Context) ([]core.Device, error) {
+	return c.devices(ctx, func(d deviceObject) bool { return d.Paired })
+}
+
+func (c *Client) Connect(ctx context.Context, address string) error {
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, d.Path, ifaceDevice+".Connect").Err
+}
+
+func (c *Client) Disconnect(ctx context.Context, address string) error {
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, d.Path, ifaceDevice+".Disconnect").Err
+}
+
+func (c *Client) Pair(ctx context.Context, address string, pin string) error {
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return err
+	}
+	conn, err := c.bus()
+	if err != nil {
+		return err
+	}
+
+	// bluetoothd routes the authentication requests of a Pair() call to the
+	// agent registered by the same D-Bus peer.
+	if err := conn.Export(agent{pin: pin, logf: c.logf}, agentPath, ifaceAgent); err != nil {
+		return fmt.Errorf("bluez: export agent: %w", err)
+	}
+	defer conn.Export(nil, agentPath, ifaceAgent)
+	if err := c.call(ctx, "/org/bluez", ifaceAgentManager+".RegisterAgent", agentPath, "KeyboardDisplay").Err; err != nil {
+		return err
+	}
+	defer c.call(context.Background(), "/org/bluez", ifaceAgentManager+".UnregisterAgent", agentPath)
+
+	if err := c.call(ctx, d.Path, ifaceDevice+".Pair").Err; err != nil {
+		return err
+	}
+	// Trust the device so that it may reconnect on its own later.
+	if err := c.call(ctx, d.Path, ifaceProperties+".Set", ifaceDevice, "Trusted", dbus.MakeVariant(true)).Err; err != nil {
+		c.logf("bluez: set Trusted failed: %v\n", err)
+	}
+	return nil
+}
+
+func (c *Client) Unpair(ctx context.Context, address string) error {
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, d.Adapter, ifaceAdapter+".RemoveDevice", d.Path).Err
+}
+
+func (c *Client) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	if durationSeconds <= 0 {
+		durationSeconds = 10
+	}
+	_, adapter, err := c.objects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.call(ctx, adapter, ifaceAdapter+".StartDiscovery").Err; err != nil {
+		return nil, err
+	}
+	defer c.call(context.Background(), adapter, ifaceAdapter+".StopDiscovery")
+
+	timer := time.NewTimer(time.Duration(durationSeconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	// Snapshot before StopDiscovery: bluetoothd invalidates RSSI once discovery ends,
+	// and RSSI is what tells devices seen by this scan apart from cached ones.
+	return c.devices(ctx, func(d deviceObject) bool { return d.RSSI != nil })
+}
+
+func (c *Client) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return err
+	}
+	conn, err := c.bus()
+	if err != nil {
+		return err
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(d.Path),
+		dbus.WithMatchInterface(ifaceProperties),
+		dbus.WithMatchMember("PropertiesChanged"),
+	}
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return mapErr(err)
+	}
+	defer conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	// Re-check after subscribing so a connection completed in between is not missed.
+	if ok, err := c.IsConnected(ctx, address); err == nil && ok {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("bluez: timed out waiting for %s to connect", address)
+		case sig := <-signals:
+			if sig == nil || sig.Path != d.Path || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, _ := sig.Body[0].(string); iface != ifaceDevice {
+				continue
+			}
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			if connected, ok := variantValue[bool](changed, "Connected"); ok && connected {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *Client) IsConnected(ctx context.Context, address string) (bool, error) {
+	d, err := c.device(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	return d.Connected, nil
+}
+
+func (c *Client) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	return c.devices(ctx, func(d deviceObject) bool { return d.Connected })
+}
+
+// mapErr turns D-Bus errors into bt-manage errors. Remote errors arrive as
+// dbus.Error values named after the BlueZ error (e.g. org.bluez.Error.Failed).
+func mapErr(err error) error {
+	var de dbus.Error
+	if errors.As(err, &de) {
+		if de.Name == "org.freedesktop.DBus.Error.ServiceUnknown" {
+			return core.ErrDependencyMissing{Dependency: "bluetoothd"}
+		}
+		return fmt.Errorf("bluez: %s: %w", de.Name, err)
+	}
+	return fmt.Errorf("bluez: %w", err)
+}
@@ -0,0 +1,227 @@
+package bluez
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func int16p(v int16) *int16 { return &v }
+func bytep(v byte) *byte    { return &v }
+
+func newTestClient(t *testing.T, devices ...mockDevice) (*Client, *mockBluez) {
+	t.Helper()
+	addr := startBus(t)
+	m := startMockBluez(t, addr, devices...)
+	c := &Client{BusAddress: addr}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, m
+}
+
+func TestClient_List(t *testing.T) {
+	c, _ := newTestClient(t,
+		mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX Master 3", Paired: true, Connected: true, RSSI: int16p(-52), Battery: bytep(85)},
+		mockDevice{Address: "11:22:33:44:55:66", Name: "Keychron K2", Paired: true},
+		mockDevice{Address: "22:33:44:55:66:77", Name: "Stranger", Paired: false},
+	)
+
+	devices, err := c.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("devices=%+v, want 2 paired", devices)
+	}
+	byAddr := map[string]core.Device{}
+	for _, d := range devices {
+		byAddr[d.Address] = d
+	}
+	mx, ok := byAddr["aa:bb:cc:dd:ee:ff"]
+	if !ok || !mx.Connected || mx.Name != "MX Master 3" {
+		t.Fatalf("mx=%+v", mx)
+	}
+	if mx.RSSI == nil || *mx.RSSI != -52 {
+		t.Fatalf("rssi=%v", mx.RSSI)
+	}
+}
+
+func TestClient_ConnectDisconnect(t *testing.T) {
+	c, m := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX", Paired: true})
+	ctx := context.Background()
+
+	if err := c.Connect(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	ok, err := c.IsConnected(ctx, "aa:bb:cc:dd:ee:ff")
+	if err != nil || !ok {
+		t.Fatalf("IsConnected=%v err=%v", ok, err)
+	}
+	cds, err := c.ConnectedDevices(ctx)
+	if err != nil || len(cds) != 1 {
+		t.Fatalf("ConnectedDevices=%+v err=%v", cds, err)
+	}
+
+	if err := c.Disconnect(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Disconnect: %v", err)
+	}
+	if v := m.deviceProp(devicePath("AA:BB:CC:DD:EE:FF"), "Connected"); v != false {
+		t.Fatalf("Connected=%v", v)
+	}
+}
+
+func TestClient_Connect_UnknownDevice(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	err := c.Connect(context.Background(), "aa:bb:cc:dd:ee:ff")
+	var nf core.ErrNotFound
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected ErrNotFound, got %T: %v", err, err)
+	}
+}
+
+func TestClient_Pair_AnswersPinViaAgent(t *testing.T) {
+	c, m := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "Keyboard", RSSI: int16p(-60)})
+	m.configure(func(m *mockBluez) { m.wantPin = "1234" })
+	ctx := context.Background()
+
+	if err := c.Pair(ctx, "aa:bb:cc:dd:ee:ff", "0000"); err == nil {
+		t.Fatalf("expected pairing with a wrong PIN to fail")
+	}
+	if err := c.Pair(ctx, "aa:bb:cc:dd:ee:ff", "1234"); err != nil {
+		t.Fatalf("Pair: %v", err)
+	}
+	path := devicePath("AA:BB:CC:DD:EE:FF")
+	if v := m.deviceProp(path, "Paired"); v != true {
+		t.Fatalf("Paired=%v", v)
+	}
+	if v := m.deviceProp(path, "Trusted"); v != true {
+		t.Fatalf("Trusted=%v", v)
+	}
+	var owner string
+	m.configure(func(m *mockBluez) { owner = m.agent.owner })
+	if owner != "" {
+		t.Fatalf("agent should be unregistered after Pair")
+	}
+}
+
+func TestClient_Unpair(t *testing.T) {
+	c, _ := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX", Paired: true})
+	ctx := context.Background()
+
+	if err := c.Unpair(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Unpair: %v", err)
+	}
+	devices, err := c.List(ctx)
+	if err != nil || len(devices) != 0 {
+		t.Fatalf("devices=%+v err=%v", devices, err)
+	}
+}
+
+func TestClient_Inquiry(t *testing.T) {
+	c, m := newTestClient(t,
+		mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "Nearby", RSSI: int16p(-40)},
+		mockDevice{Address: "11:22:33:44:55:66", Name: "Cached"},
+	)
+
+	devices, err := c.Inquiry(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "Nearby" {
+		t.Fatalf("devices=%+v", devices)
+	}
+	var discovering bool
+	m.configure(func(m *mockBluez) { discovering = m.discovering })
+	if discovering {
+		t.Fatalf("discovery should be stopped")
+	}
+}
+
+func TestClient_WaitConnect_UsesPropertiesChanged(t *testing.T) {
+	c, m := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX", Paired: true})
+	m.configure(func(m *mockBluez) { m.connectDelay = 200 * time.Millisecond })
+	ctx := context.Background()
+
+	if err := c.Connect(ctx, "aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := c.WaitConnect(ctx, "aa:bb:cc:dd:ee:ff", 5); err != nil {
+		t.Fatalf("WaitConnect: %v", err)
+	}
+}
+
+func TestClient_WaitConnect_Timeout(t *testing.T) {
+	c, _ := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX", Paired: true})
+
+	if err := c.WaitConnect(context.Background(), "aa:bb:cc:dd:ee:ff", 1); err == nil {
+		t.Fatalf("expected timeout")
+	}
+}
+
+func TestClient_ServiceMissing(t *testing.T) {
+	addr := startBus(t)
+	c := &Client{BusAddress: addr}
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err := c.List(context.Background())
+	var dm core.ErrDependencyMissing
+	if !errors.As(err, &dm) {
+		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+	if err := c.Check(context.Background()); !errors.As(err, &dm) {
+		t.Fatalf("Check: expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+}
+
+func TestClient_Check(t *testing.T) {
+	c, _ := newTestClient(t)
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+}
+
+func TestClient_PowerAndDiscoverable(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if on, err := c.Power(ctx); err != nil || !on {
+		t.Fatalf("Power=%v err=%v", on, err)
+	}
+	if err := c.SetPower(ctx, false); err != nil {
+		t.Fatalf("SetPower: %v", err)
+	}
+	if on, err := c.Power(ctx); err != nil || on {
+		t.Fatalf("Power after off=%v err=%v", on, err)
+	}
+	if err := c.SetDiscoverable(ctx, true); err != nil {
+		t.Fatalf("SetDiscoverable: %v", err)
+	}
+	if on, err := c.Discoverable(ctx); err != nil || !on {
+		t.Fatalf("Discoverable=%v err=%v", on, err)
+	}
+}
+
+func TestClient_Changes(t *testing.T) {
+	c, m := newTestClient(t, mockDevice{Address: "AA:BB:CC:DD:EE:FF", Name: "MX", Paired: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := c.Changes(ctx)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	m.setDeviceProp(devicePath("AA:BB:CC:DD:EE:FF"), "Connected", true)
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change signalled")
+	}
+
+	cancel()
+	for range changes {
+	}
+}
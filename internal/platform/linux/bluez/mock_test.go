@@ -0,0 +1,292 @@
+package bluez
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startBus runs a private dbus-daemon for the test and returns its address.
+func startBus(t *testing.T) string {
+	t.Helper()
+	bin, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(conf, []byte(fmt.Sprintf(busConfig, filepath.Join(dir, "bus"))), 0o600); err != nil {
+		t.Fatalf("write bus config: %v", err)
+	}
+
+	cmd := exec.Command(bin, "--config-file="+conf, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	return strings.TrimSpace(line)
+}
+
+// mockBluez is a minimal org.bluez service: one adapter (hci0), the devices
+// given at construction, AgentManager1 and the Device1 / Adapter1 methods used
+// by Client.
+type mockBluez struct {
+	t    *testing.T
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	objects managedObjects
+	agent   struct {
+		owner string
+		path  dbus.ObjectPath
+	}
+	discovering bool
+
+	// wantPin makes Pair request a PIN from the registered agent.
+	wantPin string
+	// connectDelay defers the Connected=true signal after Connect returns.
+	connectDelay time.Duration
+}
+
+// configure mutates the mock under its lock; handlers run on D-Bus goroutines.
+func (m *mockBluez) configure(f func(m *mockBluez)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(m)
+}
+
+const mockAdapter = dbus.ObjectPath("/org/bluez/hci0")
+
+type mockDevice struct {
+	Address   string
+	Name      string
+	Paired    bool
+	Connected bool
+	RSSI      *int16
+	Battery   *byte
+}
+
+func devicePath(address string) dbus.ObjectPath {
+	return mockAdapter + dbus.ObjectPath("/dev_"+strings.ReplaceAll(address, ":", "_"))
+}
+
+func startMockBluez(t *testing.T, address string, devices ...mockDevice) *mockBluez {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connect mock: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	m := &mockBluez{t: t, conn: conn, objects: managedObjects{
+		mockAdapter: {ifaceAdapter: {
+			"Address":      dbus.MakeVariant("00:11:22:33:44:55"),
+			"Powered":      dbus.MakeVariant(true),
+			"Discoverable": dbus.MakeVariant(false),
+		}},
+	}}
+	for _, d := range devices {
+		m.addDevice(d)
+	}
+
+	must := func(err error) {
+		if err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	}
+	must(conn.ExportMethodTable(map[string]interface{}{
+		"GetManagedObjects": m.getManagedObjects,
+	}, "/", ifaceObjectManager))
+	must(conn.ExportMethodTable(map[string]interface{}{
+		"RegisterAgent":   m.registerAgent,
+		"UnregisterAgent": m.unregisterAgent,
+	}, "/org/bluez", ifaceAgentManager))
+	must(conn.ExportMethodTable(map[string]interface{}{
+		"StartDiscovery": func() *dbus.Error { m.setDiscovering(true); return nil },
+		"StopDiscovery":  func() *dbus.Error { m.setDiscovering(false); return nil },
+		"RemoveDevice":   m.removeDevice,
+	}, mockAdapter, ifaceAdapter))
+	must(conn.ExportMethodTable(map[string]interface{}{
+		"Set": func(iface, name string, v dbus.Variant) *dbus.Error {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.objects[mockAdapter][ifaceAdapter][name] = v
+			return nil
+		},
+	}, mockAdapter, ifaceProperties))
+
+	reply, err := conn.RequestName(bluezService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request name: reply=%v err=%v", reply, err)
+	}
+	return m
+}
+
+func (m *mockBluez) addDevice(d mockDevice) {
+	props := map[string]dbus.Variant{
+		"Address":   dbus.MakeVariant(d.Address),
+		"Name":      dbus.MakeVariant(d.Name),
+		"Alias":     dbus.MakeVariant(d.Name),
+		"Adapter":   dbus.MakeVariant(mockAdapter),
+		"Paired":    dbus.MakeVariant(d.Paired),
+		"Connected": dbus.MakeVariant(d.Connected),
+		"Trusted":   dbus.MakeVariant(false),
+	}
+	if d.RSSI != nil {
+		props["RSSI"] = dbus.MakeVariant(*d.RSSI)
+	}
+	ifaces := map[string]map[string]dbus.Variant{ifaceDevice: props}
+	if d.Battery != nil {
+		ifaces[ifaceBattery] = map[string]dbus.Variant{"Percentage": dbus.MakeVariant(*d.Battery)}
+	}
+
+	path := devicePath(d.Address)
+	m.objects[path] = ifaces
+	if err := m.conn.ExportMethodTable(map[string]interface{}{
+		"Connect":    func() *dbus.Error { return m.connect(path) },
+		"Disconnect": func() *dbus.Error { m.setDeviceProp(path, "Connected", false); return nil },
+		"Pair":       func() *dbus.Error { return m.pair(path) },
+	}, path, ifaceDevice); err != nil {
+		m.t.Fatalf("export device: %v", err)
+	}
+	if err := m.conn.ExportMethodTable(map[string]interface{}{
+		"Set": func(iface, name string, v dbus.Variant) *dbus.Error {
+			m.setDeviceProp(path, name, v.Value())
+			return nil
+		},
+	}, path, ifaceProperties); err != nil {
+		m.t.Fatalf("export properties: %v", err)
+	}
+}
+
+func (m *mockBluez) getManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Copy: the reply is encoded after the lock is released.
+	out := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(m.objects))
+	for path, ifaces := range m.objects {
+		out[path] = map[string]map[string]dbus.Variant{}
+		for iface, props := range ifaces {
+			out[path][iface] = map[string]dbus.Variant{}
+			for k, v := range props {
+				out[path][iface][k] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *mockBluez) registerAgent(sender dbus.Sender, path dbus.ObjectPath, capability string) *dbus.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent.owner = string(sender)
+	m.agent.path = path
+	return nil
+}
+
+func (m *mockBluez) unregisterAgent(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent.owner = ""
+	m.agent.path = ""
+	return nil
+}
+
+func (m *mockBluez) setDiscovering(v bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discovering = v
+}
+
+func (m *mockBluez) removeDevice(path dbus.ObjectPath) *dbus.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[path]; !ok {
+		return dbus.NewError("org.bluez.Error.DoesNotExist", []interface{}{"Does Not Exist"})
+	}
+	delete(m.objects, path)
+	return nil
+}
+
+func (m *mockBluez) setDeviceProp(path dbus.ObjectPath, name string, v interface{}) {
+	m.mu.Lock()
+	m.objects[path][ifaceDevice][name] = dbus.MakeVariant(v)
+	m.mu.Unlock()
+	_ = m.conn.Emit(path, ifaceProperties+".PropertiesChanged", ifaceDevice, map[string]dbus.Variant{name: dbus.MakeVariant(v)}, []string{})
+}
+
+func (m *mockBluez) deviceProp(path dbus.ObjectPath, name string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.objects[path][ifaceDevice][name].Value()
+}
+
+func (m *mockBluez) connect(path dbus.ObjectPath) *dbus.Error {
+	m.mu.Lock()
+	delay := m.connectDelay
+	m.mu.Unlock()
+
+	if delay <= 0 {
+		m.setDeviceProp(path, "Connected", true)
+		return nil
+	}
+	go func() {
+		time.Sleep(delay)
+		m.setDeviceProp(path, "Connected", true)
+	}()
+	return nil
+}
+
+func (m *mockBluez) pair(path dbus.ObjectPath) *dbus.Error {
+	m.mu.Lock()
+	owner, agentPath, wantPin := m.agent.owner, m.agent.path, m.wantPin
+	m.mu.Unlock()
+
+	if wantPin != "" {
+		if owner == "" {
+			return dbus.NewError("org.bluez.Error.AuthenticationFailed", []interface{}{"no agent"})
+		}
+		var pin string
+		err := m.conn.Object(owner, agentPath).Call(ifaceAgent+".RequestPinCode", 0, path).Store(&pin)
+		if err != nil || pin != wantPin {
+			return dbus.NewError("org.bluez.Error.AuthenticationFailed", []interface{}{"Authentication Failed"})
+		}
+	}
+	m.setDeviceProp(path, "Paired", true)
+	return nil
+}
@@ -0,0 +1,96 @@
+package bluez
+
+import (
+	"context"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Changes implements core.NotifierPort: it signals whenever BlueZ reports a
+// property change on a device or a device object appearing or going away.
+func (c *Client) Changes(ctx context.Context) (<-chan struct{}, error) {
+	conn, err := c.bus()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchPathNamespace("/org/bluez"),
+			dbus.WithMatchInterface(ifaceProperties),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+		{
+			dbus.WithMatchInterface(ifaceObjectManager),
+			dbus.WithMatchMember("InterfacesAdded"),
+		},
+		{
+			dbus.WithMatchInterface(ifaceObjectManager),
+			dbus.WithMatchMember("InterfacesRemoved"),
+		},
+	}
+	for i, m := range matches {
+		if err := conn.AddMatchSignalContext(ctx, m...); err != nil {
+			for _, added := range matches[:i] {
+				_ = conn.RemoveMatchSignal(added...)
+			}
+			return nil, mapErr(err)
+		}
+	}
+	signals := make(chan *dbus.Signal, 64)
+	conn.Signal(signals)
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		defer conn.RemoveSignal(signals)
+		defer func() {
+			for _, m := range matches {
+				_ = conn.RemoveMatchSignal(m...)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if !relevantSignal(sig) {
+					continue
+				}
+				// Coalesce: one pending notification is enough.
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// relevantSignal keeps device and battery changes; adapter property changes
+// (e.g. Discovering) don't change the device list.
+func relevantSignal(sig *dbus.Signal) bool {
+	if sig == nil {
+		return false
+	}
+	switch sig.Name {
+	case ifaceProperties + ".PropertiesChanged":
+		if len(sig.Body) == 0 {
+			return false
+		}
+		iface, _ := sig.Body[0].(string)
+		return iface == ifaceDevice || iface == ifaceBattery
+	case ifaceObjectManager + ".InterfacesAdded", ifaceObjectManager + ".InterfacesRemoved":
+		if len(sig.Body) == 0 {
+			return false
+		}
+		path, _ := sig.Body[0].(dbus.ObjectPath)
+		return strings.Contains(string(path), "/dev_")
+	}
+	return false
+}
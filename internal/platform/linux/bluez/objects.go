@@ -0,0 +1,145 @@
+package bluez
+
+import (
+	"strings"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	bluezService = "org.bluez"
+
+	ifaceObjectManager = "org.freedesktop.DBus.ObjectManager"
+	ifaceProperties    = "org.freedesktop.DBus.Properties"
+	ifaceAdapter       = "org.bluez.Adapter1"
+	ifaceDevice        = "org.bluez.Device1"
+	ifaceBattery       = "org.bluez.Battery1"
+	ifaceAgentManager  = "org.bluez.AgentManager1"
+	ifaceAgent         = "org.bluez.Agent1"
+)
+
+type managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+type deviceObject struct {
+	Path      dbus.ObjectPath
+	Adapter   dbus.ObjectPath
+	Address   string
+	Name      string
+	Alias     string
+	Class     uint32
+	Icon      string
+	Paired    bool
+	Connected bool
+	RSSI      *int
+	Battery   *int
+}
+
+// adapterPath returns the adapter to operate on: the named one (e.g. "hci0")
+// when given, otherwise the first adapter exposed by BlueZ.
+func (objs managedObjects) adapterPath(name string) (dbus.ObjectPath, bool) {
+	var first dbus.ObjectPath
+	for path, ifaces := range objs {
+		if _, ok := ifaces[ifaceAdapter]; !ok {
+			continue
+		}
+		if name != "" && strings.HasSuffix(string(path), "/"+name) {
+			return path, true
+		}
+		if first == "" || path < first {
+			first = path
+		}
+	}
+	if name != "" || first == "" {
+		return "", false
+	}
+	return first, true
+}
+
+func (objs managedObjects) devices(adapter dbus.ObjectPath) []deviceObject {
+	out := make([]deviceObject, 0)
+	for path, ifaces := range objs {
+		props, ok := ifaces[ifaceDevice]
+		if !ok {
+			continue
+		}
+		d := deviceObject{Path: path}
+		d.Adapter, _ = variantValue[dbus.ObjectPath](props, "Adapter")
+		if adapter != "" && d.Adapter != adapter {
+			continue
+		}
+		d.Address, _ = variantValue[string](props, "Address")
+		d.Name, _ = variantValue[string](props, "Name")
+		d.Alias, _ = variantValue[string](props, "Alias")
+		d.Class, _ = variantValue[uint32](props, "Class")
+		d.Icon, _ = variantValue[string](props, "Icon")
+		d.Paired, _ = variantValue[bool](props, "Paired")
+		d.Connected, _ = variantValue[bool](props, "Connected")
+		if v, ok := variantValue[int16](props, "RSSI"); ok {
+			n := int(v)
+			d.RSSI = &n
+		}
+		if battery, ok := ifaces[ifaceBattery]; ok {
+			if v, ok := variantValue[byte](battery, "Percentage"); ok {
+				n := int(v)
+				d.Battery = &n
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func (objs managedObjects) findDevice(adapter dbus.ObjectPath, address string) (deviceObject, bool) {
+	for _, d := range objs.devices(adapter) {
+		if strings.EqualFold(d.Address, denormalizeAddress(address)) {
+			return d, true
+		}
+	}
+	return deviceObject{}, false
+}
+
+func variantValue[T any](props map[string]dbus.Variant, key string) (T, bool) {
+	var zero T
+	v, ok := props[key]
+	if !ok {
+		return zero, false
+	}
+	t, ok := v.Value().(T)
+	return t, ok
+}
+
+func (d deviceObject) toDevice() core.Device {
+	name := d.Alias
+	if name == "" {
+		name = d.Name
+	}
+	class := core.ClassOfDevice(d.Class)
+	typ := class.DeviceType()
+	if typ == "" {
+		typ = core.TypeFromIcon(d.Icon)
+	}
+	dev := core.Device{
+		Name:      name,
+		Address:   normalizeAddress(d.Address),
+		Type:      typ,
+		RSSI:      d.RSSI,
+		Connected: d.Connected,
+		Paired:    d.Paired,
+		Class:     class,
+	}
+	if d.Battery != nil {
+		dev.Battery = &core.Battery{Main: d.Battery}
+	}
+	return dev
+}
+
+func normalizeAddress(addr string) string {
+	// bt-manage では表示上は小文字の ':' 区切りに寄せる
+	return strings.ToLower(strings.ReplaceAll(addr, "-", ":"))
+}
+
+func denormalizeAddress(addr string) string {
+	// BlueZ の Address プロパティは大文字の ':' 区切り
+	return strings.ToUpper(strings.ReplaceAll(addr, "-", ":"))
+}
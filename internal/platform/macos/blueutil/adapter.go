@@ -0,0 +1,56 @@
+package blueutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Power implements core.AdapterPort via `blueutil --power`.
+func (c Client) Power(ctx context.Context) (bool, error) {
+	return c.adapterState(ctx, "--power")
+}
+
+func (c Client) SetPower(ctx context.Context, on bool) error {
+	return c.setAdapterState(ctx, "--power", on)
+}
+
+// Discoverable implements core.AdapterPort via `blueutil --discoverable`.
+func (c Client) Discoverable(ctx context.Context) (bool, error) {
+	return c.adapterState(ctx, "--discoverable")
+}
+
+func (c Client) SetDiscoverable(ctx context.Context, on bool) error {
+	return c.setAdapterState(ctx, "--discoverable", on)
+}
+
+// adapterState reads a state option, which blueutil prints as "1" or "0".
+func (c Client) adapterState(ctx context.Context, option string) (bool, error) {
+	if err := c.checkBin(); err != nil {
+		return false, err
+	}
+	stdout, err := c.run(ctx, option)
+	if err != nil {
+		return false, err
+	}
+	switch v := strings.TrimSpace(string(stdout)); v {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("blueutil: unexpected %s output: %q", option, v)
+	}
+}
+
+func (c Client) setAdapterState(ctx context.Context, option string, on bool) error {
+	if err := c.checkBin(); err != nil {
+		return err
+	}
+	state := "0"
+	if on {
+		state = "1"
+	}
+	_, err := c.run(ctx, option, state)
+	return err
+}
@@ -0,0 +1,212 @@
+package blueutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CassetteVersion is the format version written by Recorder.
+const CassetteVersion = 1
+
+// Cassette is a recorded blueutil session: every Run invocation in order.
+// It is plain JSON so it can be attached to a bug report and edited by hand.
+type Cassette struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single blueutil invocation and its outcome.
+type Interaction struct {
+	Args       []string `json:"args"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr,omitempty"`
+	ExitStatus int      `json:"exitStatus"`
+	// Error is set when the process could not run or did not exit normally
+	// (e.g. "not found", "context deadline exceeded").
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"durationMs"`
+}
+
+const errNotFoundText = "not found"
+
+const redacted = "<redacted>"
+
+// redactArgs hides the PIN of `--pair <address> <pin>` so cassettes can be
+// shared. Replay redacts the same way, so the recording still matches.
+func redactArgs(args []string) []string {
+	out := append([]string{}, args...)
+	if len(out) > 2 && out[0] == "--pair" {
+		out[2] = redacted
+	}
+	return out
+}
+
+// LoadCassette reads a cassette written by Recorder.
+func LoadCassette(path string) (Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Cassette{}, fmt.Errorf("read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cassette{}, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	if c.Version != CassetteVersion {
+		return Cassette{}, fmt.Errorf("cassette %s: unsupported version %d (want %d)", path, c.Version, CassetteVersion)
+	}
+	return c, nil
+}
+
+// Recorder is an ExecPort decorator that appends every Run to a cassette file.
+// The file is rewritten after each call so that a session cut short by
+// Ctrl-C still leaves a usable cassette.
+type Recorder struct {
+	Exec ExecPort
+	Path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func (r *Recorder) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	inner := r.Exec
+	if inner == nil {
+		inner = OSExec{}
+	}
+	start := time.Now()
+	stdout, stderr, err := inner.Run(ctx, name, args...)
+
+	in := Interaction{
+		Args:       redactArgs(args),
+		Stdout:     string(stdout),
+		Stderr:     string(stderr),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	var ee *exec.ExitError
+	var execErr *exec.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &ee) && ee.Exited():
+		in.ExitStatus = ee.ExitCode()
+	case errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound):
+		in.Error = errNotFoundText
+	default:
+		in.Error = err.Error()
+	}
+
+	if werr := r.append(in); werr != nil {
+		// Recording is best-effort; never fail the real command because of it.
+		fmt.Fprintf(os.Stderr, "bt-manage: record %s: %v\n", r.Path, werr)
+	}
+	return stdout, stderr, err
+}
+
+func (r *Recorder) append(in Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cassette.Version == 0 {
+		r.cassette = Cassette{Version: CassetteVersion, RecordedAt: time.Now().UTC()}
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, append(b, '\n'), 0o600)
+}
+
+// ErrCassetteMiss is returned by Replayer for an invocation the cassette has no recording of.
+type ErrCassetteMiss struct {
+	Args []string
+}
+
+func (e ErrCassetteMiss) Error() string {
+	return fmt.Sprintf("no recorded blueutil call for: %s", strings.Join(e.Args, " "))
+}
+
+// ReplayExitError stands in for *exec.ExitError when a recorded call exited non-zero.
+type ReplayExitError struct {
+	Status int
+}
+
+func (e ReplayExitError) Error() string { return fmt.Sprintf("exit status %d", e.Status) }
+
+// Replayer is an ExecPort that serves recordings from a Cassette instead of
+// running blueutil, so recorded sessions can be replayed on any OS.
+//
+// Invocations are matched by arguments. Recordings with the same arguments are
+// served in order; once they run out the last one keeps being served, which
+// keeps polling loops (e.g. --is-connected) going.
+type Replayer struct {
+	Cassette Cassette
+	// Realtime sleeps for each interaction's recorded duration.
+	Realtime bool
+
+	mu   sync.Mutex
+	used map[string]int
+}
+
+// LookPath implements PathLooker: replay needs no blueutil binary.
+func (r *Replayer) LookPath(file string) (string, error) { return file, nil }
+
+func (r *Replayer) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	in, ok := r.next(args)
+	if !ok {
+		return nil, nil, ErrCassetteMiss{Args: redactArgs(args)}
+	}
+
+	if r.Realtime && in.DurationMS > 0 {
+		t := time.NewTimer(time.Duration(in.DurationMS) * time.Millisecond)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	stdout, stderr := []byte(in.Stdout), []byte(in.Stderr)
+	switch {
+	case in.Error == errNotFoundText:
+		return stdout, stderr, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	case in.Error != "":
+		return stdout, stderr, errors.New(in.Error)
+	case in.ExitStatus != 0:
+		return stdout, stderr, ReplayExitError{Status: in.ExitStatus}
+	}
+	return stdout, stderr, nil
+}
+
+func (r *Replayer) next(args []string) (Interaction, bool) {
+	key := strings.Join(redactArgs(args), "\x00")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.used == nil {
+		r.used = map[string]int{}
+	}
+
+	var matches []Interaction
+	for _, in := range r.Cassette.Interactions {
+		if strings.Join(in.Args, "\x00") == key {
+			matches = append(matches, in)
+		}
+	}
+	if len(matches) == 0 {
+		return Interaction{}, false
+	}
+	i := r.used[key]
+	if i >= len(matches) {
+		return matches[len(matches)-1], true
+	}
+	r.used[key] = i + 1
+	return matches[i], true
+}
@@ -0,0 +1,115 @@
+package blueutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func loadReplayer(t *testing.T, path string) *Replayer {
+	t.Helper()
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette: %v", err)
+	}
+	return &Replayer{Cassette: c}
+}
+
+func TestReplayer_Session(t *testing.T) {
+	// Replay must not depend on blueutil being installed.
+	prev := lookPath
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	t.Cleanup(func() { lookPath = prev })
+
+	ctx := context.Background()
+	c := Client{Exec: loadReplayer(t, filepath.Join("testdata", "session.json"))}
+
+	devices, err := c.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(devices) != 2 || devices[1].Name != "Magic Trackpad" || devices[1].Address != "ac:bf:71:00:00:02" {
+		t.Fatalf("devices=%+v", devices)
+	}
+
+	if err := c.Connect(ctx, "ac:bf:71:00:00:02"); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	// Recordings with the same args are served in order, then the last one repeats.
+	for i, want := range []bool{false, true, true} {
+		got, err := c.IsConnected(ctx, "ac:bf:71:00:00:02")
+		if err != nil || got != want {
+			t.Fatalf("IsConnected #%d = %v, %v; want %v", i, got, err, want)
+		}
+	}
+
+	err = c.Pair(ctx, "ac:bf:71:00:00:03", "1234")
+	if err == nil || !strings.Contains(err.Error(), "exit status 1") || !strings.Contains(err.Error(), "0xe00002bc") {
+		t.Fatalf("Pair err=%v", err)
+	}
+
+	var miss ErrCassetteMiss
+	if err := c.Disconnect(ctx, "ac:bf:71:00:00:02"); !errors.As(err, &miss) {
+		t.Fatalf("expected ErrCassetteMiss, got %T: %v", err, err)
+	}
+}
+
+func TestRecorder_RoundTrip(t *testing.T) {
+	stubLookPath(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := &Recorder{Path: path, Exec: execFunc(func(args []string) ([]byte, []byte, error) {
+		switch args[0] {
+		case "--paired":
+			return []byte(`[{"address":"aa-bb-cc-dd-ee-ff","name":"X","connected":false,"paired":true}]`), nil, nil
+		case "--pair":
+			return nil, []byte("Failed to pair"), &exec.Error{Name: "blueutil", Err: exec.ErrNotFound}
+		default:
+			return nil, []byte("boom"), errors.New("signal: killed")
+		}
+	})}
+	c := Client{Exec: rec}
+	ctx := context.Background()
+
+	if _, err := c.List(ctx); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	_ = c.Pair(ctx, "aa:bb:cc:dd:ee:ff", "0000")
+	_ = c.Connect(ctx, "aa:bb:cc:dd:ee:ff")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(b), "0000") {
+		t.Fatalf("PIN leaked into cassette:\n%s", b)
+	}
+
+	replay := Client{Exec: loadReplayer(t, path)}
+	devices, err := replay.List(ctx)
+	if err != nil || len(devices) != 1 || devices[0].Name != "X" {
+		t.Fatalf("List = %+v, %v", devices, err)
+	}
+	var dm core.ErrDependencyMissing
+	if err := replay.Pair(ctx, "aa:bb:cc:dd:ee:ff", "0000"); !errors.As(err, &dm) {
+		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+	if err := replay.Connect(ctx, "aa:bb:cc:dd:ee:ff"); err == nil || !strings.Contains(err.Error(), "signal: killed") {
+		t.Fatalf("Connect err=%v", err)
+	}
+}
+
+func TestLoadCassette_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "interactions": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCassette(path); err == nil {
+		t.Fatalf("expected unsupported version error")
+	}
+}
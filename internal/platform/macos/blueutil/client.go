@@ -18,6 +18,9 @@ type Client struct {
 	Bin     string
 	Verbose bool
 	Logger  io.Writer
+
+	// PollInterval is used by WaitConnect when blueutil predates --wait-connect.
+	PollInterval time.Duration
 }
 
 func (c Client) bin() string {
@@ -34,6 +37,26 @@ func (c Client) execPort() ExecPort {
 	return OSExec{}
 }
 
+// checkBin fails with ErrDependencyMissing when blueutil is not installed.
+// An ExecPort that never spawns processes (e.g. Replayer) answers instead.
+func (c Client) checkBin() error {
+	look := lookPath
+	if pl, ok := c.execPort().(PathLooker); ok {
+		look = pl.LookPath
+	}
+	if _, err := look(c.bin()); err != nil {
+		return core.ErrDependencyMissing{Dependency: c.bin()}
+	}
+	return nil
+}
+
+func (c Client) pollInterval() time.Duration {
+	if c.PollInterval > 0 {
+		return c.PollInterval
+	}
+	return 500 * time.Millisecond
+}
+
 func (c Client) logf(format string, args ...any) {
 	if !c.Verbose {
 		return
@@ -46,22 +69,48 @@ func (c Client) logf(format string, args ...any) {
 }
 
 func (c Client) List(ctx context.Context) ([]core.Device, error) {
-	if _, err := lookPath(c.bin()); err != nil {
-		return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return nil, err
+	}
+	return c.deviceList(ctx, "--paired")
+}
+
+// deviceList runs a listing option (--paired, --inquiry N, --connected) with
+// JSON output. Releases without --format json get the default text format instead.
+func (c Client) deviceList(ctx context.Context, args ...string) ([]core.Device, error) {
+	stdout, err := c.run(ctx, append(append([]string{}, args...), "--format", "json")...)
+	if err == nil {
+		return parseDeviceListJSON(stdout)
 	}
+	err = c.explainFailure(ctx, featureFormatJSON, err)
+	var old core.ErrDependencyTooOld
+	if !errors.As(err, &old) {
+		return nil, err
+	}
+
+	c.logf("blueutil: %v; parsing the default output format instead\n", err)
+	stdout, err = c.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseDeviceListText(stdout)
+}
+
+// run executes blueutil with args and returns its stdout.
+func (c Client) run(ctx context.Context, args ...string) ([]byte, error) {
 	start := time.Now()
-	c.logf("blueutil: start=%s %s --paired --format json\n", start.Format("15:04:05.000"), c.bin())
-	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), "--paired", "--format", "json")
-	c.logf("blueutil: done  start=%s elapsed=%s\n", start.Format("15:04:05.000"), time.Since(start).Truncate(time.Millisecond))
+	c.logf("blueutil: start=%s %s %s\n", start.Format("15:04:05.000"), c.bin(), strings.Join(args, " "))
+	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), args...)
+	c.logf("blueutil: done  start=%s %s elapsed=%s\n", start.Format("15:04:05.000"), args[0], time.Since(start).Truncate(time.Millisecond))
 	if err != nil {
 		return nil, c.mapExecErrWithStderr(err, stderr)
 	}
-	return parseDeviceListJSON(stdout)
+	return stdout, nil
 }
 
 func (c Client) Connect(ctx context.Context, address string) error {
-	if _, err := lookPath(c.bin()); err != nil {
-		return core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return err
 	}
 	addr := denormalizeAddress(address)
 	start := time.Now()
@@ -75,8 +124,8 @@ func (c Client) Connect(ctx context.Context, address string) error {
 }
 
 func (c Client) Disconnect(ctx context.Context, address string) error {
-	if _, err := lookPath(c.bin()); err != nil {
-		return core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return err
 	}
 	addr := denormalizeAddress(address)
 	start := time.Now()
@@ -90,8 +139,8 @@ func (c Client) Disconnect(ctx context.Context, address string) error {
 }
 
 func (c Client) Pair(ctx context.Context, address string, pin string) error {
-	if _, err := lookPath(c.bin()); err != nil {
-		return core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return err
 	}
 	addr := denormalizeAddress(address)
 	args := []string{"--pair", addr}
@@ -109,8 +158,8 @@ func (c Client) Pair(ctx context.Context, address string, pin string) error {
 }
 
 func (c Client) Unpair(ctx context.Context, address string) error {
-	if _, err := lookPath(c.bin()); err != nil {
-		return core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return err
 	}
 	addr := denormalizeAddress(address)
 	start := time.Now()
@@ -124,25 +173,18 @@ func (c Client) Unpair(ctx context.Context, address string) error {
 }
 
 func (c Client) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
-	if _, err := lookPath(c.bin()); err != nil {
-		return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return nil, err
 	}
 	if durationSeconds <= 0 {
 		durationSeconds = 10
 	}
-	start := time.Now()
-	c.logf("blueutil: start=%s %s --inquiry %d --format json\n", start.Format("15:04:05.000"), c.bin(), durationSeconds)
-	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), "--inquiry", strconv.Itoa(durationSeconds), "--format", "json")
-	c.logf("blueutil: done  start=%s --inquiry %d elapsed=%s\n", start.Format("15:04:05.000"), durationSeconds, time.Since(start).Truncate(time.Millisecond))
-	if err != nil {
-		return nil, c.mapExecErrWithStderr(err, stderr)
-	}
-	return parseDeviceListJSON(stdout)
+	return c.deviceList(ctx, "--inquiry", strconv.Itoa(durationSeconds))
 }
 
 func (c Client) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
-	if _, err := lookPath(c.bin()); err != nil {
-		return core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return err
 	}
 	addr := denormalizeAddress(address)
 	args := []string{"--wait-connect", addr}
@@ -154,14 +196,46 @@ func (c Client) WaitConnect(ctx context.Context, address string, timeoutSeconds
 	_, stderr, err := c.execPort().Run(ctx, c.bin(), args...)
 	c.logf("blueutil: done  start=%s --wait-connect %s elapsed=%s\n", start.Format("15:04:05.000"), addr, time.Since(start).Truncate(time.Millisecond))
 	if err != nil {
-		return c.mapExecErrWithStderr(err, stderr)
+		err = c.explainFailure(ctx, featureWaitConnect, c.mapExecErrWithStderr(err, stderr))
+		var old core.ErrDependencyTooOld
+		if errors.As(err, &old) {
+			c.logf("blueutil: %v; polling --is-connected instead\n", err)
+			return c.pollConnect(ctx, address, timeoutSeconds)
+		}
+		return err
 	}
 	return nil
 }
 
+// pollConnect emulates --wait-connect for blueutil releases that predate it.
+func (c Client) pollConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(c.pollInterval())
+	defer ticker.Stop()
+	for {
+		ok, err := c.IsConnected(ctx, address)
+		if err == nil && ok {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("blueutil: timed out waiting for %s to connect", address)
+		case <-ticker.C:
+		}
+	}
+}
+
 func (c Client) IsConnected(ctx context.Context, address string) (bool, error) {
-	if _, err := lookPath(c.bin()); err != nil {
-		return false, core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return false, err
 	}
 	addr := denormalizeAddress(address)
 	start := time.Now()
@@ -176,17 +250,35 @@ func (c Client) IsConnected(ctx context.Context, address string) (bool, error) {
 }
 
 func (c Client) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
-	if _, err := lookPath(c.bin()); err != nil {
-		return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+	if err := c.checkBin(); err != nil {
+		return nil, err
 	}
-	start := time.Now()
-	c.logf("blueutil: start=%s %s --connected --format json\n", start.Format("15:04:05.000"), c.bin())
-	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), "--connected", "--format", "json")
-	c.logf("blueutil: done  start=%s --connected elapsed=%s\n", start.Format("15:04:05.000"), time.Since(start).Truncate(time.Millisecond))
+	devices, err := c.deviceList(ctx, "--connected")
 	if err != nil {
-		return nil, c.mapExecErrWithStderr(err, stderr)
+		err = c.explainFailure(ctx, featureConnected, err)
+		var old core.ErrDependencyTooOld
+		if errors.As(err, &old) {
+			// --paired reports the connection state as well.
+			c.logf("blueutil: %v; filtering --paired instead\n", err)
+			return c.connectedFromPaired(ctx)
+		}
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (c Client) connectedFromPaired(ctx context.Context) ([]core.Device, error) {
+	paired, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]core.Device, 0, len(paired))
+	for _, d := range paired {
+		if d.Connected {
+			out = append(out, d)
+		}
 	}
-	return parseDeviceListJSON(stdout)
+	return out, nil
 }
 
 func (c Client) mapExecErr(err error) error {
@@ -3,6 +3,7 @@ package blueutil
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os/exec"
 	"testing"
 
@@ -26,7 +27,23 @@ func (f *fakeExec) Run(ctx context.Context, name string, args ...string) ([]byte
 	return f.stdout, nil, f.err
 }
 
+// stubLookPath pretends blueutil is installed so tests run on any OS.
+func stubLookPath(t *testing.T) {
+	t.Helper()
+	prev := lookPath
+	lookPath = func(file string) (string, error) { return "/opt/homebrew/bin/" + file, nil }
+	t.Cleanup(func() { lookPath = prev })
+}
+
+// execFunc adapts a function to ExecPort.
+type execFunc func(args []string) ([]byte, []byte, error)
+
+func (f execFunc) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	return f(args)
+}
+
 func TestClient_List(t *testing.T) {
+	stubLookPath(t)
 	fx := &fakeExec{stdout: []byte(`[{"address":"aa-bb-cc-dd-ee-ff","name":"X","connected":false,"paired":true}]`)}
 	c := Client{Exec: fx, Bin: "blueutil"}
 
@@ -49,6 +66,7 @@ func TestClient_List(t *testing.T) {
 }
 
 func TestClient_DependencyMissing(t *testing.T) {
+	stubLookPath(t)
 	fx := &fakeExec{err: &exec.Error{Name: "blueutil", Err: exec.ErrNotFound}}
 	c := Client{Exec: fx, Bin: "blueutil"}
 
@@ -73,3 +91,30 @@ func TestClient_DependencyMissing_ByLookPath(t *testing.T) {
 		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
 	}
 }
+
+func TestClient_Power(t *testing.T) {
+	stubLookPath(t)
+	var calls [][]string
+	c := Client{Exec: execFunc(func(args []string) ([]byte, []byte, error) {
+		calls = append(calls, args)
+		if len(args) == 1 {
+			return []byte("0\n"), nil, nil
+		}
+		return nil, nil, nil
+	})}
+
+	on, err := c.Power(context.Background())
+	if err != nil || on {
+		t.Fatalf("Power = %v, %v", on, err)
+	}
+	if err := c.SetPower(context.Background(), true); err != nil {
+		t.Fatalf("SetPower: %v", err)
+	}
+	if err := c.SetDiscoverable(context.Background(), false); err != nil {
+		t.Fatalf("SetDiscoverable: %v", err)
+	}
+	want := [][]string{{"--power"}, {"--power", "1"}, {"--discoverable", "0"}}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Fatalf("calls=%v, want %v", calls, want)
+	}
+}
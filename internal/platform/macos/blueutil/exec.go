@@ -12,6 +12,12 @@ type ExecPort interface {
 	Run(ctx context.Context, name string, args ...string) (stdout []byte, stderr []byte, err error)
 }
 
+// PathLooker is optionally implemented by an ExecPort that resolves binaries
+// itself; Client then uses it instead of exec.LookPath.
+type PathLooker interface {
+	LookPath(file string) (string, error)
+}
+
 type OSExec struct{}
 
 func (OSExec) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
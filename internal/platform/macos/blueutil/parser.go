@@ -1,7 +1,13 @@
 package blueutil
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fumihumi/bt-manage/internal/core"
@@ -15,6 +21,7 @@ type deviceJSON struct {
 	RSSI             *int       `json:"RSSI"`
 	RawRSSI          *int       `json:"rawRSSI"`
 	Paired           bool       `json:"paired"`
+	Favourite        *bool      `json:"favourite"`
 }
 
 func parseDeviceListJSON(b []byte) ([]core.Device, error) {
@@ -32,12 +39,100 @@ func parseDeviceListJSON(b []byte) ([]core.Device, error) {
 			LastConnectedAt: d.RecentAccessDate,
 			RSSI:            firstNonNilInt(d.RSSI, d.RawRSSI),
 			Type:            "",
+			Paired:          d.Paired,
+			Favourite:       d.Favourite,
 		}
 		out = append(out, dev)
 	}
 	return out, nil
 }
 
+// parseDeviceListText parses blueutil's default output format, one device per line:
+//
+//	address: aa-bb-cc-dd-ee-ff, connected (master, -52 dBm), not favourite, paired, name: "MX Master", recent access date: 2026-01-03 00:59:42 +0000
+//
+// It is used for blueutil releases without --format json.
+func parseDeviceListText(b []byte) ([]core.Device, error) {
+	out := make([]core.Device, 0)
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		d, err := parseDeviceLine(line)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var connectedRSSIRe = regexp.MustCompile(`, connected(?: \((?:[a-z]+, )?(-?\d+) dBm\))?(?:,|$)`)
+
+const (
+	textNameKey = `, name: "`
+	textDateKey = `", recent access date: `
+)
+
+func parseDeviceLine(line string) (core.Device, error) {
+	rest, ok := strings.CutPrefix(line, "address: ")
+	if !ok {
+		return core.Device{}, fmt.Errorf("unexpected blueutil output: %q", line)
+	}
+
+	// The name may contain ", " itself, so cut it out first: it sits between
+	// `name: "` and either `", recent access date: ` or the closing quote.
+	var d core.Device
+	head, name, found := strings.Cut(rest, textNameKey)
+	if found {
+		if i := strings.LastIndex(name, textDateKey); i >= 0 {
+			d.LastConnectedAt = parseAccessDate(name[i+len(textDateKey):])
+			name = name[:i]
+		} else {
+			name = strings.TrimSuffix(name, `"`)
+		}
+		d.Name = name
+	}
+
+	addr, _, _ := strings.Cut(head, ",")
+	d.Address = normalizeAddress(strings.TrimSpace(addr))
+	// "connected (master, -52 dBm)" vs "not connected"; RSSI is only reported while connected.
+	if m := connectedRSSIRe.FindStringSubmatch(head); m != nil {
+		d.Connected = true
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			d.RSSI = &n
+		}
+	}
+	for _, flag := range strings.Split(head, ", ") {
+		switch flag {
+		case "paired":
+			d.Paired = true
+		case "favourite", "not favourite":
+			fav := flag == "favourite"
+			d.Favourite = &fav
+		}
+	}
+	if d.Address == "" {
+		return core.Device{}, fmt.Errorf("unexpected blueutil output: %q", line)
+	}
+	return d, nil
+}
+
+// parseAccessDate reads NSDate's description ("2026-01-03 00:59:42 +0000");
+// "(null)" and anything unparseable yield nil.
+func parseAccessDate(s string) *time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05 -0700", strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 func normalizeAddress(addr string) string {
 	// bt-manage では表示上は ':' 区切りに寄せる
 	// (blueutil は '-' で返す)
@@ -1,12 +1,15 @@
 package blueutil
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestParseDeviceListJSON(t *testing.T) {
 	in := []byte(`[
-  {"address":"aa-bb-cc-dd-ee-ff","recentAccessDate":"2026-01-03T00:59:42+09:00","name":"MX Master","connected":true,"paired":true,"RSSI":-12},
+  {"address":"aa-bb-cc-dd-ee-ff","recentAccessDate":"2026-01-03T00:59:42+09:00","name":"MX Master","connected":true,"favourite":true,"paired":true,"RSSI":-12},
   {"address":"11-22-33-44-55-66","recentAccessDate":"2026-01-02T00:00:00Z","name":"Keychron","connected":false,"paired":true}
 ]`)
 
@@ -26,6 +29,9 @@ func TestParseDeviceListJSON(t *testing.T) {
 	if got[1].RSSI != nil {
 		t.Fatalf("rssi=%v", got[1].RSSI)
 	}
+	if !got[0].Paired || got[0].Favourite == nil || !*got[0].Favourite || got[1].Favourite != nil {
+		t.Fatalf("flags: %+v %+v", got[0], got[1])
+	}
 }
 
 func TestParseDeviceListJSON_RawRSSIOnly(t *testing.T) {
@@ -61,3 +67,76 @@ func TestParseDeviceListJSON_RSSIOnly(t *testing.T) {
 		t.Fatalf("rssi=%v", got[0].RSSI)
 	}
 }
+
+func TestParseDeviceListText_Paired(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "paired_default.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseDeviceListText(in)
+	if err != nil {
+		t.Fatalf("parseDeviceListText: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("len=%d, want 3", len(got))
+	}
+
+	mx := got[0]
+	if mx.Address != "aa:bb:cc:dd:ee:ff" || mx.Name != "MX Master" || !mx.Connected {
+		t.Fatalf("mx=%+v", mx)
+	}
+	if mx.RSSI == nil || *mx.RSSI != -52 {
+		t.Fatalf("rssi=%v", mx.RSSI)
+	}
+	if want := time.Date(2026, 1, 3, 0, 59, 42, 0, time.UTC); mx.LastConnectedAt == nil || !mx.LastConnectedAt.Equal(want) {
+		t.Fatalf("lastConnectedAt=%v, want %v", mx.LastConnectedAt, want)
+	}
+
+	if !mx.Paired || mx.Favourite == nil || *mx.Favourite {
+		t.Fatalf("mx flags: paired=%v favourite=%v", mx.Paired, mx.Favourite)
+	}
+
+	kb := got[1]
+	if kb.Name != "Keychron K2, Office" || kb.Connected || kb.RSSI != nil {
+		t.Fatalf("keychron=%+v", kb)
+	}
+	if kb.Favourite == nil || !*kb.Favourite {
+		t.Fatalf("keychron should be a favourite: %v", kb.Favourite)
+	}
+	if want := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC); kb.LastConnectedAt == nil || !kb.LastConnectedAt.Equal(want) {
+		t.Fatalf("lastConnectedAt=%v, want %v", kb.LastConnectedAt, want)
+	}
+
+	tp := got[2]
+	if !tp.Connected || tp.RSSI == nil || *tp.RSSI != 0 || tp.LastConnectedAt != nil {
+		t.Fatalf("trackpad=%+v", tp)
+	}
+}
+
+func TestParseDeviceListText_Inquiry(t *testing.T) {
+	in, err := os.ReadFile(filepath.Join("testdata", "inquiry_default.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseDeviceListText(in)
+	if err != nil {
+		t.Fatalf("parseDeviceListText: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len=%d, want 2", len(got))
+	}
+	if got[0].Name != "WH-1000XM4" || got[0].Connected {
+		t.Fatalf("got[0]=%+v", got[0])
+	}
+	if got[1].Address != "cc:dd:ee:ff:00:11" || got[1].Name != "" || got[1].LastConnectedAt != nil {
+		t.Fatalf("got[1]=%+v", got[1])
+	}
+}
+
+func TestParseDeviceListText_Malformed(t *testing.T) {
+	if _, err := parseDeviceListText([]byte("Unknown option --paired\n")); err == nil {
+		t.Fatalf("expected error")
+	}
+}
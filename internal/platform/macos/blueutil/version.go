@@ -0,0 +1,137 @@
+package blueutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Version is a blueutil release (e.g. 2.9.1).
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is an older release than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+var versionRe = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseVersion reads `blueutil --version` output ("2.9.1").
+func parseVersion(b []byte) (Version, bool) {
+	m := versionRe.FindSubmatch(b)
+	if m == nil {
+		return Version{}, false
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(string(m[1]))
+	v.Minor, _ = strconv.Atoi(string(m[2]))
+	if len(m[3]) > 0 {
+		v.Patch, _ = strconv.Atoi(string(m[3]))
+	}
+	return v, true
+}
+
+// feature is a blueutil option Client relies on, with the first release that has it.
+type feature struct {
+	Flag  string
+	Since Version
+}
+
+// Capability table, from blueutil's changelog.
+var (
+	featureFormatJSON  = feature{Flag: "--format json", Since: Version{2, 2, 0}}
+	featureWaitConnect = feature{Flag: "--wait-connect", Since: Version{2, 5, 0}}
+	featureConnected   = feature{Flag: "--connected", Since: Version{2, 7, 0}}
+)
+
+// processVersions caches `blueutil --version` for the lifetime of the
+// process, per ExecPort and binary: an injected ExecPort (Recorder, Replayer,
+// a fake) is asked once, like the real OSExec, and independently of the
+// others. ExecPorts that aren't pointers can't be told apart reliably (func
+// types, structs holding them) and are asked every time.
+var processVersions = struct {
+	sync.Mutex
+	byPort map[versionKey]versionResult
+}{byPort: map[versionKey]versionResult{}}
+
+type versionKey struct {
+	exec ExecPort
+	bin  string
+}
+
+type versionResult struct {
+	v  Version
+	ok bool
+}
+
+// version returns the installed blueutil version; ok is false when it can't be determined.
+func (c Client) version(ctx context.Context) (Version, bool) {
+	exec := c.execPort()
+	if _, real := exec.(OSExec); !real && reflect.TypeOf(exec).Kind() != reflect.Pointer {
+		return c.probeVersion(ctx)
+	}
+	key := versionKey{exec: exec, bin: c.bin()}
+
+	processVersions.Lock()
+	defer processVersions.Unlock()
+	if r, ok := processVersions.byPort[key]; ok {
+		return r.v, r.ok
+	}
+	v, ok := c.probeVersion(ctx)
+	if ctx.Err() == nil {
+		processVersions.byPort[key] = versionResult{v: v, ok: ok}
+	}
+	return v, ok
+}
+
+func (c Client) probeVersion(ctx context.Context) (Version, bool) {
+	stdout, _, err := c.execPort().Run(ctx, c.bin(), "--version")
+	if err != nil {
+		c.logf("blueutil: --version failed: %v\n", err)
+		return Version{}, false
+	}
+	v, ok := parseVersion(stdout)
+	c.logf("blueutil: version=%s ok=%v\n", v, ok)
+	return v, ok
+}
+
+// supports reports whether the installed blueutil has f. An undeterminable
+// version is assumed to be recent, so the command itself gets to decide.
+func (c Client) supports(ctx context.Context, f feature) (bool, Version, bool) {
+	v, ok := c.version(ctx)
+	if !ok {
+		return true, v, false
+	}
+	return !v.Less(f.Since), v, true
+}
+
+// explainFailure is called after a command using f failed. When blueutil
+// predates f the raw "exit status 1" is replaced by ErrDependencyTooOld.
+func (c Client) explainFailure(ctx context.Context, f feature, err error) error {
+	var dm core.ErrDependencyMissing
+	if ctx.Err() != nil || errors.As(err, &dm) {
+		return err
+	}
+	if ok, v, known := c.supports(ctx, f); known && !ok {
+		return core.ErrDependencyTooOld{Dependency: "blueutil", Feature: f.Flag, Have: v.String(), Need: f.Since.String()}
+	}
+	return err
+}
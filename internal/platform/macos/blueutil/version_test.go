@@ -0,0 +1,180 @@
+package blueutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Version
+		ok   bool
+	}{
+		{"2.9.1\n", Version{2, 9, 1}, true},
+		{"blueutil v2.10.0", Version{2, 10, 0}, true},
+		{"2.4", Version{2, 4, 0}, true},
+		{"unknown option --version", Version{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseVersion([]byte(tc.in))
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("parseVersion(%q) = %v, %v; want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+	if !(Version{2, 9, 1}).Less(Version{2, 10, 0}) || (Version{3, 0, 0}).Less(Version{2, 99, 99}) {
+		t.Fatalf("Less ordering is wrong")
+	}
+}
+
+// oldBlueutil behaves like a release that has none of the options in the
+// capability table: they all exit 1 with a usage message.
+func oldBlueutil(version string, isConnected ...string) (execFunc, *[]string) {
+	var calls []string
+	polls := 0
+	return func(args []string) ([]byte, []byte, error) {
+		calls = append(calls, args[0])
+		switch args[0] {
+		case "--version":
+			return []byte(version + "\n"), nil, nil
+		case "--is-connected":
+			v := isConnected[min(polls, len(isConnected)-1)]
+			polls++
+			return []byte(v + "\n"), nil, nil
+		}
+		return nil, []byte("Unknown option " + args[0]), ReplayExitError{Status: 1}
+	}, &calls
+}
+
+func TestClient_TooOld(t *testing.T) {
+	stubLookPath(t)
+	fx, _ := oldBlueutil("2.1.0")
+	c := Client{Exec: fx}
+
+	err := c.explainFailure(context.Background(), featureFormatJSON, errors.New("blueutil: exit status 1"))
+	var old core.ErrDependencyTooOld
+	if !errors.As(err, &old) {
+		t.Fatalf("expected ErrDependencyTooOld, got %T: %v", err, err)
+	}
+	if old.Need != "2.2.0" || old.Have != "2.1.0" || old.Feature != "--format json" {
+		t.Fatalf("err=%+v", old)
+	}
+	if want := "blueutil too old: need >= 2.2.0 for --format json (have 2.1.0)"; err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestClient_ListFallsBackToTextFormat(t *testing.T) {
+	stubLookPath(t)
+	text, err := os.ReadFile(filepath.Join("testdata", "paired_default.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fx, calls := oldBlueutil("2.1.0")
+	c := Client{Exec: execFunc(func(args []string) ([]byte, []byte, error) {
+		if len(args) == 1 && args[0] == "--paired" {
+			*calls = append(*calls, args[0])
+			return text, nil, nil
+		}
+		return fx(args)
+	})}
+
+	devices, err := c.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(devices) != 3 || devices[0].Name != "MX Master" {
+		t.Fatalf("devices=%+v", devices)
+	}
+	if got, want := strings.Join(*calls, " "), "--paired --version --paired"; got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+
+	connected, err := c.ConnectedDevices(context.Background())
+	if err != nil {
+		t.Fatalf("ConnectedDevices: %v", err)
+	}
+	if len(connected) != 2 {
+		t.Fatalf("connected=%+v", connected)
+	}
+}
+
+func TestClient_FailureOnRecentVersionIsNotTooOld(t *testing.T) {
+	stubLookPath(t)
+	fx := execFunc(func(args []string) ([]byte, []byte, error) {
+		if args[0] == "--version" {
+			return []byte("2.9.1\n"), nil, nil
+		}
+		return nil, []byte("Error: Bluetooth is off"), ReplayExitError{Status: 1}
+	})
+	c := Client{Exec: fx}
+
+	_, err := c.List(context.Background())
+	var old core.ErrDependencyTooOld
+	if err == nil || errors.As(err, &old) || !strings.Contains(err.Error(), "Bluetooth is off") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestClient_WaitConnectFallsBackToPolling(t *testing.T) {
+	stubLookPath(t)
+	fx, calls := oldBlueutil("2.4.0", "0", "0", "1")
+	c := Client{Exec: fx, PollInterval: time.Millisecond}
+
+	if err := c.WaitConnect(context.Background(), "aa:bb:cc:dd:ee:ff", 5); err != nil {
+		t.Fatalf("WaitConnect: %v", err)
+	}
+	got := strings.Join(*calls, " ")
+	if want := "--wait-connect --version --is-connected --is-connected --is-connected"; got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestClient_ConnectedDevicesFallsBackToPaired(t *testing.T) {
+	stubLookPath(t)
+	fx := execFunc(func(args []string) ([]byte, []byte, error) {
+		switch args[0] {
+		case "--version":
+			return []byte("2.6.0\n"), nil, nil
+		case "--paired":
+			return []byte(`[{"address":"aa-bb-cc-dd-ee-01","name":"A","connected":true,"paired":true},{"address":"aa-bb-cc-dd-ee-02","name":"B","connected":false,"paired":true}]`), nil, nil
+		}
+		return nil, nil, ReplayExitError{Status: 1}
+	})
+	c := Client{Exec: fx}
+
+	devices, err := c.ConnectedDevices(context.Background())
+	if err != nil {
+		t.Fatalf("ConnectedDevices: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "A" {
+		t.Fatalf("devices=%+v", devices)
+	}
+}
+
+func TestClient_VersionProbedOncePerExecPort(t *testing.T) {
+	stubLookPath(t)
+	fx := &fakeExec{stdout: []byte("2.1.0\n")}
+	c := Client{Exec: fx}
+	for i := 0; i < 3; i++ {
+		if v, ok := c.version(context.Background()); !ok || v != (Version{2, 1, 0}) {
+			t.Fatalf("version = %v, %v", v, ok)
+		}
+	}
+	if len(fx.calls) != 1 {
+		t.Fatalf("--version run %d times, want once", len(fx.calls))
+	}
+
+	// Another ExecPort is asked on its own.
+	other := &fakeExec{stdout: []byte("2.9.1\n")}
+	if v, _ := (Client{Exec: other}).version(context.Background()); v != (Version{2, 9, 1}) || len(other.calls) != 1 {
+		t.Fatalf("other port: version %v after %d calls", v, len(other.calls))
+	}
+}
@@ -0,0 +1,118 @@
+package sysprofiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Client implements core.EnricherPort on macOS using
+// `system_profiler SPBluetoothDataType -json`, which knows device types,
+// battery levels, vendor/product IDs, firmware versions and services.
+//
+// system_profiler takes a second or more, so results are cached for CacheFor.
+type Client struct {
+	Exec    ExecPort
+	Bin     string
+	Verbose bool
+	Logger  io.Writer
+
+	// Timeout bounds a single system_profiler run (default 10s).
+	Timeout time.Duration
+	// CacheFor is how long a report is reused (default 30s; negative disables caching).
+	CacheFor time.Duration
+
+	mu       sync.Mutex
+	cached   []core.Device
+	cachedAt time.Time
+}
+
+func (c *Client) bin() string {
+	if c.Bin != "" {
+		return c.Bin
+	}
+	return "system_profiler"
+}
+
+func (c *Client) execPort() ExecPort {
+	if c.Exec != nil {
+		return c.Exec
+	}
+	return OSExec{}
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (c *Client) cacheFor() time.Duration {
+	if c.CacheFor != 0 {
+		return c.CacheFor
+	}
+	return 30 * time.Second
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if !c.Verbose {
+		return
+	}
+	w := c.Logger
+	if w == nil {
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+func (c *Client) Details(ctx context.Context) ([]core.Device, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.cachedAt) < c.cacheFor() {
+		return c.cached, nil
+	}
+	devices, err := c.run(ctx)
+	if err != nil {
+		c.logf("system_profiler: %v\n", err)
+		return nil, err
+	}
+	c.cached, c.cachedAt = devices, time.Now()
+	return devices, nil
+}
+
+func (c *Client) run(ctx context.Context) ([]core.Device, error) {
+	if _, err := lookPath(c.bin()); err != nil {
+		return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+
+	start := time.Now()
+	c.logf("system_profiler: start=%s %s SPBluetoothDataType -json\n", start.Format("15:04:05.000"), c.bin())
+	stdout, stderr, err := c.execPort().Run(ctx, c.bin(), "SPBluetoothDataType", "-json")
+	c.logf("system_profiler: done  start=%s elapsed=%s\n", start.Format("15:04:05.000"), time.Since(start).Truncate(time.Millisecond))
+	if err != nil {
+		var ee *exec.Error
+		if errors.As(err, &ee) && errors.Is(ee.Err, exec.ErrNotFound) {
+			return nil, core.ErrDependencyMissing{Dependency: c.bin()}
+		}
+		if msg := strings.TrimSpace(string(stderr)); msg != "" {
+			return nil, fmt.Errorf("system_profiler: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("system_profiler: %w", err)
+	}
+	devices, err := parseReport(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("system_profiler: parse: %w", err)
+	}
+	return devices, nil
+}
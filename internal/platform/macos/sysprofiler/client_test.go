@@ -0,0 +1,81 @@
+package sysprofiler
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+type fakeExec struct {
+	stdout []byte
+	err    error
+	calls  int
+}
+
+func (f *fakeExec) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	f.calls++
+	return f.stdout, nil, f.err
+}
+
+func stubLookPath(t *testing.T) {
+	t.Helper()
+	prev := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/sbin/" + file, nil }
+	t.Cleanup(func() { lookPath = prev })
+}
+
+func TestClient_DetailsCached(t *testing.T) {
+	stubLookPath(t)
+	fx := &fakeExec{stdout: readFixture(t, "sonoma.json")}
+	c := &Client{Exec: fx}
+
+	for i := 0; i < 2; i++ {
+		devices, err := c.Details(context.Background())
+		if err != nil || len(devices) != 3 {
+			t.Fatalf("Details = %d devices, %v", len(devices), err)
+		}
+	}
+	if fx.calls != 1 {
+		t.Fatalf("calls=%d, want 1 (cached)", fx.calls)
+	}
+
+	c.CacheFor = -1
+	_, _ = c.Details(context.Background())
+	if fx.calls != 2 {
+		t.Fatalf("calls=%d, want 2 with caching disabled", fx.calls)
+	}
+}
+
+func TestClient_Enrich(t *testing.T) {
+	stubLookPath(t)
+	c := &Client{Exec: &fakeExec{stdout: readFixture(t, "sonoma.json")}}
+
+	devices := []core.Device{
+		{Name: "MX Master 3", Address: "aa:bb:cc:dd:ee:01", Connected: true},
+		{Name: "Unknown", Address: "aa:bb:cc:dd:ee:99"},
+	}
+	got, err := core.Enrich(context.Background(), c, devices)
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	if got[0].Type != "Mouse" || got[0].Battery == nil || *got[0].Battery.Main != 85 || !got[0].Connected {
+		t.Fatalf("got[0]=%+v", got[0])
+	}
+	if got[1].Type != "" || got[1].Battery != nil {
+		t.Fatalf("got[1]=%+v", got[1])
+	}
+}
+
+func TestClient_DependencyMissing(t *testing.T) {
+	stubLookPath(t)
+	c := &Client{Exec: &fakeExec{err: &exec.Error{Name: "system_profiler", Err: exec.ErrNotFound}}}
+
+	_, err := c.Details(context.Background())
+	var dm core.ErrDependencyMissing
+	if !errors.As(err, &dm) {
+		t.Fatalf("expected ErrDependencyMissing, got %T: %v", err, err)
+	}
+}
@@ -0,0 +1,5 @@
+package sysprofiler
+
+import "os/exec"
+
+var lookPath = exec.LookPath
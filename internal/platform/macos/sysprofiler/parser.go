@@ -0,0 +1,141 @@
+package sysprofiler
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+type report struct {
+	Bluetooth []map[string]json.RawMessage `json:"SPBluetoothDataType"`
+}
+
+// Device lists are arrays of single-key objects: [{"MX Master 3": {...props}}, ...].
+//
+//   - macOS 12+: "device_connected" / "device_not_connected"
+//   - macOS 11 and older: "device_title"
+var deviceListKeys = []string{"device_connected", "device_not_connected", "device_title"}
+
+type deviceProps struct {
+	Address       string `json:"device_address"`
+	LegacyAddress string `json:"device_addr"`
+
+	MinorType       string `json:"device_minorType"`
+	LegacyMinorType string `json:"device_minorClassOfDevice_string"`
+
+	BatteryMain  string `json:"device_batteryLevelMain"`
+	BatteryLeft  string `json:"device_batteryLevelLeft"`
+	BatteryRight string `json:"device_batteryLevelRight"`
+	BatteryCase  string `json:"device_batteryLevelCase"`
+
+	VendorID  string `json:"device_vendorID"`
+	ProductID string `json:"device_productID"`
+	Firmware  string `json:"device_firmwareVersion"`
+	Services  string `json:"device_services"`
+}
+
+// parseReport reads `system_profiler SPBluetoothDataType -json`. Devices
+// without an address are skipped; a device listed twice keeps the first entry.
+func parseReport(b []byte) ([]core.Device, error) {
+	var r report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	out := make([]core.Device, 0)
+	seen := map[string]bool{}
+	for _, section := range r.Bluetooth {
+		for _, key := range deviceListKeys {
+			raw, ok := section[key]
+			if !ok {
+				continue
+			}
+			var entries []map[string]deviceProps
+			if err := json.Unmarshal(raw, &entries); err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				names := make([]string, 0, len(entry))
+				for name := range entry {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					d := entry[name].toDevice(name)
+					if d.Address == "" || seen[d.Address] {
+						continue
+					}
+					seen[d.Address] = true
+					out = append(out, d)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func (p deviceProps) toDevice(name string) core.Device {
+	d := core.Device{
+		Name:      name,
+		Address:   normalizeAddress(firstNonEmpty(p.Address, p.LegacyAddress)),
+		Type:      firstNonEmpty(p.MinorType, p.LegacyMinorType),
+		VendorID:  strings.TrimSpace(p.VendorID),
+		ProductID: strings.TrimSpace(p.ProductID),
+		Firmware:  strings.TrimSpace(p.Firmware),
+		Services:  parseServices(p.Services),
+	}
+	b := core.Battery{
+		Main:  parsePercent(p.BatteryMain),
+		Left:  parsePercent(p.BatteryLeft),
+		Right: parsePercent(p.BatteryRight),
+		Case:  parsePercent(p.BatteryCase),
+	}
+	if b.Main != nil || b.Left != nil || b.Right != nil || b.Case != nil {
+		d.Battery = &b
+	}
+	return d
+}
+
+// parsePercent reads "85%" (or "85").
+func parsePercent(s string) *int {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 100 {
+		return nil
+	}
+	return &n
+}
+
+// parseServices reads "0x400019 < HID A2DP ACL >" (or a plain "HID, ACL" list).
+func parseServices(s string) []string {
+	if i := strings.Index(s, "<"); i >= 0 {
+		s = s[i+1:]
+		if j := strings.Index(s, ">"); j >= 0 {
+			s = s[:j]
+		}
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func normalizeAddress(addr string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(addr), "-", ":"))
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package sysprofiler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseReport(t *testing.T) {
+	got, err := parseReport(readFixture(t, "sonoma.json"))
+	if err != nil {
+		t.Fatalf("parseReport: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("len=%d, want 3 (device without address skipped): %+v", len(got), got)
+	}
+
+	mx := got[0]
+	if mx.Name != "MX Master 3" || mx.Address != "aa:bb:cc:dd:ee:01" || mx.Type != "Mouse" {
+		t.Fatalf("mx=%+v", mx)
+	}
+	if mx.VendorID != "0x046D" || mx.ProductID != "0xB023" || mx.Firmware != "17.2.17" {
+		t.Fatalf("mx ids=%+v", mx)
+	}
+	if !reflect.DeepEqual(mx.Services, []string{"HID", "ACL"}) {
+		t.Fatalf("services=%v", mx.Services)
+	}
+	if mx.Battery == nil || mx.Battery.Main == nil || *mx.Battery.Main != 85 || mx.Battery.Left != nil {
+		t.Fatalf("battery=%+v", mx.Battery)
+	}
+
+	pods := got[1]
+	if pods.Type != "Headphones" || pods.Battery == nil || pods.Battery.Main != nil {
+		t.Fatalf("pods=%+v", pods)
+	}
+	if *pods.Battery.Left != 90 || *pods.Battery.Right != 88 || *pods.Battery.Case != 40 {
+		t.Fatalf("pods battery=%+v", pods.Battery)
+	}
+
+	kb := got[2]
+	if kb.Type != "Keyboard" || kb.Battery != nil || kb.Firmware != "" {
+		t.Fatalf("keyboard=%+v", kb)
+	}
+}
+
+func TestParseReport_Legacy(t *testing.T) {
+	got, err := parseReport(readFixture(t, "bigsur.json"))
+	if err != nil {
+		t.Fatalf("parseReport: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("len=%d", len(got))
+	}
+	if got[0].Address != "aa:bb:cc:dd:ee:04" || got[0].Type != "Trackpad" || got[0].VendorID != "0x004C" {
+		t.Fatalf("got=%+v", got[0])
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	for in, want := range map[string]int{"85%": 85, " 5 %": -1, "100": 100, "101%": -1, "": -1, "n/a": -1} {
+		got := parsePercent(in)
+		if want < 0 {
+			if got != nil {
+				t.Fatalf("parsePercent(%q)=%d, want nil", in, *got)
+			}
+			continue
+		}
+		if got == nil || *got != want {
+			t.Fatalf("parsePercent(%q)=%v, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+)
+
+// Power implements core.AdapterPort. A powering-on adapter reports off until
+// AdapterSpec.PowerOnDelay has passed.
+func (s *Simulator) Power(ctx context.Context) (bool, error) {
+	if err := s.advance(ctx, s.latency("power")); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isPowered(), nil
+}
+
+// SetPower switches the adapter. Powering off drops every connection.
+func (s *Simulator) SetPower(ctx context.Context, on bool) error {
+	if err := s.advance(ctx, s.latency("setPower")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	if s.fails("setPower", nil) {
+		return fmt.Errorf("sim: set power: simulated failure")
+	}
+	if on == s.adapter.powered {
+		return nil
+	}
+	s.adapter.powered = on
+	if on {
+		s.adapter.poweredAt = s.elapsed + s.scenario.Adapter.PowerOnDelay
+		return nil
+	}
+	s.adapter.discoverable = false
+	for _, d := range s.devices {
+		d.connected = false
+	}
+	return nil
+}
+
+func (s *Simulator) Discoverable(ctx context.Context) (bool, error) {
+	if err := s.advance(ctx, s.latency("discoverable")); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isPowered() && s.adapter.discoverable, nil
+}
+
+func (s *Simulator) SetDiscoverable(ctx context.Context, on bool) error {
+	if err := s.advance(ctx, s.latency("setDiscoverable")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	if err := s.errPoweredOff("set discoverable"); err != nil {
+		return err
+	}
+	s.adapter.discoverable = on
+	return nil
+}
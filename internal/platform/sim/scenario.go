@@ -0,0 +1,140 @@
+package sim
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario describes the simulated Bluetooth world. It is loaded from YAML or
+// JSON (JSON is valid YAML); durations are strings such as "800ms" or "5s".
+type Scenario struct {
+	// Seed makes failure probabilities reproducible.
+	Seed int64 `yaml:"seed"`
+	// TimeScale scales real sleeping: 1 = real time, 0 = instant.
+	// Simulated time always advances by the full amounts, so results do not depend on it.
+	TimeScale *float64 `yaml:"timeScale"`
+	// Latency is added to every operation ("default" applies to those not listed).
+	Latency map[string]time.Duration `yaml:"latency"`
+	// Failures are per-operation failure probabilities in [0,1].
+	Failures map[string]float64 `yaml:"failures"`
+	Adapter  AdapterSpec        `yaml:"adapter"`
+	Devices  []DeviceSpec       `yaml:"devices"`
+}
+
+// AdapterSpec is the simulated local adapter.
+type AdapterSpec struct {
+	// Powered is the initial power state (default true).
+	Powered *bool `yaml:"powered"`
+	// Discoverable is the initial discoverability.
+	Discoverable bool `yaml:"discoverable"`
+	// PowerOnDelay is how long after SetPower(true) the adapter reports powered.
+	PowerOnDelay time.Duration `yaml:"powerOnDelay"`
+}
+
+func (a AdapterSpec) powered() bool {
+	return a.Powered == nil || *a.Powered
+}
+
+// DeviceSpec is one simulated device.
+type DeviceSpec struct {
+	Name      string `yaml:"name"`
+	Address   string `yaml:"address"`
+	Type      string `yaml:"type"`
+	RSSI      *int   `yaml:"rssi"`
+	Paired    bool   `yaml:"paired"`
+	Connected bool   `yaml:"connected"`
+	// Battery levels in percent: main, or left/right/case for earbuds.
+	Battery *core.Battery `yaml:"battery"`
+	// Nearby devices show up in Inquiry (default true).
+	Nearby *bool `yaml:"nearby"`
+	// AppearAfter delays the device's first appearance in Inquiry (simulated time since start).
+	AppearAfter time.Duration `yaml:"appearAfter"`
+	// ConnectOnAttempt makes Connect fail until the k-th attempt (counted over the whole session).
+	ConnectOnAttempt int `yaml:"connectOnAttempt"`
+	// ConnectDelay is how long after a successful Connect the device reports connected.
+	ConnectDelay time.Duration `yaml:"connectDelay"`
+	// SilentFailures makes failed Connect attempts return success while the
+	// device stays disconnected, which is what flaky devices do to blueutil.
+	SilentFailures bool `yaml:"silentFailures"`
+	// Pin, when set, must be given to Pair.
+	Pin string `yaml:"pin"`
+	// Failures override the scenario-wide probabilities for this device.
+	Failures map[string]float64 `yaml:"failures"`
+}
+
+func (d DeviceSpec) nearby() bool {
+	return d.Nearby == nil || *d.Nearby
+}
+
+// LoadScenario reads a scenario file.
+func LoadScenario(path string) (Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("sim: %w", err)
+	}
+	return ParseScenario(b)
+}
+
+// ParseScenario parses a YAML or JSON scenario.
+func ParseScenario(b []byte) (Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return Scenario{}, fmt.Errorf("sim: parse scenario: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return Scenario{}, err
+	}
+	return s, nil
+}
+
+func (s Scenario) validate() error {
+	seen := map[string]bool{}
+	for i, d := range s.Devices {
+		if strings.TrimSpace(d.Address) == "" {
+			return fmt.Errorf("sim: device %d (%q) has no address", i, d.Name)
+		}
+		addr := normalizeAddress(d.Address)
+		if seen[addr] {
+			return fmt.Errorf("sim: duplicate device address %s", d.Address)
+		}
+		seen[addr] = true
+		for op, p := range d.Failures {
+			if p < 0 || p > 1 {
+				return fmt.Errorf("sim: device %s: failure probability for %s must be within [0,1]", d.Address, op)
+			}
+		}
+	}
+	for op, p := range s.Failures {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("sim: failure probability for %s must be within [0,1]", op)
+		}
+	}
+	return nil
+}
+
+// DefaultScenario is used when no scenario file is given: a small desk setup
+// with a Magic Trackpad that only connects on the third attempt.
+func DefaultScenario() Scenario {
+	rssi := func(v int) *int { return &v }
+	battery := func(v int) *core.Battery { return &core.Battery{Main: &v} }
+	return Scenario{
+		Seed:    1,
+		Latency: map[string]time.Duration{"default": 100 * time.Millisecond, "connect": 500 * time.Millisecond},
+		Devices: []DeviceSpec{
+			{Name: "MX Keys", Address: "aa:bb:cc:00:00:01", Type: "Keyboard", Paired: true, Connected: true, RSSI: rssi(-48), Battery: battery(85)},
+			{Name: "MX Master 3", Address: "aa:bb:cc:00:00:02", Type: "Mouse", Paired: true, RSSI: rssi(-55)},
+			{Name: "Magic Trackpad", Address: "aa:bb:cc:00:00:03", Type: "Trackpad", Paired: true, RSSI: rssi(-61), Battery: battery(12), ConnectOnAttempt: 3, SilentFailures: true},
+			{Name: "WH-1000XM4", Address: "aa:bb:cc:00:00:04", Type: "Headphones", Paired: true, RSSI: rssi(-70), ConnectDelay: 2 * time.Second},
+			{Name: "Keychron K2", Address: "aa:bb:cc:00:00:05", Type: "Keyboard", RSSI: rssi(-66), AppearAfter: 3 * time.Second},
+		},
+	}
+}
+
+func normalizeAddress(addr string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(addr), "-", ":"))
+}
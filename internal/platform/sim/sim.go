@@ -0,0 +1,382 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// Simulator is an in-memory core.BluetoothPort driven by a Scenario.
+//
+// It keeps its own simulated clock which advances by operation latencies,
+// inquiry windows and waits. Device appearance and connection delays are
+// evaluated against that clock, so a scenario plays out identically no
+// matter how fast it runs (see Scenario.TimeScale).
+type Simulator struct {
+	Verbose bool
+	Logger  io.Writer
+
+	mu        sync.Mutex
+	scenario  Scenario
+	rng       *rand.Rand
+	draws     int
+	elapsed   time.Duration
+	devices   map[string]*deviceState
+	order     []string
+	timeScale float64
+	statePath string
+	adapter   adapterState
+}
+
+type adapterState struct {
+	powered      bool
+	poweredAt    time.Duration // simulated time at which a pending power-on completes
+	discoverable bool
+}
+
+type deviceState struct {
+	spec        DeviceSpec
+	paired      bool
+	connected   bool
+	connectedAt time.Duration // simulated time at which a pending connection completes
+	attempts    int
+}
+
+// New builds a simulator for the scenario.
+func New(s Scenario) *Simulator {
+	sim := &Simulator{
+		scenario:  s,
+		rng:       rand.New(rand.NewSource(s.Seed)),
+		devices:   map[string]*deviceState{},
+		timeScale: 1,
+		adapter:   adapterState{powered: s.Adapter.powered(), discoverable: s.Adapter.Discoverable},
+	}
+	if s.TimeScale != nil && *s.TimeScale >= 0 {
+		sim.timeScale = *s.TimeScale
+	}
+	for _, spec := range s.Devices {
+		addr := normalizeAddress(spec.Address)
+		spec.Address = addr
+		sim.devices[addr] = &deviceState{spec: spec, paired: spec.Paired, connected: spec.Connected}
+		sim.order = append(sim.order, addr)
+	}
+	return sim
+}
+
+// Elapsed returns the simulated time since the scenario started.
+func (s *Simulator) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.elapsed
+}
+
+func (s *Simulator) logf(format string, args ...any) {
+	if !s.Verbose || s.Logger == nil {
+		return
+	}
+	fmt.Fprintf(s.Logger, "sim: t=%s "+format, append([]any{s.elapsed}, args...)...)
+}
+
+func (s *Simulator) latency(op string) time.Duration {
+	if d, ok := s.scenario.Latency[op]; ok {
+		return d
+	}
+	return s.scenario.Latency["default"]
+}
+
+// advance moves the simulated clock and sleeps the scaled real time.
+// It must be called without holding s.mu.
+func (s *Simulator) advance(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	s.elapsed += d
+	s.save()
+	wall := time.Duration(float64(d) * s.timeScale)
+	s.mu.Unlock()
+
+	if wall <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(wall)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// fails draws from the seeded RNG. Caller holds s.mu.
+func (s *Simulator) fails(op string, d *deviceState) bool {
+	p, ok := 0.0, false
+	if d != nil {
+		p, ok = d.spec.Failures[op]
+	}
+	if !ok {
+		p = s.scenario.Failures[op]
+	}
+	if p <= 0 {
+		return false
+	}
+	s.draws++
+	return s.rng.Float64() < p
+}
+
+// device looks up a device. Caller holds s.mu.
+func (s *Simulator) device(address string) (*deviceState, error) {
+	d, ok := s.devices[normalizeAddress(address)]
+	if !ok {
+		return nil, fmt.Errorf("sim: device %s not found", address)
+	}
+	return d, nil
+}
+
+// isConnected reports a settled connection. Caller holds s.mu.
+func (s *Simulator) isConnected(d *deviceState) bool {
+	return s.isPowered() && d.connected && d.connectedAt <= s.elapsed
+}
+
+// isPowered reports a powered adapter that finished powering on. Caller holds s.mu.
+func (s *Simulator) isPowered() bool {
+	return s.adapter.powered && s.adapter.poweredAt <= s.elapsed
+}
+
+// errPoweredOff is what radio operations return while the adapter is off. Caller holds s.mu.
+func (s *Simulator) errPoweredOff(op string) error {
+	if s.isPowered() {
+		return nil
+	}
+	return fmt.Errorf("sim: %s: Bluetooth is powered off", op)
+}
+
+func (s *Simulator) toDevice(d *deviceState) core.Device {
+	dev := core.Device{
+		Name:      d.spec.Name,
+		Address:   d.spec.Address,
+		Type:      d.spec.Type,
+		RSSI:      d.spec.RSSI,
+		Connected: s.isConnected(d),
+		Paired:    d.paired,
+	}
+	if d.spec.Battery != nil {
+		b := *d.spec.Battery
+		dev.Battery = &b
+	}
+	return dev
+}
+
+func (s *Simulator) snapshot(keep func(*deviceState) bool) []core.Device {
+	out := make([]core.Device, 0)
+	for _, addr := range s.order {
+		d := s.devices[addr]
+		if keep(d) {
+			out = append(out, s.toDevice(d))
+		}
+	}
+	return out
+}
+
+func (s *Simulator) List(ctx context.Context) ([]core.Device, error) {
+	if err := s.advance(ctx, s.latency("list")); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails("list", nil) {
+		return nil, fmt.Errorf("sim: list: simulated failure")
+	}
+	return s.snapshot(func(d *deviceState) bool { return d.paired }), nil
+}
+
+func (s *Simulator) Connect(ctx context.Context, address string) error {
+	if err := s.advance(ctx, s.latency("connect")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	if err := s.errPoweredOff("connect " + address); err != nil {
+		return err
+	}
+	d, err := s.device(address)
+	if err != nil {
+		return err
+	}
+	if !d.paired {
+		return fmt.Errorf("sim: connect %s: device is not paired", address)
+	}
+	if d.connected {
+		return nil
+	}
+
+	d.attempts++
+	failed := d.attempts < d.spec.ConnectOnAttempt || s.fails("connect", d)
+	if failed {
+		if d.spec.SilentFailures {
+			s.logf("connect %s attempt %d: silent failure\n", address, d.attempts)
+			return nil
+		}
+		s.logf("connect %s attempt %d: failed\n", address, d.attempts)
+		return fmt.Errorf("sim: connect %s: page timeout (attempt %d)", address, d.attempts)
+	}
+
+	s.logf("connect %s attempt %d: ok\n", address, d.attempts)
+	d.connected = true
+	d.connectedAt = s.elapsed + d.spec.ConnectDelay
+	return nil
+}
+
+func (s *Simulator) Disconnect(ctx context.Context, address string) error {
+	if err := s.advance(ctx, s.latency("disconnect")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	d, err := s.device(address)
+	if err != nil {
+		return err
+	}
+	if s.fails("disconnect", d) {
+		return fmt.Errorf("sim: disconnect %s: simulated failure", address)
+	}
+	d.connected = false
+	return nil
+}
+
+func (s *Simulator) Pair(ctx context.Context, address string, pin string) error {
+	if err := s.advance(ctx, s.latency("pair")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	if err := s.errPoweredOff("pair " + address); err != nil {
+		return err
+	}
+	d, err := s.device(address)
+	if err != nil {
+		return err
+	}
+	if !d.paired && !s.visible(d) {
+		return fmt.Errorf("sim: pair %s: device is not in range", address)
+	}
+	if d.spec.Pin != "" && pin != d.spec.Pin {
+		return fmt.Errorf("sim: pair %s: authentication failed", address)
+	}
+	if s.fails("pair", d) {
+		return fmt.Errorf("sim: pair %s: simulated failure", address)
+	}
+	d.paired = true
+	return nil
+}
+
+func (s *Simulator) Unpair(ctx context.Context, address string) error {
+	if err := s.advance(ctx, s.latency("unpair")); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	d, err := s.device(address)
+	if err != nil {
+		return err
+	}
+	if s.fails("unpair", d) {
+		return fmt.Errorf("sim: unpair %s: simulated failure", address)
+	}
+	d.paired = false
+	d.connected = false
+	return nil
+}
+
+// visible reports whether Inquiry would find the device now. Caller holds s.mu.
+func (s *Simulator) visible(d *deviceState) bool {
+	return d.spec.nearby() && s.elapsed >= d.spec.AppearAfter
+}
+
+func (s *Simulator) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	if durationSeconds <= 0 {
+		durationSeconds = 10
+	}
+	if err := s.advance(ctx, time.Duration(durationSeconds)*time.Second+s.latency("inquiry")); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.save()
+
+	if err := s.errPoweredOff("inquiry"); err != nil {
+		return nil, err
+	}
+	if s.fails("inquiry", nil) {
+		return nil, fmt.Errorf("sim: inquiry: simulated failure")
+	}
+	return s.snapshot(s.visible), nil
+}
+
+func (s *Simulator) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	if err := s.advance(ctx, s.latency("waitConnect")); err != nil {
+		return err
+	}
+	timeout := time.Duration(timeoutSeconds) * time.Second
+
+	s.mu.Lock()
+	d, err := s.device(address)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	connected := d.connected && s.isPowered()
+	remaining := d.connectedAt - s.elapsed
+	s.mu.Unlock()
+
+	if connected && remaining <= 0 {
+		return nil
+	}
+	if connected && (timeout <= 0 || remaining <= timeout) {
+		return s.advance(ctx, remaining)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("sim: %s is not connecting", address)
+	}
+	if err := s.advance(ctx, timeout); err != nil {
+		return err
+	}
+	return fmt.Errorf("sim: timed out waiting for %s to connect", address)
+}
+
+func (s *Simulator) IsConnected(ctx context.Context, address string) (bool, error) {
+	if err := s.advance(ctx, s.latency("isConnected")); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.device(address)
+	if err != nil {
+		return false, err
+	}
+	return s.isConnected(d), nil
+}
+
+func (s *Simulator) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	if err := s.advance(ctx, s.latency("connectedDevices")); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot(s.isConnected), nil
+}
@@ -0,0 +1,378 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+func loadFixture(t *testing.T, name string) *Simulator {
+	t.Helper()
+	s, err := LoadScenario(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("LoadScenario: %v", err)
+	}
+	return New(s)
+}
+
+// namePicker picks devices by name, waiting on the stream until the device shows up.
+type namePicker struct {
+	name string
+}
+
+func (p namePicker) PickDevice(ctx context.Context, title string, devices []core.Device) (core.Device, error) {
+	for _, d := range devices {
+		if d.Name == p.name {
+			return d, nil
+		}
+	}
+	return core.Device{}, core.ErrNotFound{Query: p.name}
+}
+
+func (p namePicker) PickDevices(ctx context.Context, title string, devices []core.Device) ([]core.Device, error) {
+	d, err := p.PickDevice(ctx, title, devices)
+	if err != nil {
+		return nil, err
+	}
+	return []core.Device{d}, nil
+}
+
+func (p namePicker) PickDeviceStream(ctx context.Context, title string, updates <-chan []core.Device) (core.Device, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return core.Device{}, ctx.Err()
+		case ds, ok := <-updates:
+			if !ok {
+				return core.Device{}, core.ErrCanceled{}
+			}
+			if d, err := p.PickDevice(ctx, title, ds); err == nil {
+				return d, nil
+			}
+		}
+	}
+}
+
+func TestParseScenario(t *testing.T) {
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+	if sim.scenario.Seed != 42 || sim.timeScale != 0.001 {
+		t.Fatalf("seed=%d timeScale=%v", sim.scenario.Seed, sim.timeScale)
+	}
+	if sim.latency("connect") != 800*time.Millisecond || sim.latency("list") != 50*time.Millisecond {
+		t.Fatalf("latency connect=%s list=%s", sim.latency("connect"), sim.latency("list"))
+	}
+	if _, ok := sim.devices["aa:bb:cc:00:00:03"]; !ok {
+		t.Fatalf("address should be normalized; devices=%v", sim.order)
+	}
+
+	json := loadFixture(t, "lossy.json")
+	if json.scenario.Failures["connect"] != 0.5 || json.devices["aa:bb:cc:00:00:10"].spec.ConnectDelay != 2*time.Second {
+		t.Fatalf("json scenario=%+v", json.scenario)
+	}
+}
+
+func TestParseScenario_Invalid(t *testing.T) {
+	for _, in := range []string{
+		`devices: [{name: A}]`,
+		`devices: [{name: A, address: "aa:aa:aa:aa:aa:aa"}, {name: B, address: "AA-AA-AA-AA-AA-AA"}]`,
+		`failures: {connect: 1.5}`,
+	} {
+		if _, err := ParseScenario([]byte(in)); err == nil {
+			t.Fatalf("expected error for %s", in)
+		}
+	}
+}
+
+func TestSimulator_ConnectOnKthAttempt(t *testing.T) {
+	ctx := context.Background()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+	const addr = "aa:bb:cc:00:00:03"
+
+	if err := sim.Disconnect(ctx, addr); err != nil {
+		t.Fatalf("Disconnect: %v", err)
+	}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if err := sim.Connect(ctx, addr); err != nil {
+			t.Fatalf("Connect attempt %d: %v (silent failures must not error)", attempt, err)
+		}
+		ok, _ := sim.IsConnected(ctx, addr)
+		if want := attempt == 3; ok != want {
+			t.Fatalf("attempt %d: connected=%v, want %v", attempt, ok, want)
+		}
+	}
+}
+
+func TestSimulator_InquiryAppearAfter(t *testing.T) {
+	ctx := context.Background()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+
+	first, err := sim.Inquiry(ctx, 3)
+	if err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	for _, d := range first {
+		if d.Name == "Keychron K2" || d.Name == "Far Away Speaker" {
+			t.Fatalf("unexpected device at t=%s: %+v", sim.Elapsed(), d)
+		}
+	}
+
+	second, err := sim.Inquiry(ctx, 3)
+	if err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	found := false
+	for _, d := range second {
+		found = found || d.Name == "Keychron K2"
+	}
+	if !found {
+		t.Fatalf("Keychron K2 should appear after 5s; t=%s devices=%+v", sim.Elapsed(), second)
+	}
+}
+
+func TestSimulator_PairRequiresPin(t *testing.T) {
+	ctx := context.Background()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+	const addr = "aa:bb:cc:00:00:05"
+
+	if _, err := sim.Inquiry(ctx, 6); err != nil {
+		t.Fatalf("Inquiry: %v", err)
+	}
+	if err := sim.Pair(ctx, addr, ""); err == nil {
+		t.Fatalf("expected authentication failure without PIN")
+	}
+	if err := sim.Pair(ctx, addr, "0000"); err != nil {
+		t.Fatalf("Pair: %v", err)
+	}
+	if err := sim.Pair(ctx, "aa:bb:cc:00:00:06", ""); err == nil {
+		t.Fatalf("expected out-of-range device to fail pairing")
+	}
+}
+
+func TestSimulator_WaitConnectUsesConnectDelay(t *testing.T) {
+	ctx := context.Background()
+	s, err := ParseScenario([]byte(`{"timeScale": 0, "devices": [{"name": "H", "address": "aa:aa:aa:aa:aa:aa", "paired": true, "connectDelay": "4s"}]}`))
+	if err != nil {
+		t.Fatalf("ParseScenario: %v", err)
+	}
+	sim := New(s)
+	const addr = "aa:aa:aa:aa:aa:aa"
+
+	if err := sim.Connect(ctx, addr); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if ok, _ := sim.IsConnected(ctx, addr); ok {
+		t.Fatalf("should not be connected before the delay")
+	}
+	if err := sim.WaitConnect(ctx, addr, 2); err == nil {
+		t.Fatalf("expected timeout with a 2s budget")
+	}
+	if err := sim.WaitConnect(ctx, addr, 5); err != nil {
+		t.Fatalf("WaitConnect: %v", err)
+	}
+	if ok, _ := sim.IsConnected(ctx, addr); !ok {
+		t.Fatalf("expected connected")
+	}
+}
+
+func TestSimulator_SeedIsDeterministic(t *testing.T) {
+	run := func() string {
+		sim := loadFixture(t, "lossy.json")
+		var b strings.Builder
+		for i := 0; i < 20; i++ {
+			err := sim.Connect(context.Background(), "aa:bb:cc:00:00:10")
+			if err != nil {
+				b.WriteString("x")
+				continue
+			}
+			b.WriteString("o")
+			_ = sim.Disconnect(context.Background(), "aa:bb:cc:00:00:10")
+		}
+		return b.String()
+	}
+	a, b := run(), run()
+	if a != b {
+		t.Fatalf("runs differ: %s vs %s", a, b)
+	}
+	if !strings.Contains(a, "x") || !strings.Contains(a, "o") {
+		t.Fatalf("expected a mix of failures and successes with p=0.5: %s", a)
+	}
+}
+
+func TestSimulator_StateFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+	const addr = "aa:bb:cc:00:00:03"
+
+	first := loadFixture(t, "flaky-trackpad.yaml")
+	if err := first.UseStateFile(path); err != nil {
+		t.Fatalf("UseStateFile: %v", err)
+	}
+	if err := first.Disconnect(ctx, addr); err != nil {
+		t.Fatalf("Disconnect: %v", err)
+	}
+	_ = first.Connect(ctx, addr)
+
+	second := loadFixture(t, "flaky-trackpad.yaml")
+	if err := second.UseStateFile(path); err != nil {
+		t.Fatalf("UseStateFile: %v", err)
+	}
+	if ok, _ := second.IsConnected(ctx, addr); ok {
+		t.Fatalf("state should carry the disconnect over")
+	}
+	if got := second.devices[addr].attempts; got != 1 {
+		t.Fatalf("attempts=%d, want 1", got)
+	}
+	if second.Elapsed() < first.Elapsed() {
+		t.Fatalf("clock should carry over: %s < %s", second.Elapsed(), first.Elapsed())
+	}
+}
+
+// Repair of the flaky trackpad end to end: the first two connects after
+// re-pairing fail silently and are caught by connect verification.
+func TestRepairer_EndToEnd(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+
+	r := core.Repairer{Bluetooth: sim, Picker: namePicker{name: "Magic Trackpad"}}
+	from, to, err := r.Repair(ctx, core.RepairParams{
+		Interactive:     true,
+		IsTTY:           true,
+		InquiryDuration: 10,
+		WaitConnect:     10,
+		MaxAttempts:     6,
+	})
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if from.Address != "aa:bb:cc:00:00:03" || to.Address != "aa:bb:cc:00:00:03" {
+		t.Fatalf("from=%+v to=%+v", from, to)
+	}
+	if got := sim.devices[to.Address].attempts; got != 3 {
+		t.Fatalf("attempts=%d, want 3", got)
+	}
+	if ok, _ := sim.IsConnected(ctx, to.Address); !ok {
+		t.Fatalf("expected trackpad connected after repair")
+	}
+}
+
+func TestRepairer_EndToEnd_GivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+
+	r := core.Repairer{Bluetooth: sim, Picker: namePicker{name: "Magic Trackpad"}}
+	_, _, err := r.Repair(ctx, core.RepairParams{
+		Interactive:     true,
+		IsTTY:           true,
+		InquiryDuration: 10,
+		WaitConnect:     10,
+		MaxAttempts:     2,
+	})
+	if err == nil {
+		t.Fatalf("expected repair to give up after 2 attempts")
+	}
+}
+
+func TestPairer_EndToEnd_WaitsForDiscovery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+
+	p := core.Pairer{Bluetooth: sim, Picker: namePicker{name: "Keychron K2"}}
+	dev, err := p.Pair(ctx, core.PairParams{
+		Interactive:     true,
+		IsTTY:           true,
+		InquiryDuration: 30,
+		Pin:             "0000",
+		WaitConnect:     10,
+		MaxAttempts:     3,
+	})
+	if err != nil {
+		t.Fatalf("Pair: %v", err)
+	}
+	if dev.Address != "aa:bb:cc:00:00:05" {
+		t.Fatalf("dev=%+v", dev)
+	}
+	devices, _ := sim.List(ctx)
+	if len(devices) != 2 {
+		t.Fatalf("paired devices=%+v", devices)
+	}
+}
+
+// Pairing and repairing by address need no picker: inquiry runs until the
+// device shows up.
+func TestPairerAndRepairer_ByAddress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sim := loadFixture(t, "flaky-trackpad.yaml")
+
+	p := core.Pairer{Bluetooth: sim}
+	dev, err := p.Pair(ctx, core.PairParams{Address: "AA:BB:CC:00:00:05", InquiryDuration: 30, Pin: "0000", WaitConnect: 10})
+	if err != nil {
+		t.Fatalf("Pair: %v", err)
+	}
+	if dev.Address != "aa:bb:cc:00:00:05" || sim.Elapsed() < 5*time.Second {
+		t.Fatalf("dev=%+v after %s", dev, sim.Elapsed())
+	}
+
+	r := core.Repairer{Bluetooth: sim}
+	from, to, err := r.Repair(ctx, core.RepairParams{Address: "aa-bb-cc-00-00-03", InquiryDuration: 10, WaitConnect: 10, MaxAttempts: 6})
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if from.Name != "Magic Trackpad" || to.Address != from.Address {
+		t.Fatalf("from=%+v to=%+v", from, to)
+	}
+	if ok, _ := sim.IsConnected(ctx, to.Address); !ok {
+		t.Fatalf("expected trackpad connected after repair")
+	}
+
+	var nf core.ErrNotFound
+	if _, _, err := r.Repair(ctx, core.RepairParams{Address: "aa:bb:cc:00:00:06"}); !errors.As(err, &nf) {
+		t.Fatalf("Repair(unpaired) = %v, want ErrNotFound", err)
+	}
+}
+
+// --power-on end to end: connect fails while the adapter is off, and
+// PowerOn waits out the power-on delay before the connect is retried.
+// Each Power poll costs 500ms of simulated time, which is what lets the delay pass.
+func TestSimulator_PowerOn(t *testing.T) {
+	ctx := context.Background()
+	s, err := ParseScenario([]byte(`{"timeScale": 0, "latency": {"power": "500ms"}, "adapter": {"powered": false, "powerOnDelay": "2s"},
+		"devices": [{"name": "K", "address": "aa:aa:aa:aa:aa:aa", "paired": true}]}`))
+	if err != nil {
+		t.Fatalf("ParseScenario: %v", err)
+	}
+	sim := New(s)
+	const addr = "aa:aa:aa:aa:aa:aa"
+
+	err = sim.Connect(ctx, addr)
+	if err = core.ExplainPoweredOff(ctx, sim, err); !errors.As(err, &core.ErrPoweredOff{}) {
+		t.Fatalf("expected ErrPoweredOff, got %v", err)
+	}
+
+	a := core.AdapterControl{Adapter: sim, PollInterval: time.Millisecond}
+	start := sim.Elapsed()
+	if err := a.PowerOn(ctx, 10*time.Second); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+	if waited := sim.Elapsed() - start; waited < 2*time.Second {
+		t.Fatalf("PowerOn returned after %s of simulated time, before the adapter was ready", waited)
+	}
+	if err := sim.Connect(ctx, addr); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	if on, err := a.TogglePower(ctx, time.Second); err != nil || on {
+		t.Fatalf("TogglePower=%v err=%v", on, err)
+	}
+	if ok, _ := sim.IsConnected(ctx, addr); ok {
+		t.Fatalf("powering off should drop connections")
+	}
+}
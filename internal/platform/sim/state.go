@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// state is what survives between CLI invocations when a state file is used,
+// so that `connect` followed by `list` behaves like one session.
+type state struct {
+	Elapsed time.Duration          `json:"elapsed"`
+	Draws   int                    `json:"draws"`
+	Devices map[string]deviceSaved `json:"devices"`
+	Adapter *adapterSaved          `json:"adapter,omitempty"`
+}
+
+type adapterSaved struct {
+	Powered      bool          `json:"powered"`
+	PoweredAt    time.Duration `json:"poweredAt"`
+	Discoverable bool          `json:"discoverable"`
+}
+
+type deviceSaved struct {
+	Paired      bool          `json:"paired"`
+	Connected   bool          `json:"connected"`
+	ConnectedAt time.Duration `json:"connectedAt"`
+	Attempts    int           `json:"attempts"`
+}
+
+// UseStateFile restores the simulator from path (if it exists) and saves
+// every state change back to it.
+func (s *Simulator) UseStateFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statePath = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("sim: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("sim: parse state %s: %w", path, err)
+	}
+
+	s.elapsed = st.Elapsed
+	// Replay the RNG so that the sequence continues where the last run stopped.
+	for i := 0; i < st.Draws; i++ {
+		s.rng.Float64()
+	}
+	s.draws = st.Draws
+	if a := st.Adapter; a != nil {
+		s.adapter = adapterState{powered: a.Powered, poweredAt: a.PoweredAt, discoverable: a.Discoverable}
+	}
+	for addr, saved := range st.Devices {
+		d, ok := s.devices[addr]
+		if !ok {
+			continue
+		}
+		d.paired = saved.Paired
+		d.connected = saved.Connected
+		d.connectedAt = saved.ConnectedAt
+		d.attempts = saved.Attempts
+	}
+	return nil
+}
+
+// save writes the state file, if any. Caller holds s.mu.
+func (s *Simulator) save() {
+	if s.statePath == "" {
+		return
+	}
+	st := state{
+		Elapsed: s.elapsed,
+		Draws:   s.draws,
+		Devices: map[string]deviceSaved{},
+		Adapter: &adapterSaved{Powered: s.adapter.powered, PoweredAt: s.adapter.poweredAt, Discoverable: s.adapter.discoverable},
+	}
+	for addr, d := range s.devices {
+		st.Devices[addr] = deviceSaved{
+			Paired:      d.paired,
+			Connected:   d.connected,
+			ConnectedAt: d.connectedAt,
+			Attempts:    d.attempts,
+		}
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(s.statePath, b, 0o600); err != nil {
+		s.logf("save state: %v\n", err)
+	}
+}
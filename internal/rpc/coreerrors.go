@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+)
+
+// CodeFailed is the error code of failed operations. Core errors are
+// described in the error data (a "kind" plus its fields) so that clients can
+// tell them apart, and DecodeCoreError can return them as such (the CLI turns
+// them into hints and exit codes).
+const CodeFailed = -32000
+
+type errorData struct {
+	Kind       string `json:"kind"`
+	Query      string `json:"query,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Dependency string `json:"dependency,omitempty"`
+	Feature    string `json:"feature,omitempty"`
+	Have       string `json:"have,omitempty"`
+	Need       string `json:"need,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+}
+
+// EncodeCoreError is a Server.EncodeError that describes core errors.
+func EncodeCoreError(err error) *Error {
+	var (
+		data errorData
+		nf   core.ErrNotFound
+		am   core.ErrAmbiguous
+		dm   core.ErrDependencyMissing
+		old  core.ErrDependencyTooOld
+		up   core.ErrUnsupportedPlatform
+	)
+	switch {
+	case errors.As(err, &nf):
+		data = errorData{Kind: "not-found", Query: nf.Query}
+	case errors.As(err, &am):
+		data = errorData{Kind: "ambiguous", Query: am.Query, Count: am.Count}
+	case errors.As(err, &dm):
+		data = errorData{Kind: "dependency-missing", Dependency: dm.Dependency}
+	case errors.As(err, &old):
+		data = errorData{Kind: "dependency-too-old", Dependency: old.Dependency, Feature: old.Feature, Have: old.Have, Need: old.Need}
+	case errors.As(err, &up):
+		data = errorData{Kind: "unsupported-platform", Platform: up.Platform}
+	case errors.As(err, new(core.ErrPoweredOff)):
+		data = errorData{Kind: "powered-off"}
+	case errors.As(err, new(core.ErrCanceled)), errors.Is(err, context.Canceled):
+		data = errorData{Kind: "canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		data = errorData{Kind: "deadline-exceeded"}
+	}
+	e := &Error{Code: CodeFailed, Message: err.Error()}
+	if data.Kind != "" {
+		e.Data, _ = json.Marshal(data)
+	}
+	return e
+}
+
+// DecodeCoreError turns an error encoded by EncodeCoreError back into the
+// error the server saw, as far as callers can tell.
+func DecodeCoreError(err error) error {
+	var re *Error
+	if !errors.As(err, &re) {
+		return err
+	}
+	var data errorData
+	if len(re.Data) > 0 {
+		_ = json.Unmarshal(re.Data, &data)
+	}
+	var kind error
+	switch data.Kind {
+	case "not-found":
+		kind = core.ErrNotFound{Query: data.Query}
+	case "ambiguous":
+		kind = core.ErrAmbiguous{Query: data.Query, Count: data.Count}
+	case "dependency-missing":
+		kind = core.ErrDependencyMissing{Dependency: data.Dependency}
+	case "dependency-too-old":
+		kind = core.ErrDependencyTooOld{Dependency: data.Dependency, Feature: data.Feature, Have: data.Have, Need: data.Need}
+	case "unsupported-platform":
+		kind = core.ErrUnsupportedPlatform{Platform: data.Platform}
+	case "powered-off":
+		kind = core.ErrPoweredOff{}
+	case "canceled":
+		kind = core.ErrCanceled{}
+	case "deadline-exceeded":
+		kind = context.DeadlineExceeded
+	}
+	return remoteError{msg: re.Message, kind: kind}
+}
+
+// remoteError keeps the server's message (which may wrap the core error in
+// context) while matching the core error with errors.As.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e remoteError) Error() string { return e.msg }
+func (e remoteError) Unwrap() error { return e.kind }
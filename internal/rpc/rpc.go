@@ -0,0 +1,248 @@
+// Package rpc is a small JSON-RPC 2.0 implementation over newline-delimited
+// JSON streams (Unix sockets, stdio). Requests on one stream are handled
+// concurrently; handlers can send notifications (e.g. progress) with Notify.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const Version = "2.0"
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessage bounds one message (device lists are small).
+const maxMessage = 4 << 20
+
+// Message is a request, response or notification (a request without id).
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Errorf returns an *Error with code.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler handles one request. A returned *Error is sent as is; other
+// errors become CodeInternalError unless the server's ErrorEncoder maps them.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// Server serves JSON-RPC on streams.
+type Server struct {
+	Handler Handler
+	// EncodeError turns handler errors into error objects (optional).
+	EncodeError func(error) *Error
+	// FinishOnEOF lets in-flight requests finish when r ends, instead of
+	// cancelling them: on stdin, the end of input is not the client
+	// hanging up.
+	FinishOnEOF bool
+}
+
+type (
+	notifyKey struct{}
+	afterKey  struct{}
+)
+
+// Notify sends a notification to the client of the request being handled in
+// ctx. Outside of a request it does nothing.
+func Notify(ctx context.Context, method string, params any) error {
+	send, ok := ctx.Value(notifyKey{}).(func(Message) error)
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return send(Message{JSONRPC: Version, Method: method, Params: b})
+}
+
+// AfterResponse arranges for f to run once the response to the request being
+// handled in ctx has been written, e.g. to start work whose notifications
+// must not reach the client before the response. Outside of a request, or for
+// notifications (which get no response), f runs right away.
+func AfterResponse(ctx context.Context, f func()) {
+	after, ok := ctx.Value(afterKey{}).(*[]func())
+	if !ok {
+		f()
+		return
+	}
+	*after = append(*after, f)
+}
+
+// Serve reads requests from r and writes responses to w until r ends or ctx
+// is done. In-flight requests are cancelled when it returns (see
+// FinishOnEOF), after which their responses are dropped.
+func (s Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // before waiting for the handlers
+
+	var wmu sync.Mutex
+	enc := json.NewEncoder(w)
+	send := func(m Message) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		return enc.Encode(m)
+	}
+	reqCtx := context.WithValue(ctx, notifyKey{}, send)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxMessage)
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for sc.Scan() {
+			line := append([]byte(nil), sc.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- sc.Err()
+	}()
+
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if s.FinishOnEOF {
+				wg.Wait()
+			}
+			return err
+		case line = <-lines:
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var m Message
+		if err := json.Unmarshal(line, &m); err != nil {
+			_ = send(Message{JSONRPC: Version, ID: json.RawMessage("null"), Error: Errorf(CodeParseError, "parse error: %v", err)})
+			continue
+		}
+		if m.Method == "" {
+			if m.ID != nil {
+				_ = send(Message{JSONRPC: Version, ID: m.ID, Error: Errorf(CodeInvalidRequest, "missing method")})
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hctx := reqCtx
+			var after []func()
+			if m.ID != nil {
+				hctx = context.WithValue(reqCtx, afterKey{}, &after)
+			}
+			res, err := s.Handler(hctx, m.Method, m.Params)
+			if m.ID == nil {
+				return // notification: no response
+			}
+			defer func() {
+				for _, f := range after {
+					f()
+				}
+			}()
+			if ctx.Err() != nil {
+				return
+			}
+			resp := Message{JSONRPC: Version, ID: m.ID}
+			if err != nil {
+				resp.Error = s.encodeError(err)
+			} else if resp.Result, err = json.Marshal(res); err != nil {
+				resp.Result = nil
+				resp.Error = Errorf(CodeInternalError, "encode result: %v", err)
+			}
+			_ = send(resp)
+		}()
+	}
+}
+
+func (s Server) encodeError(err error) *Error {
+	var re *Error
+	if errors.As(err, &re) {
+		return re
+	}
+	if s.EncodeError != nil {
+		if e := s.EncodeError(err); e != nil {
+			return e
+		}
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// Call sends one request on rw and waits for its response, passing any
+// notifications received meanwhile to onNotify (optional). rw should be
+// dedicated to this call; when ctx is done, closing it is up to the caller.
+func Call(ctx context.Context, rw io.ReadWriter, method string, params, result any, onNotify func(method string, params json.RawMessage)) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(rw).Encode(Message{JSONRPC: Version, ID: json.RawMessage("1"), Method: method, Params: p}); err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(rw)
+	sc.Buffer(make([]byte, 0, 64<<10), maxMessage)
+	for sc.Scan() {
+		var m Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			return fmt.Errorf("rpc: invalid response: %w", err)
+		}
+		if m.ID == nil {
+			if onNotify != nil && m.Method != "" {
+				onNotify(m.Method, m.Params)
+			}
+			continue
+		}
+		if m.Error != nil {
+			return m.Error
+		}
+		if result == nil || len(m.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, s Server) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Serve(ctx, server, server)
+		server.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		<-done
+	})
+	return client
+}
+
+func TestCall(t *testing.T) {
+	errBusy := errors.New("busy")
+	conn := serve(t, Server{
+		Handler: func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+			switch method {
+			case "add":
+				var p []int
+				if err := json.Unmarshal(params, &p); err != nil {
+					return nil, Errorf(CodeInvalidParams, "want numbers")
+				}
+				for i := 1; i <= 2; i++ {
+					_ = Notify(ctx, "progress", i)
+				}
+				return p[0] + p[1], nil
+			case "busy":
+				return nil, fmt.Errorf("wrapped: %w", errBusy)
+			}
+			return nil, Errorf(CodeMethodNotFound, "unknown method %q", method)
+		},
+		EncodeError: func(err error) *Error {
+			if errors.Is(err, errBusy) {
+				return &Error{Code: -32000, Message: err.Error(), Data: json.RawMessage(`"busy"`)}
+			}
+			return nil
+		},
+	})
+	ctx := context.Background()
+
+	var sum int
+	var progress []string
+	err := Call(ctx, conn, "add", []int{2, 3}, &sum, func(method string, params json.RawMessage) {
+		progress = append(progress, method+"="+string(params))
+	})
+	if err != nil || sum != 5 {
+		t.Fatalf("add = %d, %v", sum, err)
+	}
+	if strings.Join(progress, ",") != "progress=1,progress=2" {
+		t.Fatalf("notifications = %v", progress)
+	}
+
+	var re *Error
+	if err := Call(ctx, conn, "add", "x", nil, nil); !errors.As(err, &re) || re.Code != CodeInvalidParams {
+		t.Fatalf("invalid params: %v", err)
+	}
+	if err := Call(ctx, conn, "nope", nil, nil, nil); !errors.As(err, &re) || re.Code != CodeMethodNotFound {
+		t.Fatalf("unknown method: %v", err)
+	}
+	if err := Call(ctx, conn, "busy", nil, nil, nil); !errors.As(err, &re) || re.Code != -32000 || string(re.Data) != `"busy"` || re.Message != "wrapped: busy" {
+		t.Fatalf("encoded error: %#v", err)
+	}
+}
+
+func TestServe_ParseError(t *testing.T) {
+	conn := serve(t, Server{Handler: func(context.Context, string, json.RawMessage) (any, error) { return nil, nil }})
+	if _, err := conn.Write([]byte("{not json\n")); err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := json.NewDecoder(conn).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Error == nil || m.Error.Code != CodeParseError || string(m.ID) != "null" {
+		t.Fatalf("response = %+v", m)
+	}
+}
+
+func TestServe_CancelsOnDisconnect(t *testing.T) {
+	canceled := make(chan struct{})
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = Server{Handler: func(ctx context.Context, _ string, _ json.RawMessage) (any, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}}.Serve(context.Background(), server, server)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		client.Close()
+	}()
+	if err := Call(ctx, client, "wait", nil, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Call = %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+	<-done
+}
@@ -0,0 +1,307 @@
+// Package rpcapi serves bt-manage as JSON-RPC 2.0 over one stream (see
+// `serve --stdio`), for editor and launcher extensions that keep a
+// bt-manage process running instead of spawning one per action. Messages
+// are newline-delimited JSON. The methods mirror the commands:
+//
+//	list          {"connected", "disconnected"}     like `list --format json`
+//	info          {"name", "exact"}                 one device (an object, not a 1-element array)
+//	connect       {"name", "exact", "dryRun"}       like `connect --format json`
+//	disconnect    {"name", "exact", "dryRun"}       like `disconnect --format json`
+//	pair.start    jobs.PairRequest                  start a pair job; returns the job
+//	repair.start  jobs.RepairRequest                start a repair job; returns the job
+//	job.get       {"id"}                            a job, as last reported
+//
+// Names match like the commands': an address, an exact name or alias, or a
+// unique prefix unless "exact" is set. A running job reports its progress
+// lines as "job.progress" notifications ({"id", "message"}) and ends with a
+// "job.finished" notification carrying the job; both follow the response
+// that started it. Failures use rpc.CodeFailed, with the kind of error in
+// the error data (see rpc.EncodeCoreError).
+package rpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/jobs"
+	"github.com/fumihumi/bt-manage/internal/output"
+	"github.com/fumihumi/bt-manage/internal/rpc"
+)
+
+// Method names.
+const (
+	MethodList        = "list"
+	MethodInfo        = "info"
+	MethodConnect     = "connect"
+	MethodDisconnect  = "disconnect"
+	MethodPairStart   = "pair.start"
+	MethodRepairStart = "repair.start"
+	MethodJobGet      = "job.get"
+)
+
+// Notification names.
+const (
+	NotifyJobProgress = "job.progress"
+	NotifyJobFinished = "job.finished"
+)
+
+// DefaultOpTimeout bounds connect and disconnect calls.
+const DefaultOpTimeout = 30 * time.Second
+
+// Server is the JSON-RPC API over one Bluetooth env.
+type Server struct {
+	Bluetooth core.BluetoothPort
+	Enricher  core.EnricherPort // optional
+	Groups    []core.ExclusiveGroup
+	// OpTimeout bounds connect and disconnect calls (default DefaultOpTimeout).
+	OpTimeout time.Duration
+	// Retry shapes connects, including those of pair and repair jobs.
+	Retry core.RetryPolicy
+	// OnPairFailed is called when a pair or repair job fails for a device (optional).
+	OnPairFailed func(dev core.Device, err error)
+	// Log receives errors that are not reported to the client (optional).
+	Log io.Writer
+
+	jobs jobs.Manager
+	base context.Context // jobs outlive their request, not the stream
+	mu   sync.Mutex      // serializes Log writes
+}
+
+// DeviceParams selects a device, like the name argument of the commands.
+type DeviceParams struct {
+	Name   string `json:"name"`
+	Exact  bool   `json:"exact,omitempty"`
+	DryRun bool   `json:"dryRun,omitempty"` // connect and disconnect only
+}
+
+// ListParams filters list, like the flags of the list command.
+type ListParams struct {
+	Connected    bool `json:"connected,omitempty"`
+	Disconnected bool `json:"disconnected,omitempty"`
+}
+
+// JobParams names a job.
+type JobParams struct {
+	ID string `json:"id"`
+}
+
+// Progress is the params of a job.progress notification.
+type Progress struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// Serve serves the API on r and w until r ends or ctx is done. Requests in
+// flight when r ends are still answered; running jobs are then cancelled
+// and waited for.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	base, cancel := context.WithCancel(ctx)
+	s.base = base
+	defer s.jobs.Wait()
+	defer cancel() // before waiting for the jobs
+
+	srv := rpc.Server{Handler: s.handle, EncodeError: rpc.EncodeCoreError, FinishOnEOF: true}
+	return srv.Serve(ctx, r, w)
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case MethodList:
+		var p ListParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.list(ctx, p)
+	case MethodInfo:
+		var p DeviceParams
+		if err := decodeDevice(params, &p); err != nil {
+			return nil, err
+		}
+		return core.Inspector{Bluetooth: s.Bluetooth, Enricher: s.Enricher}.Inspect(ctx, core.InspectParams{Name: p.Name, Exact: p.Exact})
+	case MethodConnect:
+		var p DeviceParams
+		if err := decodeDevice(params, &p); err != nil {
+			return nil, err
+		}
+		return s.connect(ctx, p)
+	case MethodDisconnect:
+		var p DeviceParams
+		if err := decodeDevice(params, &p); err != nil {
+			return nil, err
+		}
+		return s.disconnect(ctx, p)
+	case MethodPairStart:
+		var p jobs.PairRequest
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		run, err := p.Pair(s.Bluetooth, s.Retry)
+		if err != nil {
+			return nil, rpc.Errorf(rpc.CodeInvalidParams, "%v", err)
+		}
+		return s.startJob(ctx, "pair", p.Address, run)
+	case MethodRepairStart:
+		var p jobs.RepairRequest
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		run, err := p.Repair(s.Bluetooth, s.Retry)
+		if err != nil {
+			return nil, rpc.Errorf(rpc.CodeInvalidParams, "%v", err)
+		}
+		return s.startJob(ctx, "repair", p.Address, run)
+	case MethodJobGet:
+		var p JobParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		j, ok := s.jobs.Get(p.ID)
+		if !ok {
+			return nil, rpc.Errorf(rpc.CodeInvalidParams, "no job %s", p.ID)
+		}
+		return j, nil
+	}
+	return nil, rpc.Errorf(rpc.CodeMethodNotFound, "unknown method %q", method)
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return rpc.Errorf(rpc.CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+func decodeDevice(params json.RawMessage, p *DeviceParams) error {
+	if err := decodeParams(params, p); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return rpc.Errorf(rpc.CodeInvalidParams, "name is required")
+	}
+	return nil
+}
+
+// list returns the devices as `list --format json` prints them.
+func (s *Server) list(ctx context.Context, p ListParams) (json.RawMessage, error) {
+	if p.Connected && p.Disconnected {
+		return nil, rpc.Errorf(rpc.CodeInvalidParams, "connected and disconnected are mutually exclusive")
+	}
+	devices, err := core.Lister{Bluetooth: s.Bluetooth, Enricher: s.Enricher}.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.Connected || p.Disconnected {
+		filtered := make([]core.Device, 0, len(devices))
+		for _, d := range devices {
+			if d.Connected == p.Connected {
+				filtered = append(filtered, d)
+			}
+		}
+		devices = filtered
+	}
+	var buf bytes.Buffer
+	if err := output.WriteJSON(&buf, devices); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Server) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := s.OpTimeout
+	if timeout <= 0 {
+		timeout = DefaultOpTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// connect returns the device and the group members it displaced, each with
+// an "action", like `connect --format json`.
+func (s *Server) connect(ctx context.Context, p DeviceParams) (json.RawMessage, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	c := core.Connector{Bluetooth: s.Bluetooth, Groups: s.Groups, Retry: s.Retry}
+	res, err := c.Connect(ctx, core.ConnectParams{Name: p.Name, Exact: p.Exact, DryRun: p.DryRun})
+	if err != nil && res.Device.Address == "" {
+		return nil, err
+	}
+	if err != nil {
+		// Connected, but displacing group members failed: still a success for the device.
+		s.logf("connect %s: %v", p.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := output.WriteConnectJSON(&buf, []core.Device{res.Device}, res.Displaced); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Server) disconnect(ctx context.Context, p DeviceParams) (json.RawMessage, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	d := core.Disconnector{Bluetooth: s.Bluetooth}
+	dev, err := d.DisconnectByNameOrInteractive(ctx, core.DisconnectParams{Name: p.Name, Exact: p.Exact, DryRun: p.DryRun})
+	if err != nil {
+		return nil, err
+	}
+	if !p.DryRun {
+		dev.Connected = false
+	}
+	var buf bytes.Buffer
+	if err := output.WriteConnectJSON(&buf, nil, []core.Device{dev}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// startJob registers the job and starts it once the response is written, so
+// that its notifications follow the job's ID.
+func (s *Server) startJob(ctx context.Context, kind, address string, run jobs.Func) (jobs.Job, error) {
+	if s.base == nil {
+		return jobs.Job{}, errors.New("rpcapi: not serving")
+	}
+	j, begin, err := s.jobs.Start(s.base, kind, address, run, jobs.Observer{
+		Progress: func(id, line string) {
+			s.notify(ctx, NotifyJobProgress, Progress{ID: id, Message: line})
+		},
+		Finished: func(j jobs.Job, err error) {
+			if err != nil && s.OnPairFailed != nil && j.Device != nil {
+				s.OnPairFailed(*j.Device, err)
+			}
+			s.notify(ctx, NotifyJobFinished, j)
+		},
+	})
+	if err != nil { // jobs.ErrBusy
+		return jobs.Job{}, err
+	}
+	rpc.AfterResponse(ctx, begin)
+	return j, nil
+}
+
+// notify sends a notification on the stream of the request in ctx, which
+// stays usable after that request has been answered.
+func (s *Server) notify(ctx context.Context, method string, params any) {
+	if err := rpc.Notify(ctx, method, params); err != nil {
+		s.logf("%s: %v", method, err)
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Log == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.Log, "rpc: "+format+"\n", args...)
+}
@@ -0,0 +1,291 @@
+package rpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fumihumi/bt-manage/internal/core"
+	"github.com/fumihumi/bt-manage/internal/jobs"
+	"github.com/fumihumi/bt-manage/internal/rpc"
+)
+
+// fakeBluetooth keeps paired devices in memory; nearby devices can be found
+// with Inquiry and paired.
+type fakeBluetooth struct {
+	mu     sync.Mutex
+	paired []core.Device
+	nearby []core.Device
+}
+
+func (f *fakeBluetooth) List(ctx context.Context) ([]core.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]core.Device(nil), f.paired...), nil
+}
+
+func (f *fakeBluetooth) setConnected(address string, connected bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.paired {
+		if f.paired[i].Address == address {
+			f.paired[i].Connected = connected
+			return nil
+		}
+	}
+	return core.ErrNotFound{Query: address}
+}
+
+func (f *fakeBluetooth) Connect(ctx context.Context, address string) error {
+	return f.setConnected(address, true)
+}
+
+func (f *fakeBluetooth) Disconnect(ctx context.Context, address string) error {
+	return f.setConnected(address, false)
+}
+
+func (f *fakeBluetooth) Pair(ctx context.Context, address, pin string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.nearby {
+		if d.Address == address {
+			d.Paired = true
+			f.paired = append(f.paired, d)
+			return nil
+		}
+	}
+	return core.ErrNotFound{Query: address}
+}
+
+func (f *fakeBluetooth) Unpair(ctx context.Context, address string) error { return nil }
+
+func (f *fakeBluetooth) Inquiry(ctx context.Context, durationSeconds int) ([]core.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]core.Device(nil), f.nearby...), nil
+}
+
+func (f *fakeBluetooth) WaitConnect(ctx context.Context, address string, timeoutSeconds int) error {
+	return nil
+}
+
+func (f *fakeBluetooth) IsConnected(ctx context.Context, address string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.paired {
+		if d.Address == address {
+			return d.Connected, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeBluetooth) ConnectedDevices(ctx context.Context) ([]core.Device, error) {
+	devices, _ := f.List(ctx)
+	var out []core.Device
+	for _, d := range devices {
+		if d.Connected {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// client speaks to the server like an extension would: requests and
+// notifications interleave on one long-lived stream.
+type client struct {
+	t      *testing.T
+	enc    *json.Encoder
+	dec    *json.Decoder
+	nextID int
+	// notes are the notifications received so far, in order.
+	notes []rpc.Message
+}
+
+func startServer(t *testing.T, bt core.BluetoothPort) *client {
+	t.Helper()
+	conn, server := net.Pipe()
+	s := &Server{
+		Bluetooth: bt,
+		Groups:    []core.ExclusiveGroup{{Name: "audio", Members: []string{"AirPods Pro", "Desk Speaker"}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx, server, server)
+		server.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		conn.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &client{t: t, enc: json.NewEncoder(conn), dec: json.NewDecoder(conn)}
+}
+
+func (c *client) read() rpc.Message {
+	c.t.Helper()
+	var m rpc.Message
+	if err := c.dec.Decode(&m); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return m
+}
+
+// call sends a request and returns its response, keeping notifications that
+// arrive first.
+func (c *client) call(method string, params any) rpc.Message {
+	c.t.Helper()
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	p, _ := json.Marshal(params)
+	if err := c.enc.Encode(rpc.Message{JSONRPC: rpc.Version, ID: json.RawMessage(id), Method: method, Params: p}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		m := c.read()
+		if m.ID == nil {
+			c.notes = append(c.notes, m)
+			continue
+		}
+		if string(m.ID) != id {
+			c.t.Fatalf("response id %s, want %s", m.ID, id)
+		}
+		return m
+	}
+}
+
+func newFake() *fakeBluetooth {
+	return &fakeBluetooth{
+		paired: []core.Device{
+			{Name: "AirPods Pro", Address: "aa:bb:cc:00:00:01", Paired: true},
+			{Name: "Desk Speaker", Address: "aa:bb:cc:00:00:02", Paired: true, Connected: true},
+		},
+		nearby: []core.Device{
+			{Name: "New Keyboard", Address: "aa:bb:cc:00:00:03"},
+		},
+	}
+}
+
+func TestServer_Commands(t *testing.T) {
+	c := startServer(t, newFake())
+
+	var devices []core.Device
+	if m := c.call(MethodList, nil); m.Error != nil || json.Unmarshal(m.Result, &devices) != nil || len(devices) != 2 {
+		t.Fatalf("list: %+v", m)
+	}
+	if m := c.call(MethodList, ListParams{Connected: true}); json.Unmarshal(m.Result, &devices) != nil || len(devices) != 1 || devices[0].Name != "Desk Speaker" {
+		t.Fatalf("list connected: %+v", m)
+	}
+
+	var dev core.Device
+	if m := c.call(MethodInfo, DeviceParams{Name: "Air"}); m.Error != nil || json.Unmarshal(m.Result, &dev) != nil || dev.Address != "aa:bb:cc:00:00:01" {
+		t.Fatalf("info: %+v", m)
+	}
+
+	m := c.call(MethodConnect, DeviceParams{Name: "AirPods Pro", Exact: true})
+	var entries []struct {
+		Name   string `json:"name"`
+		Action string `json:"action"`
+	}
+	if m.Error != nil || json.Unmarshal(m.Result, &entries) != nil {
+		t.Fatalf("connect: %+v", m)
+	}
+	if len(entries) != 2 || entries[0].Name != "AirPods Pro" || entries[0].Action != "connect" ||
+		entries[1].Name != "Desk Speaker" || entries[1].Action != "disconnect" {
+		t.Fatalf("connect result = %+v", entries)
+	}
+
+	if m := c.call(MethodDisconnect, DeviceParams{Name: "AirPods"}); m.Error != nil || json.Unmarshal(m.Result, &entries) != nil ||
+		len(entries) != 1 || entries[0].Action != "disconnect" {
+		t.Fatalf("disconnect: %+v", m)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	bt := newFake()
+	bt.paired = append(bt.paired, core.Device{Name: "AirPods Max", Address: "aa:bb:cc:00:00:04", Paired: true})
+	c := startServer(t, bt)
+
+	tests := []struct {
+		method string
+		params any
+		code   int
+		kind   string
+	}{
+		{"scan", nil, rpc.CodeMethodNotFound, ""},
+		{MethodConnect, nil, rpc.CodeInvalidParams, ""},
+		{MethodConnect, map[string]any{"name": "x", "force": true}, rpc.CodeInvalidParams, ""},
+		{MethodConnect, DeviceParams{Name: "Nope"}, rpc.CodeFailed, "not-found"},
+		{MethodInfo, DeviceParams{Name: "AirPods"}, rpc.CodeFailed, "ambiguous"},
+		{MethodPairStart, jobs.PairRequest{PIN: "1234"}, rpc.CodeInvalidParams, ""},
+		{MethodJobGet, JobParams{ID: "9"}, rpc.CodeInvalidParams, ""},
+	}
+	for _, tt := range tests {
+		m := c.call(tt.method, tt.params)
+		if m.Error == nil || m.Error.Code != tt.code {
+			t.Errorf("%s %+v: got %+v, want code %d", tt.method, tt.params, m.Error, tt.code)
+			continue
+		}
+		var data struct {
+			Kind string `json:"kind"`
+		}
+		_ = json.Unmarshal(m.Error.Data, &data)
+		if data.Kind != tt.kind {
+			t.Errorf("%s %+v: kind %q, want %q", tt.method, tt.params, data.Kind, tt.kind)
+		}
+	}
+}
+
+func TestServer_PairStreamsProgress(t *testing.T) {
+	bt := newFake()
+	c := startServer(t, bt)
+
+	m := c.call(MethodPairStart, jobs.PairRequest{Address: "AA:BB:CC:00:00:03", PIN: "1234"})
+	var job jobs.Job
+	if m.Error != nil || json.Unmarshal(m.Result, &job) != nil || job.Status != jobs.Running {
+		t.Fatalf("pair.start: %+v", m)
+	}
+	if len(c.notes) != 0 {
+		t.Fatalf("notifications before the response: %+v", c.notes)
+	}
+
+	var progress []string
+	for job.Status == jobs.Running {
+		n := c.read()
+		switch n.Method {
+		case NotifyJobProgress:
+			var p Progress
+			if err := json.Unmarshal(n.Params, &p); err != nil || p.ID != job.ID {
+				t.Fatalf("progress %s: %v", n.Params, err)
+			}
+			progress = append(progress, p.Message)
+		case NotifyJobFinished:
+			if err := json.Unmarshal(n.Params, &job); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("unexpected message %+v", n)
+		}
+	}
+	if job.Status != jobs.Succeeded || job.Device == nil || job.Device.Name != "New Keyboard" {
+		t.Fatalf("finished job = %+v", job)
+	}
+	if len(progress) == 0 || len(progress) != len(job.Progress) {
+		t.Fatalf("progress notifications %q, job progress %q", progress, job.Progress)
+	}
+	if ok, _ := bt.IsConnected(context.Background(), "aa:bb:cc:00:00:03"); !ok {
+		t.Fatal("keyboard should be connected after pairing")
+	}
+
+	if m := c.call(MethodJobGet, JobParams{ID: job.ID}); m.Error != nil || json.Unmarshal(m.Result, &job) != nil || job.Status != jobs.Succeeded {
+		t.Fatalf("job.get: %+v", m)
+	}
+}
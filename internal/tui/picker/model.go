@@ -53,6 +53,10 @@ func newModel(title string, devices []core.Device) model {
 		a := sorted[i]
 		b := sorted[j]
 
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+
 		// Connect: prefer disconnected first.
 		if title == "Connect" {
 			if a.Connected != b.Connected {
@@ -72,7 +76,7 @@ func newModel(title string, devices []core.Device) model {
 			}
 		}
 
-		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		return strings.ToLower(a.DisplayName()) < strings.ToLower(b.DisplayName())
 	})
 
 	m := model{
@@ -153,7 +157,7 @@ func (m *model) applyFilter() {
 func deviceSearchKey(d core.Device) string {
 	// Lowercase, concatenated for simple substring match.
 	// Address may be empty depending on backend; keep it safe.
-	return strings.ToLower(strings.TrimSpace(d.Name + " " + d.Address))
+	return strings.ToLower(strings.TrimSpace(d.Name + " " + d.Alias + " " + d.Address + " " + d.Type + " " + strings.Join(d.Tags, " ")))
 }
 
 func min(a, b int) int {
@@ -86,3 +86,20 @@ func TestMultiModelCancel(t *testing.T) {
 		t.Fatalf("expected canceled=true")
 	}
 }
+
+func TestDeviceMeta_Details(t *testing.T) {
+	left, right := 90, 88
+	d := core.Device{Address: "aa:bb", Type: "Headphones", Battery: &core.Battery{Left: &left, Right: &right}, Connected: true}
+	if got, want := deviceMeta(d), "aa:bb • Headphones • battery L 90% R 88% • connected"; got != want {
+		t.Fatalf("deviceMeta = %q, want %q", got, want)
+	}
+}
+
+func TestModel_FilterByType(t *testing.T) {
+	m := newModel("Pick", []core.Device{{Name: "Alpha", Type: "Keyboard"}, {Name: "Beta", Type: "Headphones"}})
+	m.input.SetValue("headph")
+	m.applyFilter()
+	if len(m.filtered) != 1 || m.filtered[0].Name != "Beta" {
+		t.Fatalf("filtered=%v", m.filtered)
+	}
+}
@@ -60,6 +60,10 @@ func newMultiModel(title string, devices []core.Device) multiModel {
 		a := sorted[i]
 		b := sorted[j]
 
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+
 		if title == "Connect" {
 			if a.Connected != b.Connected {
 				return !a.Connected && b.Connected
@@ -71,7 +75,7 @@ func newMultiModel(title string, devices []core.Device) multiModel {
 			}
 		}
 
-		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		return strings.ToLower(a.DisplayName()) < strings.ToLower(b.DisplayName())
 	})
 
 	m := multiModel{
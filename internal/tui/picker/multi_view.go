@@ -18,7 +18,7 @@ func (m multiModel) View() string {
 
 	b.WriteString(titleStyle.Render(m.title))
 	b.WriteString("\n")
-	b.WriteString(dimStyle.Render("type to filter (name/address) • ↑/↓ (ctrl+p/ctrl+n) move • space toggle • enter confirm • esc cancel"))
+	b.WriteString(dimStyle.Render("type to filter (name/alias/address/type/tag) • ↑/↓ (ctrl+p/ctrl+n) move • space toggle • enter confirm • esc cancel"))
 	b.WriteString("\n\n")
 
 	b.WriteString(m.input.View())
@@ -50,7 +50,7 @@ func (m multiModel) View() string {
 		}
 
 		prefix := "  "
-		nameLine := d.Name
+		nameLine := d.DisplayName()
 		if nameLine == "" {
 			nameLine = "(unknown)"
 		}
@@ -83,12 +83,5 @@ func (m multiModel) View() string {
 
 func deviceMetaMulti(d core.Device) string {
 	// reuse single picker meta format
-	parts := make([]string, 0, 2)
-	if strings.TrimSpace(d.Address) != "" {
-		parts = append(parts, d.Address)
-	}
-	if d.Connected {
-		parts = append(parts, "connected")
-	}
-	return strings.Join(parts, " • ")
+	return deviceMeta(d)
 }
@@ -18,7 +18,7 @@ func (m model) View() string {
 
 	b.WriteString(titleStyle.Render(m.title))
 	b.WriteString("\n")
-	b.WriteString(dimStyle.Render("type to filter (name/address) • ↑/↓ (ctrl+p/ctrl+n) move • enter select • esc cancel"))
+	b.WriteString(dimStyle.Render("type to filter (name/alias/address/type/tag) • ↑/↓ (ctrl+p/ctrl+n) move • enter select • esc cancel"))
 	b.WriteString("\n\n")
 
 	b.WriteString(m.input.View())
@@ -46,7 +46,7 @@ func (m model) View() string {
 	for i := start; i < end; i++ {
 		d := m.filtered[i]
 		prefix := "  "
-		nameLine := d.Name
+		nameLine := d.DisplayName()
 		if nameLine == "" {
 			nameLine = "(unknown)"
 		}
@@ -73,10 +73,27 @@ func (m model) View() string {
 }
 
 func deviceMeta(d core.Device) string {
-	parts := make([]string, 0, 2)
+	parts := make([]string, 0, 4)
+	if d.Pinned {
+		parts = append(parts, "pinned")
+	}
+	if d.Alias != "" && d.Name != "" && d.Alias != d.Name {
+		parts = append(parts, d.Name)
+	}
 	if strings.TrimSpace(d.Address) != "" {
 		parts = append(parts, d.Address)
 	}
+	if d.Type != "" {
+		parts = append(parts, d.Type)
+	}
+	if d.Battery != nil {
+		if s := d.Battery.String(); s != "" {
+			parts = append(parts, "battery "+s)
+		}
+	}
+	if len(d.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(d.Tags, " #"))
+	}
 	if d.Connected {
 		parts = append(parts, "connected")
 	}